@@ -18,6 +18,7 @@ SPDX-License-Identifier: Apache-2.0
 
 import argparse
 import json
+import os
 import re
 import shutil
 import subprocess
@@ -35,6 +36,14 @@ This CLI is used for storing, retrieving, querying a set of data to and from sto
 """
 
 
+def _set_progress_out(args: argparse.Namespace):
+    """
+    Have the progress tracker write its progress to the file given by --progress-out.
+    """
+    if args.progress_out:
+        os.environ[storage.OSMO_PROGRESS_FILE] = args.progress_out
+
+
 def _run_upload_command(service_client: client.ServiceClient, args: argparse.Namespace):
     """
     Upload Data
@@ -42,6 +51,7 @@ def _run_upload_command(service_client: client.ServiceClient, args: argparse.Nam
         args : Parsed command line arguments.
     """
     # pylint: disable=unused-argument
+    _set_progress_out(args)
     # Upload
     storage_client = storage.Client.create(
         storage_uri=args.remote_uri,
@@ -66,6 +76,7 @@ def _run_download_command(service_client: client.ServiceClient, args: argparse.N
         args : Parsed command line arguments.
     """
     # pylint: disable=unused-argument
+    _set_progress_out(args)
     storage_client = storage.Client.create(
         storage_uri=args.remote_uri,
         metrics_dir=args.benchmark_out,
@@ -177,6 +188,27 @@ def _run_delete_command(service_client: client.ServiceClient, args: argparse.Nam
     )
 
 
+def _run_size_command(service_client: client.ServiceClient, args: argparse.Namespace):
+    """
+    Print the number and total size of the objects at a backend URI as JSON
+    Args:
+        args : Parsed command line arguments.
+    """
+    # pylint: disable=unused-argument
+    storage_client = storage.Client.create(
+        storage_uri=args.remote_uri,
+        logging_level=args.log_level.value,
+    )
+
+    count = 0
+    size = 0
+    for obj in storage_client.list_objects(regex=args.regex, recursive=True):
+        count += 1
+        size += obj.size
+
+    print(json.dumps({'count': count, 'size': size}))
+
+
 def _run_check_command(service_client: client.ServiceClient, args: argparse.Namespace):
     """
     Check the access to a backend URI
@@ -259,6 +291,9 @@ def setup_parser(parser: argparse._SubParsersAction):
                                     f'Defaults to {storage.DEFAULT_NUM_THREADS}')
     upload_parser.add_argument('--benchmark-out', '-b',
                                help='Path to folder where benchmark data will be written to.')
+    upload_parser.add_argument('--progress-out',
+                               help='Path to file where the upload progress will be '
+                                    'periodically written to as JSON.')
     upload_parser.set_defaults(func=_run_upload_command)
 
     # Handle 'download' command
@@ -288,6 +323,9 @@ def setup_parser(parser: argparse._SubParsersAction):
                                       f'Defaults to {storage.DEFAULT_NUM_THREADS}')
     download_parser.add_argument('--benchmark-out', '-b',
                                  help='Path to folder where benchmark data will be written to.')
+    download_parser.add_argument('--progress-out',
+                                 help='Path to file where the download progress will be '
+                                      'periodically written to as JSON.')
     download_parser.set_defaults(func=_run_download_command)
 
     # Handel 'list' command
@@ -333,6 +371,19 @@ def setup_parser(parser: argparse._SubParsersAction):
                                help='Regex to filter which types of files to delete')
     delete_parser.set_defaults(func=_run_delete_command)
 
+    # Handle 'size' command
+    size_parser = subparsers.add_parser('size',
+                                        help='Print the number and size of the objects at a '
+                                             'backend URI as JSON',
+                                        epilog='Ex. osmo data size s3://bucket/path')
+    size_parser.add_argument('remote_uri',
+                             type=validation.is_storage_path,
+                             help='URI where the objects will be counted.')
+    size_parser.add_argument('--regex', '-x',
+                             type=validation.is_regex,
+                             help='Regex to filter which types of files to count')
+    size_parser.set_defaults(func=_run_size_command)
+
     check_parser = subparsers.add_parser(
         'check',
         help='Check the access to a backend URI',
@@ -28,6 +28,7 @@ from .common import list_local_files
 from .copying import CopySummary
 from .core.executor import ExecutorParameters, DEFAULT_NUM_PROCESSES, DEFAULT_NUM_THREADS
 from .core.header import RequestHeaders
+from .core.progress import OSMO_PROGRESS_FILE
 from .deleting import DeleteSummary
 from .downloading import DownloadWorkerInput, DownloadSummary
 from .streaming import BytesStream, BytesIO, LinesStream, StreamSummary
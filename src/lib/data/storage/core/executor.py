@@ -376,6 +376,8 @@ def _execute_single_thread(
                 ),
             )
 
+        progress_updater.update(files_change=1)
+
     return result
 
 
@@ -438,6 +440,8 @@ def _execute_multi_thread(
                 else:
                     result.output += future.result()
 
+            progress_updater.update(files_change=1)
+
             # Submit more work if we have room
             while len(workers) < thread_worker_max_inflight and _submit():
                 pass
@@ -522,7 +526,10 @@ def _chunk_generator(
         if progress_update_queue is not None:
             # Progress tracker enabled.
             progress_update_queue.put(
-                progress.ProgressUpdateSnapshot(total_size_change=chunk_item_size),
+                progress.ProgressUpdateSnapshot(
+                    total_size_change=chunk_item_size,
+                    total_files_change=len(chunk),
+                ),
             )
 
         yield chunk
@@ -735,7 +742,10 @@ def _run_in_process_job(
             except StopIteration as iter_error:
                 ret = iter_error.value
                 break
-            progress_updater.update(total_size_change=worker_input.size)
+            progress_updater.update(
+                total_size_change=worker_input.size,
+                total_files_change=1,
+            )
             yield worker_input
         return ret
 
@@ -19,6 +19,7 @@ Module for managing progress tracking.
 """
 
 import dataclasses
+import json
 import os
 import queue
 import threading
@@ -46,6 +47,11 @@ OSMO_PROGRESS_FLUSH_INTERVAL = 'OSMO_PROGRESS_FLUSH_INTERVAL'
 OSMO_PROGRESS_MIN_UPDATE_INTERVAL = 'OSMO_PROGRESS_MIN_UPDATE_INTERVAL'
 
 
+# The file the progress tracker periodically writes its progress to as JSON, so that other
+# processes can report it.
+OSMO_PROGRESS_FILE = 'OSMO_PROGRESS_FILE'
+
+
 ###############################################
 
 def _get_progress_flush_interval() -> datetime.timedelta:
@@ -55,6 +61,17 @@ def _get_progress_flush_interval() -> datetime.timedelta:
     return common.to_timedelta(os.getenv(OSMO_PROGRESS_FLUSH_INTERVAL, '1s'))
 
 
+def _get_progress_file() -> str | None:
+    """
+    Get the progress file from the environment variable.
+    """
+    return os.getenv(OSMO_PROGRESS_FILE) or None
+
+
+# The minimum interval between two writes of the progress file.
+_PROGRESS_FILE_WRITE_INTERVAL = datetime.timedelta(seconds=1)
+
+
 def _get_progress_min_update_interval() -> datetime.timedelta:
     """
     Get the progress min update interval from the environment variable.
@@ -71,6 +88,8 @@ class ProgressUpdateSnapshot(NamedTuple):
     total_size_change: int = 0
     amount_change: int = 0
     name: str = ''
+    total_files_change: int = 0
+    files_change: int = 0
 
 
 @dataclasses.dataclass(slots=True)
@@ -82,17 +101,23 @@ class ProgressUpdate:
     total_size_change: int = 0
     amount_change: int = 0
     name: str = ''
+    total_files_change: int = 0
+    files_change: int = 0
 
     def reset(self):
         self.total_size_change = 0
         self.amount_change = 0
         self.name = ''
+        self.total_files_change = 0
+        self.files_change = 0
 
     def snapshot(self) -> ProgressUpdateSnapshot:
         return ProgressUpdateSnapshot(
             self.total_size_change,
             self.amount_change,
             self.name,
+            self.total_files_change,
+            self.files_change,
         )
 
 
@@ -113,6 +138,10 @@ class ProgressTracker(tqdm.tqdm):
         self.last_update_time = common.current_time()
         self.min_update_interval = _get_progress_min_update_interval()
         self.interactive = ProgressTracker._is_interactive_session()
+        self.total_files = 0
+        self.files_done = 0
+        self.progress_file = _get_progress_file()
+        self.last_progress_file_write: datetime.datetime | None = None
 
         super().__init__(*args, **kwargs)
 
@@ -175,6 +204,45 @@ class ProgressTracker(tqdm.tqdm):
         if progress_update.amount_change != 0:
             self.update(progress_update.amount_change)
 
+        self.total_files += progress_update.total_files_change
+        self.files_done += progress_update.files_change
+        self._write_progress_file()
+
+    def _write_progress_file(self, force: bool = False):
+        """
+        Atomically write the progress to the progress file if it is set.
+        """
+        if not self.progress_file:
+            return
+        now = common.current_time()
+        if (
+            not force and self.last_progress_file_write is not None and
+            now - self.last_progress_file_write < _PROGRESS_FILE_WRITE_INTERVAL
+        ):
+            return
+        self.last_progress_file_write = now
+
+        progress_json = {
+            'bytes_done': int(self.n),
+            'bytes_total': int(self.total or 0),
+            'files_done': self.files_done,
+            'files_total': self.total_files,
+        }
+        temp_file = f'{self.progress_file}.tmp'
+        try:
+            os.makedirs(os.path.dirname(self.progress_file) or '.', exist_ok=True)
+            with open(temp_file, 'w', encoding='utf-8') as file:
+                json.dump(progress_json, file)
+            os.replace(temp_file, self.progress_file)
+        except OSError:
+            # Progress reporting must never fail the transfer
+            pass
+
+    @override
+    def close(self):
+        self._write_progress_file(force=True)
+        super().close()
+
     @override
     def refresh(self, *args, **kwargs):
         now = common.current_time()
@@ -216,6 +284,8 @@ class ProgressUpdater(Protocol):
         total_size_change: int | None = None,
         amount_change: int | None = None,
         name: str | None = None,
+        total_files_change: int | None = None,
+        files_change: int | None = None,
     ) -> None:
         """
         Update the progress tracker with the progress update.
@@ -240,6 +310,8 @@ class NoOpProgressUpdater(ProgressUpdater):
         total_size_change: int | None = None,
         amount_change: int | None = None,
         name: str | None = None,
+        total_files_change: int | None = None,
+        files_change: int | None = None,
     ) -> None:
         pass
 
@@ -264,12 +336,16 @@ class SingleThreadProgressUpdater(ProgressUpdater):
         total_size_change: int | None = None,
         amount_change: int | None = None,
         name: str | None = None,
+        total_files_change: int | None = None,
+        files_change: int | None = None,
     ):
         self.progress_tracker.progress_update(
             ProgressUpdateSnapshot(
                 total_size_change=total_size_change or 0,
                 amount_change=amount_change or 0,
                 name=name or '',
+                total_files_change=total_files_change or 0,
+                files_change=files_change or 0,
             )
         )
 
@@ -295,17 +371,23 @@ class MultiThreadProgressUpdater(SingleThreadProgressUpdater):
         total_size_change: int | None = None,
         amount_change: int | None = None,
         name: str | None = None,
+        total_files_change: int | None = None,
+        files_change: int | None = None,
     ):
         if (
             total_size_change is not None
             or amount_change is not None
             or name is not None
+            or total_files_change is not None
+            or files_change is not None
         ):
             with self.lock:
                 super().update(
                     total_size_change=total_size_change,
                     amount_change=amount_change,
                     name=name,
+                    total_files_change=total_files_change,
+                    files_change=files_change,
                 )
 
 
@@ -381,6 +463,8 @@ class MultiProcessProgressUpdater(ProgressUpdater):
         total_size_change: int | None = None,
         amount_change: int | None = None,
         name: str | None = None,
+        total_files_change: int | None = None,
+        files_change: int | None = None,
     ):
         """
         Update the progress update with the given values.
@@ -389,6 +473,8 @@ class MultiProcessProgressUpdater(ProgressUpdater):
             total_size_change is not None
             or amount_change is not None
             or name is not None
+            or total_files_change is not None
+            or files_change is not None
         ):
             with self._lock:
                 if total_size_change is not None:
@@ -397,6 +483,10 @@ class MultiProcessProgressUpdater(ProgressUpdater):
                     self._progress_update.amount_change += amount_change
                 if name is not None:
                     self._progress_update.name = name
+                if total_files_change is not None:
+                    self._progress_update.total_files_change += total_files_change
+                if files_change is not None:
+                    self._progress_update.files_change += files_change
 
                 self._has_updates = True
 
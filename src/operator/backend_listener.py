@@ -395,19 +395,26 @@ def get_container_failure_message(pod: kubernetes.client.models.v1_pod.V1Pod) ->
             container_name = error_msg_container_name(container_status.name)
             exit_code = state.terminated.exit_code
 
-            # Get the error code from the message if it is osmo-ctrl
+            # Get the error code and reason from the termination record if it is osmo-ctrl
+            ctrl_reason = ''
             if container_name == error_msg_container_name('osmo-ctrl'):
                 if state.terminated.message:
                     try:
                         message_json = json.loads(state.terminated.message)
                         if 'code' in message_json:
                             exit_code = message_json['code']
+                        if message_json.get('message'):
+                            ctrl_reason = message_json['message']
+                            if message_json.get('target'):
+                                ctrl_reason += f' ({message_json["target"]})'
                     except json.JSONDecodeError:
                         pass
 
             error_msg += f'\n- Exit code ' \
                          f'{get_container_exit_code(container_status.name, exit_code)} ' \
                          f'due to {container_name} failure. '
+            if ctrl_reason:
+                error_msg += f'{ctrl_reason} '
             exit_codes[container_status.name] = exit_code
             error_reasons[container_status.name] = state.terminated.reason
 
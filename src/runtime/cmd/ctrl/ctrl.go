@@ -20,6 +20,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
@@ -33,6 +34,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,10 +56,15 @@ import (
 const BUFFERSIZE int = 32 * 1024
 const BARRIER_TICKER_DURATION = time.Duration(5) * time.Minute
 
+// Time to wait for osmo-user to reconnect once the unix connection breaks
+const USER_RESUME_TIMEOUT = time.Minute
+
+// Time to send the queued logs for before failing
+const FAIL_FLUSH_TIMEOUT = 10 * time.Second
+
 var waitGoRoutines sync.WaitGroup
-var webConn *websocket.Conn
+var webConn ServiceConn
 var bufferMutex sync.Mutex
-var numDroppedMsg int
 var jwtTokenMux sync.RWMutex
 var jwtToken string // Should only be written by refreshJWTToken()
 var tokenExpiration time.Time
@@ -68,6 +75,23 @@ var barrierReq string
 
 var rsyncStatus rsync.RsyncStatus
 
+// ServiceConn is the connection to the workflow service, or the local service in local mode
+type ServiceConn interface {
+	WriteJSON(v interface{}) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	ReadMessage() (messageType int, p []byte, err error)
+	Close() error
+}
+
+// RouterConn is a connection to the router, or to a client of a local port in local mode
+type RouterConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+	Close() error
+}
+
 type PortForwardType string
 
 const (
@@ -123,19 +147,20 @@ func (e *DialWebsocketError) Error() string {
 	return e.Message
 }
 
-func refreshJWTToken(cmdArgs args.CtrlArgs) error {
+// refreshJWTToken fetches a new jwt token. Failures which can not be resolved by retrying are
+// returned as an ExitError, the others as a DialWebsocketError.
+func refreshJWTToken(ctx context.Context, cmdArgs args.CtrlArgs) error {
 	refreshToken, err := os.ReadFile(cmdArgs.RefreshToken)
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.TOKEN_INVALID_CODE)
-		panic(fmt.Sprintf("Unable to read refresh token from file %s due to error %s\n",
-			cmdArgs.RefreshToken, err))
+		return osmo_errors.Errorf(osmo_errors.TOKEN_INVALID_CODE,
+			"Unable to read refresh token from file %s due to error %s", cmdArgs.RefreshToken, err)
 	}
 
 	// Create a URL object from the base URL
 	u, err := url.Parse(cmdArgs.RefreshTokenUrl.String())
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.TOKEN_INVALID_CODE)
-		panic(fmt.Sprintf("Parsing refreshUrl failed: %v\n%s", cmdArgs.RefreshTokenUrl, err))
+		return osmo_errors.Errorf(osmo_errors.TOKEN_INVALID_CODE,
+			"Parsing refreshUrl failed: %v\n%s", cmdArgs.RefreshTokenUrl, err)
 	}
 
 	// Query parameters (token goes in the body, not the URL)
@@ -150,16 +175,29 @@ func refreshJWTToken(cmdArgs args.CtrlArgs) error {
 	// Send token in request body as JSON
 	requestBody, err := json.Marshal(map[string]string{"token": string(refreshToken)})
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.TOKEN_INVALID_CODE)
-		panic(fmt.Sprintf("Error marshaling token request body: %s\n", err))
+		return osmo_errors.Errorf(osmo_errors.TOKEN_INVALID_CODE,
+			"Error marshaling token request body: %s", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(),
+		bytes.NewBuffer(requestBody))
+	if err != nil {
+		return osmo_errors.Errorf(osmo_errors.TOKEN_INVALID_CODE,
+			"Error creating token request: %s", err)
 	}
-	resp, err := http.Post(u.String(), "application/json", bytes.NewBuffer(requestBody))
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(request)
 	if err != nil {
 		return &DialWebsocketError{
 			ErrorType: string(FetchFailureError),
 			Message:   fmt.Sprintf("Error fetching new jwt token: %s\n", err),
 		}
 	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return &DialWebsocketError{
+			ErrorType: string(InvalidTokenError),
+			Message:   fmt.Sprintf("Refresh token was rejected: %s\n", resp.Status),
+		}
+	}
 	if resp.StatusCode != http.StatusOK {
 		var jwtTokenResp JWTTokenResponse
 		err := json.NewDecoder(resp.Body).Decode(&jwtTokenResp)
@@ -200,11 +238,34 @@ func refreshJWTToken(cmdArgs args.CtrlArgs) error {
 	return nil
 }
 
-func dialWebsocket(url string, conn **websocket.Conn, cmdArgs args.CtrlArgs, retryCount int) error {
+// serviceDialer returns the dialer of the websocket connection to the service
+func serviceDialer() websocket.Dialer {
 	// TODO: Validate ssl certs when this is moved into a sidecar
 	// container where we can add a list of certificate authorities.
 	dialer := *websocket.DefaultDialer
 	dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return dialer
+}
+
+// serviceHeaders returns the headers authenticating with the current jwt token
+func serviceHeaders(cmdArgs args.CtrlArgs) http.Header {
+	headerKey := cmdArgs.TokenHeader
+	headers := make(http.Header)
+	jwtTokenMux.RLock()
+	defer jwtTokenMux.RUnlock()
+	if strings.EqualFold(headerKey, "authorization") {
+		headers.Add(headerKey, "Bearer "+jwtToken)
+	} else {
+		headers.Add(headerKey, jwtToken)
+	}
+	return headers
+}
+
+// dialWebsocket connects to the service. Failures which can not be resolved by retrying, such as
+// reaching the websocket timeout, are returned as an ExitError. Canceling ctx aborts the dial.
+func dialWebsocket(ctx context.Context, url string, conn *ServiceConn, cmdArgs args.CtrlArgs,
+	retryCount int) error {
+	dialer := serviceDialer()
 
 	var err error
 	var newConn *websocket.Conn
@@ -216,24 +277,18 @@ func dialWebsocket(url string, conn **websocket.Conn, cmdArgs args.CtrlArgs, ret
 	isRefresh = time.Now().After(tokenExpiration)
 	jwtTokenMux.RUnlock()
 	if isRefresh {
-		err := refreshJWTToken(cmdArgs)
+		err := refreshJWTToken(ctx, cmdArgs)
+		if err != nil && !isFatalDialError(ctx, err) {
+			if sleepErr := common.SleepContext(ctx,
+				data.ExponentialBackoffWithJitter(retryCount)); sleepErr != nil {
+				return sleepErr
+			}
+		}
 		if err != nil {
-			time.Sleep(data.ExponentialBackoffWithJitter(retryCount))
 			return err
 		}
 	}
-	headerKey := cmdArgs.TokenHeader
-	headers := make(http.Header)
-	jwtTokenMux.RLock()
-	if strings.EqualFold(headerKey, "authorization") {
-		headers.Add(headerKey, "Bearer "+jwtToken)
-	} else {
-		headers.Add(headerKey, jwtToken)
-	}
-	jwtTokenMux.RUnlock()
-
-	newConn, resp, err = dialer.Dial(url, headers)
-	*conn = newConn
+	newConn, resp, err = dialer.DialContext(ctx, url, serviceHeaders(cmdArgs))
 	if err != nil {
 		// Enhanced error logging with HTTP response details
 		if resp != nil {
@@ -244,25 +299,68 @@ func dialWebsocket(url string, conn **websocket.Conn, cmdArgs args.CtrlArgs, ret
 			}
 		}
 		if !data.WebsocketConnection.ReachedTimeout() {
-			time.Sleep(data.ExponentialBackoffWithJitter(retryCount))
+			if sleepErr := common.SleepContext(ctx,
+				data.ExponentialBackoffWithJitter(retryCount)); sleepErr != nil {
+				return sleepErr
+			}
 			return err
 		}
 
 		log.Printf("Unable to connect to websocket: Timeout")
-		osmo_errors.SetExitCode(osmo_errors.WEBSOCKET_TIMEOUT_CODE)
-		panic(fmt.Sprintf("Failed to connect to websocket %s with error: %s", url, err))
+		return osmo_errors.Errorf(osmo_errors.WEBSOCKET_TIMEOUT_CODE,
+			"Failed to connect to websocket %s with error: %s", url, err)
 	}
+	*conn = newConn
 	return nil
 }
 
-func connWorkflowService(url string, cmdArgs args.CtrlArgs) {
+// preflightService checks that the service is reachable and accepts the refresh token, so that
+// ctrl fails fast instead of retrying the websocket connection until it times out
+func preflightService(ctx context.Context, cmdArgs args.CtrlArgs) error {
+	if _, err := os.ReadFile(cmdArgs.RefreshToken); err != nil {
+		return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+			"Unable to read refresh token from file %s: %s", cmdArgs.RefreshToken, err)
+	}
+
+	deadline := time.Now().Add(cmdArgs.PreflightTimeout)
+	for retryCount := 0; ; retryCount++ {
+		err := refreshJWTToken(ctx, cmdArgs)
+		dialErr, isDialErr := err.(*DialWebsocketError)
+		switch {
+		case err != nil && !isDialErr:
+			return osmo_errors.NewExitError(osmo_errors.PREFLIGHT_FAILED_CODE, err)
+		case isDialErr && dialErr.ErrorType == string(InvalidTokenError):
+			return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+				"The service did not accept the refresh token: %s", dialErr.Message)
+		case !isDialErr || dialErr.ErrorType != string(FetchFailureError):
+			// Pending and finished tasks are also reported by a reachable service
+			log.Println("Preflight: the service is reachable")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+				"Unable to reach the service at %s within %v: %s",
+				cmdArgs.RefreshTokenUrl.Host, cmdArgs.PreflightTimeout, dialErr.Message)
+		}
+		backoff := data.ExponentialBackoffWithJitter(retryCount)
+		if err := common.SleepContext(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// connWorkflowService connects to the service until the connection succeeds, fails with an
+// ExitError or ctx is canceled
+func connWorkflowService(ctx context.Context, url string, cmdArgs args.CtrlArgs) error {
 	// Attempt to dial the websocket
 	data.WebsocketConnection.DisconnectStartTime = time.Now()
 	count := 0
 
 	for {
-		err := dialWebsocket(url, &webConn, cmdArgs, count)
-		if err != nil {
+		err := dialWebsocket(ctx, url, &webConn, cmdArgs, count)
+		if isFatalDialError(ctx, err) {
+			return err
+		} else if err != nil {
 			count++
 			if count%100 == 1 {
 				switch e := err.(type) {
@@ -286,40 +384,124 @@ func connWorkflowService(url string, cmdArgs args.CtrlArgs) {
 	} else {
 		log.Printf("Connected to websocket: %s retries", strconv.Itoa(count))
 	}
+	return nil
+}
+
+// isFatalDialError returns true if the dial error can not be resolved by retrying
+func isFatalDialError(ctx context.Context, err error) bool {
+	var exitErr *osmo_errors.ExitError
+	return err != nil && (ctx.Err() != nil || errors.As(err, &exitErr))
 }
 
-// Enqueue log into circular queue in a threadsafe manner
-func threadsafeEnqueue(logQueue *common.CircularBuffer, message string) {
+// Stream of the metrics in the log queue
+const metricsStream = "METRICS"
+
+// Enqueue log into the priority queue in a threadsafe manner
+func threadsafeEnqueue(logQueue *common.PriorityLogQueue, stream string,
+	priority common.LogPriority, message string) {
 	bufferMutex.Lock()
 	defer bufferMutex.Unlock()
-	if logQueue.IsFull() {
-		numDroppedMsg++
+	logQueue.Push(stream, priority, message)
+}
+
+// logPriority returns the priority of the messages of an IOType
+func logPriority(ioType messages.IOType) common.LogPriority {
+	switch ioType {
+	case messages.Barrier:
+		return common.PriorityControl
+	case messages.LogDone:
+		return common.PriorityDone
+	case messages.StdOut:
+		return common.PriorityStdout
+	case messages.StdErr:
+		return common.PriorityStderr
+	default:
+		return common.PriorityCtrl
+	}
+}
+
+// Masks secrets in all log lines before they are sent to the service
+var logRedactor *common.Redactor
+
+// createLogRedactor masks the credentials of the mounted configs, the secret environment
+// variables and the text matching the redaction patterns
+func createLogRedactor(cmdArgs args.CtrlArgs) *common.Redactor {
+	redactor, err := common.NewRedactor(
+		append(append([]string{}, common.DefaultRedactPatterns...), cmdArgs.RedactPatterns...))
+	if err != nil {
+		osmo_errors.Fail(osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err))
 	}
-	logQueue.Push(message)
+	for _, config := range []string{cmdArgs.UserConfig, cmdArgs.ServiceConfig} {
+		if err := redactor.AddSecretsFromConfig(config); err != nil {
+			log.Printf("Not redacting secrets of %s: %v", config, err)
+		}
+	}
+	redactor.AddSecretsFromEnv(
+		append(append([]string{}, common.DefaultSecretEnvPatterns...), cmdArgs.RedactEnv...))
+	return redactor
+}
+
+// Format of the user log lines parsed into structured logs
+var userLogFormat = common.LogFormatText
+
+// enqueueLog redacts a log line, enqueues it for the service and returns the redacted line.
+// Structured user log lines also carry their level and fields.
+func enqueueLog(logQueue *common.PriorityLogQueue, logSource string, text string,
+	ioType messages.IOType) string {
+	return enqueueTaggedLog(logQueue, logSource, text, ioType, "")
+}
+
+// enqueueTaggedLog enqueues a log line of a hook or sidecar of osmo-user, which is prefixed
+// with its tag. The tag is also a field of structured lines.
+func enqueueTaggedLog(logQueue *common.PriorityLogQueue, logSource string, text string,
+	ioType messages.IOType, tag string) string {
+	text = logRedactor.Redact(string(ioType), text)
+	logMsg := ""
+	if userLogFormat != common.LogFormatText &&
+		(ioType == messages.StdOut || ioType == messages.StdErr) {
+		if structured, ok := common.ParseStructuredLog(text, userLogFormat); ok {
+			fields := structured.Fields
+			if tag != "" {
+				fields = make(map[string]string, len(structured.Fields)+1)
+				for key, value := range structured.Fields {
+					fields[key] = value
+				}
+				fields["process"] = tag
+				text = fmt.Sprintf("[%s] %s", tag, text)
+			}
+			logMsg = messages.CreateStructuredLog(logSource, text, ioType, structured.Level,
+				fields)
+		}
+	}
+	if logMsg == "" {
+		if tag != "" {
+			text = fmt.Sprintf("[%s] %s", tag, text)
+		}
+		logMsg = messages.CreateLog(logSource, text, ioType)
+	}
+	threadsafeEnqueue(logQueue, string(ioType), logPriority(ioType), logMsg)
+	return text
 }
 
 // Reads from both channels and writes the output into the websocket
 func putLogs(
 	logSource string, osmoChan chan string, downloadChan chan string, uploadChan chan string,
-	stopChan chan bool, metricChan chan metrics.Metric, logQueue *common.CircularBuffer) {
+	stopChan chan bool, metricChan chan metrics.Metric, logQueue *common.PriorityLogQueue) {
 	for {
-		var logMsg string
 		select {
 		case downloadMsg := <-downloadChan:
-			logMsg = messages.CreateLog(logSource, downloadMsg, messages.Download)
-			log.Printf("%s", downloadMsg)
-			threadsafeEnqueue(logQueue, logMsg)
+			log.Printf("%s", enqueueLog(logQueue, logSource, downloadMsg, messages.Download))
 		case uploadMsg := <-uploadChan:
-			logMsg = messages.CreateLog(logSource, uploadMsg, messages.Upload)
-			log.Printf("%s", uploadMsg)
-			threadsafeEnqueue(logQueue, logMsg)
+			log.Printf("%s", enqueueLog(logQueue, logSource, uploadMsg, messages.Upload))
 		case osmoMsg := <-osmoChan:
-			logMsg = messages.CreateLog(logSource, osmoMsg, messages.OSMOCtrl)
-			log.Printf("%s", osmoMsg)
-			threadsafeEnqueue(logQueue, logMsg)
+			log.Printf("%s", enqueueLog(logQueue, logSource, osmoMsg, messages.OSMOCtrl))
 		case osmoMetrics := <-metricChan:
-			logMsg = metrics.CreateMetrics(logSource, osmoMetrics, metrics.Metrics)
-			threadsafeEnqueue(logQueue, logMsg)
+			logMsg, err := metrics.CreateMetrics(logSource, osmoMetrics, metrics.Metrics)
+			if err != nil {
+				log.Println("Dropping metrics which can not be encoded:", err)
+				continue
+			}
+			threadsafeEnqueue(logQueue, metricsStream, common.PriorityCtrl, logMsg)
 		case <-stopChan:
 			defer waitGoRoutines.Done()
 			log.Printf("Go routine putLogs is done")
@@ -339,7 +521,7 @@ type ServiceRequest struct {
 	EnableTelemetry bool   `json:"enable_telemetry"`
 }
 
-func createWebsocketConnection(
+func createWebsocketConnection(ctx context.Context,
 	address string, cookie string, cmdArgs args.CtrlArgs) (*websocket.Conn, error) {
 	var conn *websocket.Conn = nil
 	var err error = nil
@@ -349,25 +531,16 @@ func createWebsocketConnection(
 	isRefresh = time.Now().After(tokenExpiration)
 	jwtTokenMux.RUnlock()
 	if isRefresh {
-		err := refreshJWTToken(cmdArgs)
+		err := refreshJWTToken(ctx, cmdArgs)
 		if err != nil {
-			time.Sleep(1 * time.Second)
 			return nil, err
 		}
 	}
 
-	headerKey := cmdArgs.TokenHeader
-	headers := make(http.Header)
-	jwtTokenMux.RLock()
-	if strings.EqualFold(headerKey, "authorization") {
-		headers.Add(headerKey, "Bearer "+jwtToken)
-	} else {
-		headers.Add(headerKey, jwtToken)
-	}
-	jwtTokenMux.RUnlock()
+	headers := serviceHeaders(cmdArgs)
 	headers.Add("Cookie", cookie)
 
-	conn, _, err = websocket.DefaultDialer.Dial(address, headers)
+	conn, _, err = websocket.DefaultDialer.DialContext(ctx, address, headers)
 	return conn, err
 }
 
@@ -384,31 +557,107 @@ func createConnection(address string, retryMax int, protocal string) (net.Conn,
 	return conn, err
 }
 
-func sendUserExecStart(unixConn net.Conn, entryCommand string) error {
-	return json.NewEncoder(unixConn).Encode(
-		messages.UserExecStartRequest(entryCommand))
+func sendUserExecStart(userConn *messages.Conn, entryCommand string) error {
+	return userConn.Send(messages.UserExecStartRequest(entryCommand))
 }
 
-func ctrlUserExec(unixConn net.Conn, routerAddress string, key string, cookie string,
-	cmdArgs args.CtrlArgs) {
-	defer unixConn.Close()
-	url := fmt.Sprintf("%s/api/router/exec/%s/backend/%s", routerAddress, cmdArgs.Workflow, key)
+// acceptUserConns accepts the connections of osmo-user after the first one, which introduce
+// themselves with a hello in protocol version 2: exec sessions and reconnections
+func acceptUserConns(listener net.Listener, userConn *messages.Conn, execConns chan net.Conn) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			hello, conn, err := messages.ReadHello(conn, messages.HandshakeTimeout)
+			if err != nil {
+				log.Println("Failed to read the hello of a user connection:", err)
+				conn.Close()
+				return
+			}
+			switch hello.Role {
+			case messages.ExecRole:
+				select {
+				case execConns <- conn:
+				case <-time.After(messages.HandshakeTimeout):
+					log.Println("Closing an exec connection which was not requested")
+					conn.Close()
+				}
+			case messages.ControlRole:
+				if err := userConn.Resume(conn, hello); err != nil {
+					log.Println("Failed to resume the user connection:", err)
+					conn.Close()
+					return
+				}
+				log.Println("Resumed the user connection")
+			default:
+				log.Printf("Unknown role %s of a user connection", hello.Role)
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// acceptExecConn waits for the connection of an exec session. In protocol version 1, exec
+// sessions are accepted directly since they do not send a hello.
+func acceptExecConn(listener net.Listener, execConns chan net.Conn,
+	timeout time.Duration) (net.Conn, error) {
+	if execConns == nil {
+		unixListener := listener.(*net.UnixListener)
+		unixListener.SetDeadline(time.Now().Add(timeout))
+		return listener.Accept()
+	}
+	select {
+	case conn := <-execConns:
+		return conn, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("No exec connection within %v", timeout)
+	}
+}
+
+// closeOnDone closes the connections once ctx is done, which unblocks their readers. The
+// returned function stops watching ctx.
+func closeOnDone(ctx context.Context, conns ...io.Closer) func() bool {
+	return context.AfterFunc(ctx, func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+}
+
+// connectRouter connects to the router, retrying failures every second until ctx is done. In
+// local mode, it returns the client of the local port with the key instead.
+func connectRouter(ctx context.Context, url string, key string, cookie string,
+	cmdArgs args.CtrlArgs, retryMax int) (RouterConn, error) {
+	if localService != nil {
+		return localService.connectRouter(key)
+	}
 	var conn *websocket.Conn
 	var err error
-	var retryMax int = 5
-
 	for i := 0; i < retryMax; i++ {
-		conn, err = createWebsocketConnection(url, cookie, cmdArgs)
+		conn, err = createWebsocketConnection(ctx, url, cookie, cmdArgs)
 		if err == nil {
 			break
 		}
-		time.Sleep(time.Second)
+		if sleepErr := common.SleepContext(ctx, time.Second); sleepErr != nil {
+			return nil, sleepErr
+		}
 	}
+	return conn, err
+}
+
+func ctrlUserExec(ctx context.Context, unixConn net.Conn, routerAddress string, key string,
+	cookie string, cmdArgs args.CtrlArgs) {
+	defer unixConn.Close()
+	url := fmt.Sprintf("%s/api/router/exec/%s/backend/%s", routerAddress, cmdArgs.Workflow, key)
+	conn, err := connectRouter(ctx, url, key, cookie, cmdArgs, 5)
 	if err != nil {
 		log.Println("User Exec: error connecting to the router:", err)
 		return
 	}
 	defer conn.Close()
+	defer closeOnDone(ctx, conn, unixConn)()
 
 	var waitGroup sync.WaitGroup
 	waitGroup.Add(1)
@@ -449,6 +698,7 @@ func ctrlUserExec(unixConn net.Conn, routerAddress string, key string, cookie st
 }
 
 func userPortForwardTCP(
+	ctx context.Context,
 	routerAddress string,
 	clientInfo ServiceRequest,
 	cmdArgs args.CtrlArgs,
@@ -458,21 +708,13 @@ func userPortForwardTCP(
 		"%s/api/router/%s/%s/backend/%s",
 		routerAddress, clientInfo.Action, cmdArgs.Workflow, clientInfo.Key)
 
-	var conn *websocket.Conn
-	var err error
-	var retryMax int = 10
-	for i := 0; i < retryMax; i++ {
-		conn, err = createWebsocketConnection(url, clientInfo.Cookie, cmdArgs)
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Second)
-	}
+	conn, err := connectRouter(ctx, url, clientInfo.Key, clientInfo.Cookie, cmdArgs, 10)
 	if err != nil {
 		log.Println("userPortForwardTCP: error connecting to the router:", err)
 		return
 	}
 	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
 
 	for {
 		_, data, err := conn.ReadMessage()
@@ -495,9 +737,10 @@ func userPortForwardTCP(
 
 		if message.Type == PortForwardWS {
 			go portforwardConnectWS(
-				routerAddress, message, clientInfo.TaskPort, cmdArgs)
+				ctx, routerAddress, message, clientInfo.TaskPort, cmdArgs)
 		} else {
 			go portforwardConnectTCP(
+				ctx,
 				clientInfo.Action,
 				routerAddress,
 				message.Key,
@@ -511,7 +754,7 @@ func userPortForwardTCP(
 	}
 }
 
-func copyWebsocket(dst, src *websocket.Conn, closeConn chan bool) {
+func copyWebsocket(dst, src RouterConn, closeConn chan bool) {
 	defer func() { closeConn <- true }()
 	for {
 		messageType, data, err := src.ReadMessage()
@@ -554,7 +797,77 @@ func putPortforwardTCPTelemetry(
 	}
 }
 
+func putCheckpointMetrics(
+	metricChan chan metrics.Metric,
+	cmdArgs args.CtrlArgs,
+	round *messages.CheckpointRound,
+) {
+	if round == nil || round.SizeInBytes == 0 {
+		return
+	}
+	metricChan <- metrics.TaskIOMetrics{
+		RetryId:       cmdArgs.RetryId,
+		GroupName:     cmdArgs.GroupName,
+		TaskName:      cmdArgs.LogSource,
+		URL:           round.Url,
+		Type:          "CHECKPOINT",
+		StartTime:     round.StartTime.Format("2006-01-02 15:04:05.000"),
+		EndTime:       round.EndTime.Format("2006-01-02 15:04:05.000"),
+		SizeInBytes:   round.SizeInBytes,
+		NumberOfFiles: round.NumberOfFiles,
+		OperationType: data.URLOperation,
+		DownloadType:  data.NotApplicable,
+	}
+}
+
+func putResourceMetrics(
+	metricChan chan metrics.Metric,
+	cmdArgs args.CtrlArgs,
+	usage *common.ResourceUsage,
+) {
+	if usage == nil {
+		return
+	}
+	metricChan <- metrics.ResourceMetrics{
+		RetryId:               cmdArgs.RetryId,
+		GroupName:             cmdArgs.GroupName,
+		TaskName:              cmdArgs.LogSource,
+		Time:                  usage.Time.Format("2006-01-02 15:04:05.000"),
+		Processes:             usage.Processes,
+		CpuSeconds:            usage.CpuSeconds,
+		RssBytes:              usage.RssBytes,
+		OpenFiles:             usage.OpenFiles,
+		CgroupCpuSeconds:      usage.CgroupCpuSeconds,
+		CgroupMemoryBytes:     usage.CgroupMemoryBytes,
+		CgroupMemoryPeakBytes: usage.CgroupMemoryPeakBytes,
+		OomKills:              usage.OomKills,
+		OutputSizeBytes:       usage.OutputSizeBytes,
+		NetworkRxBytes:        usage.NetworkRxBytes,
+		NetworkTxBytes:        usage.NetworkTxBytes,
+	}
+}
+
+func putRestartMetrics(
+	metricChan chan metrics.Metric,
+	cmdArgs args.CtrlArgs,
+	restart *messages.CommandRestart,
+) {
+	if restart == nil {
+		return
+	}
+	metricChan <- metrics.RestartMetrics{
+		RetryId:        cmdArgs.RetryId,
+		GroupName:      cmdArgs.GroupName,
+		TaskName:       cmdArgs.LogSource,
+		Time:           restart.Time.Format("2006-01-02 15:04:05.000"),
+		Restart:        restart.Restart,
+		ExitCode:       restart.ExitCode,
+		BackoffSeconds: restart.Backoff.Seconds(),
+	}
+}
+
 func portforwardConnectTCP(
+	ctx context.Context,
 	actionType ActionType,
 	routerAddress string,
 	key string,
@@ -564,7 +877,7 @@ func portforwardConnectTCP(
 	enableTelemetry bool,
 	metricChan chan metrics.Metric,
 ) {
-	var remoteConn *websocket.Conn
+	var remoteConn RouterConn
 	var localConn net.Conn
 	var err error
 	var retryMax int = 5
@@ -577,13 +890,7 @@ func portforwardConnectTCP(
 
 	url := fmt.Sprintf(
 		"%s/api/router/portforward/%s/backend/%s", routerAddress, cmdArgs.Workflow, key)
-	for i := 0; i < retryMax; i++ {
-		remoteConn, err = createWebsocketConnection(url, cookie, cmdArgs)
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Second)
-	}
+	remoteConn, err = connectRouter(ctx, url, key, cookie, cmdArgs, retryMax)
 	if err != nil {
 		log.Println("portforwardConnectTCP: error connecting to the router:", err)
 		return
@@ -601,6 +908,7 @@ func portforwardConnectTCP(
 	defer localConn.Close()
 	defer log.Println("Closing local and remote connections. key: ",
 		key, localConn.LocalAddr(), remoteConn.LocalAddr())
+	defer closeOnDone(ctx, localConn, remoteConn)()
 
 	go func() {
 		// Optional telemetry for portforward output
@@ -690,9 +998,9 @@ func portforwardConnectTCP(
 	<-closeConn
 }
 
-func portforwardConnectWS(routerAddress string, message PortForwardMessage, localPort int,
-	cmdArgs args.CtrlArgs) {
-	var remoteConn *websocket.Conn
+func portforwardConnectWS(ctx context.Context, routerAddress string, message PortForwardMessage,
+	localPort int, cmdArgs args.CtrlArgs) {
+	var remoteConn RouterConn
 	var localConn *websocket.Conn
 	var err error
 	var retryMax int = 5
@@ -705,13 +1013,7 @@ func portforwardConnectWS(routerAddress string, message PortForwardMessage, loca
 
 	url := fmt.Sprintf(
 		"%s/api/router/portforward/%s/backend/%s", routerAddress, cmdArgs.Workflow, message.Key)
-	for i := 0; i < retryMax; i++ {
-		remoteConn, err = createWebsocketConnection(url, message.Cookie, cmdArgs)
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Second)
-	}
+	remoteConn, err = connectRouter(ctx, url, message.Key, message.Cookie, cmdArgs, retryMax)
 	if err != nil {
 		log.Println("portforwardConnectWS: error connecting to the router:", err)
 		return
@@ -731,11 +1033,14 @@ func portforwardConnectWS(routerAddress string, message PortForwardMessage, loca
 	}
 
 	for i := 0; i < retryMax; i++ {
-		localConn, _, err = websocket.DefaultDialer.Dial(localAddr, headers)
+		localConn, _, err = websocket.DefaultDialer.DialContext(ctx, localAddr, headers)
 		if err == nil {
 			break
 		}
-		time.Sleep(time.Second)
+		if sleepErr := common.SleepContext(ctx, time.Second); sleepErr != nil {
+			err = sleepErr
+			break
+		}
 	}
 	if err != nil {
 		log.Println("portforwardConnectWS: error connecting to local server listening at port: ",
@@ -745,6 +1050,7 @@ func portforwardConnectWS(routerAddress string, message PortForwardMessage, loca
 	defer localConn.Close()
 	defer log.Println("Closing local and remote connections. key: ",
 		message.Key, localConn.LocalAddr(), remoteConn.LocalAddr())
+	defer closeOnDone(ctx, localConn, remoteConn)()
 
 	log.Println("start coroutine")
 	go copyWebsocket(remoteConn, localConn, closeConn)
@@ -754,27 +1060,20 @@ func portforwardConnectWS(routerAddress string, message PortForwardMessage, loca
 	<-closeConn
 }
 
-func userPortForwardUDP(
+func userPortForwardUDP(ctx context.Context,
 	routerAddress string, key string, cookie string, taskPort int, cmdArgs args.CtrlArgs) {
 	url := fmt.Sprintf(
 		"%s/api/router/portforward/%s/backend/%s", routerAddress, cmdArgs.Workflow, key)
 
-	var conn *websocket.Conn
 	var mutex sync.Mutex
-	var err error
 	var retryMax int = 10
-	for i := 0; i < retryMax; i++ {
-		conn, err = createWebsocketConnection(url, cookie, cmdArgs)
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Second)
-	}
+	conn, err := connectRouter(ctx, url, key, cookie, cmdArgs, retryMax)
 	if err != nil {
 		log.Println("userPortForwardUDP: error connecting to the router:", err)
 		return
 	}
 	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
 
 	map_addr := make(map[string]net.Conn)
 	// Some services like Isaac-sim can not resolve "localhost"
@@ -826,7 +1125,7 @@ func getSrcAddr(data []byte) string {
 	return srcAddr
 }
 
-func readUDP(remoteConn *websocket.Conn, mutex *sync.Mutex,
+func readUDP(remoteConn RouterConn, mutex *sync.Mutex,
 	localConn net.Conn, data []byte) {
 	buffer := make([]byte, BUFFERSIZE)
 	copy(buffer[:6], data[:6])
@@ -855,7 +1154,30 @@ func readUDP(remoteConn *websocket.Conn, mutex *sync.Mutex,
 	}
 }
 
-func sendLogs(logSource string, logQueue *common.CircularBuffer, logsPeriodMs int,
+// sendDroppedWarnings reports the number of messages dropped per stream and returns false if
+// a warning could not be sent. The counts of unsent warnings are kept for the next attempt.
+func sendDroppedWarnings(logSource string, logQueue *common.PriorityLogQueue) bool {
+	dropped := logQueue.TakeDropped()
+	streams := make([]string, 0, len(dropped))
+	for stream := range dropped {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	for i, stream := range streams {
+		warningMsg := fmt.Sprintf("WARNING: Maximum logging rate exceeded, "+
+			"%d %s lines have been dropped!", dropped[stream], stream)
+		logMsg := messages.CreateLog(logSource, warningMsg, messages.StdErr)
+		if err := messages.Put(webConn, logMsg); err != nil {
+			for _, unsent := range streams[i:] {
+				logQueue.AddDropped(unsent, dropped[unsent])
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func sendLogs(logSource string, logQueue *common.PriorityLogQueue, logsPeriodMs int,
 	stopChan chan bool) {
 	// Adjust the interval for throttling
 	ticker := time.NewTicker(time.Duration(logsPeriodMs) * time.Millisecond)
@@ -872,36 +1194,53 @@ func sendLogs(logSource string, logQueue *common.CircularBuffer, logsPeriodMs in
 				continue
 			}
 			bufferMutex.Lock()
-			// Only pop when log is successfully pushed through the websocket connection
-			logJson, err := logQueue.Peek()
-			if err == nil {
-				if numDroppedMsg > 0 {
-					warningMsg := fmt.Sprintf("WARNING: Maximum logging rate exceeded, "+
-						"%d lines have been dropped!", numDroppedMsg)
-					logMsg := messages.CreateLog(logSource, warningMsg, messages.StdErr)
-					err := messages.Put(webConn, logMsg)
-					if err != nil {
-						continue
-					}
-					numDroppedMsg = 0
-				}
-				err := messages.Put(webConn, logJson)
-				if err != nil {
-					log.Println("Failed to send log message:", err, logJson)
-				} else {
-					logQueue.Pop()
-				}
-			}
+			sendQueuedLog(logSource, logQueue)
 			bufferMutex.Unlock()
 		}
 	}
 }
 
-// Keeps websocket connection alive and catch any errors from the server
-func pingPang(timeout time.Duration, url string, osmoChan chan string, startExecChan chan bool,
-	restartChan chan bool, metricChan chan metrics.Metric,
-	unixConn net.Conn, logsFinished *bool, cmdArgs args.CtrlArgs,
-	listener net.Listener, logQueue *common.CircularBuffer) {
+// sendQueuedLog sends the next log of the queue and returns false if the queue is empty or the
+// log could not be sent. The caller holds bufferMutex.
+func sendQueuedLog(logSource string, logQueue *common.PriorityLogQueue) bool {
+	// Only pop when log is successfully pushed through the websocket connection
+	logJson, err := logQueue.Peek()
+	if err != nil {
+		return false
+	}
+	if !sendDroppedWarnings(logSource, logQueue) {
+		return false
+	}
+	if err := messages.Put(webConn, logJson); err != nil {
+		log.Println("Failed to send log message:", err, logJson)
+		return false
+	}
+	logQueue.Pop()
+	return true
+}
+
+// flushLogs sends the queued logs without throttling until the queue is empty, a log could not
+// be sent or the timeout has passed
+func flushLogs(logSource string, logQueue *common.PriorityLogQueue, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !data.WebsocketConnection.IsBroken && time.Now().Before(deadline) {
+		bufferMutex.Lock()
+		sent := sendQueuedLog(logSource, logQueue)
+		bufferMutex.Unlock()
+		if !sent {
+			return
+		}
+	}
+}
+
+// Keeps websocket connection alive and catch any errors from the server. Reconnecting is
+// aborted with ctx, and the handlers of the service requests are canceled with execCtx. Failures
+// which end ctrl are passed to failAsync, since they can not be recovered in this goroutine.
+func pingPang(ctx context.Context, execCtx context.Context, timeout time.Duration, url string,
+	osmoChan chan string, startExecChan chan bool, metricChan chan metrics.Metric,
+	userConn *messages.Conn, logsFinished *bool, cmdArgs args.CtrlArgs,
+	listener net.Listener, execConns chan net.Conn, logQueue *common.PriorityLogQueue,
+	failAsync func(error)) {
 
 	count := 0
 	logCount := 0.0
@@ -916,8 +1255,12 @@ func pingPang(timeout time.Duration, url string, osmoChan chan string, startExec
 			}
 
 			count++
-			err := dialWebsocket(url, &webConn, cmdArgs, count)
-			if err != nil {
+			err := dialWebsocket(ctx, url, &webConn, cmdArgs, count)
+			if isFatalDialError(ctx, err) {
+				failAsync(err)
+				log.Printf("Go routine pingPang is done")
+				return
+			} else if err != nil {
 				if count == 1 || math.Mod(logCount, 60) == 0 {
 					log.Printf("Failed to connect to websocket %s with error: %s. "+
 						"%s mins till timeout.", url, err,
@@ -942,6 +1285,11 @@ func pingPang(timeout time.Duration, url string, osmoChan chan string, startExec
 		}
 
 		messageType, message, err := webConn.ReadMessage()
+		if err != nil && localService != nil {
+			// The local service is only closed when ctrl exits
+			log.Printf("Go routine pingPang is done")
+			return
+		}
 		if err != nil {
 			log.Println("Failed to get message:", err)
 			data.WebsocketConnection.IsBroken = true
@@ -969,31 +1317,31 @@ func pingPang(timeout time.Duration, url string, osmoChan chan string, startExec
 			}
 			if clientInfo.Action == ActionExec {
 				log.Printf("Receive exec action")
-				err := sendUserExecStart(unixConn, clientInfo.EntryCommand)
+				err := sendUserExecStart(userConn, clientInfo.EntryCommand)
 				if err != nil {
 					log.Println("Error sending user exec start request", err)
 					continue
 				}
-				unixListener := listener.(*net.UnixListener)
-				unixListener.SetDeadline(time.Now().Add(cmdArgs.ExecTimeout))
-				execConn, err := listener.Accept()
+				execConn, err := acceptExecConn(listener, execConns, cmdArgs.ExecTimeout)
 				if err != nil {
 					log.Println("Error connect to user terminal", err)
 					continue
 				}
-				go ctrlUserExec(execConn, clientInfo.RouterAddress, clientInfo.Key,
+				go ctrlUserExec(execCtx, execConn, clientInfo.RouterAddress, clientInfo.Key,
 					clientInfo.Cookie, cmdArgs)
 			} else if clientInfo.Action == ActionPortForward {
 				log.Printf("Receive portforward action")
 				if clientInfo.UseUDP {
-					go userPortForwardUDP(
+					go userPortForwardUDP(execCtx,
 						clientInfo.RouterAddress, clientInfo.Key,
 						clientInfo.Cookie, clientInfo.TaskPort, cmdArgs)
 				} else {
-					go userPortForwardTCP(clientInfo.RouterAddress, clientInfo, cmdArgs, metricChan)
+					go userPortForwardTCP(execCtx, clientInfo.RouterAddress, clientInfo, cmdArgs,
+						metricChan)
 				}
 			} else if clientInfo.Action == ActionWebServer {
-				go userPortForwardTCP(clientInfo.RouterAddress, clientInfo, cmdArgs, metricChan)
+				go userPortForwardTCP(execCtx, clientInfo.RouterAddress, clientInfo, cmdArgs,
+					metricChan)
 			} else if clientInfo.Action == ActionBarrier {
 				log.Printf("Receive barrier action")
 				barrierMutex.Lock()
@@ -1012,7 +1360,13 @@ func pingPang(timeout time.Duration, url string, osmoChan chan string, startExec
 					log.Println("Skip restart action")
 					continue
 				}
-				go restartExec(osmoChan, startExecChan, restartChan, unixConn, cmdArgs, logQueue)
+				go func() {
+					err := restartExec(execCtx, osmoChan, startExecChan, userConn, cmdArgs,
+						logQueue)
+					if err != nil {
+						failAsync(err)
+					}
+				}()
 			} else if clientInfo.Action == ActionRsync {
 				osmoChan <- "Receive rsync action"
 				if !rsyncStatus.IsRunning() {
@@ -1024,86 +1378,159 @@ func pingPang(timeout time.Duration, url string, osmoChan chan string, startExec
 					clientInfo.TaskPort = int(common.RsyncPort)
 				}
 
-				go userPortForwardTCP(clientInfo.RouterAddress, clientInfo, cmdArgs, metricChan)
+				go userPortForwardTCP(execCtx, clientInfo.RouterAddress, clientInfo, cmdArgs,
+					metricChan)
 			}
 		}
 	}
 }
 
-// Wait until barrier has been met to restart user command
-func restartExec(osmoChan chan string, startExecChan chan bool, restartChan chan bool,
-	unixConn net.Conn, cmdArgs args.CtrlArgs, logQueue *common.CircularBuffer) {
+// Wait until barrier has been met to restart user command. A restart which is skipped is
+// reported, an error is only returned if the user command can not be started again.
+func restartExec(ctx context.Context, osmoChan chan string, startExecChan chan bool,
+	userConn *messages.Conn, cmdArgs args.CtrlArgs, logQueue *common.PriorityLogQueue) error {
 
-	err := json.NewEncoder(unixConn).Encode(messages.UserStopRequest())
+	// The user replies once its command is stopped
+	_, err := userConn.Call(ctx, messages.UserStopRequest(), messages.UserStopFinished)
 	if err != nil {
-		osmoChan <- "Failed to send stop request"
-		return
+		osmoChan <- fmt.Sprintf("Failed to stop the user command: %v", err)
+		return nil
 	}
-	<-restartChan
 
 	if cmdArgs.Barrier != "" {
-		barrier(osmoChan, startExecChan, cmdArgs.Barrier, logQueue)
+		if err := barrier(ctx, osmoChan, startExecChan, cmdArgs.Barrier, logQueue); err != nil {
+			osmoChan <- fmt.Sprintf("Skipping restart: %v", err)
+			return nil
+		}
 	}
 
-	err = json.NewEncoder(unixConn).Encode(messages.UserStartRequest())
+	err = userConn.Send(messages.UserStartRequest())
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-		panic(fmt.Sprintf("Failed to send request: %v\n", err))
+		return osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"Failed to send request: %v", err)
 	}
+	return nil
 }
 
-func copyFile(src string, dest string) {
+func copyFile(src string, dest string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.FILE_FAILED_CODE)
-		panic(fmt.Sprintf("File %s not found.", src))
+		return osmo_errors.Errorf(osmo_errors.FILE_FAILED_CODE, "File %s not found.", src)
 	}
 	defer srcFile.Close()
 	destFile, err := os.Create(dest)
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.FILE_FAILED_CODE)
-		panic(fmt.Sprintf("Failed to create file %s: %s", dest, err))
+		return osmo_errors.Errorf(osmo_errors.FILE_FAILED_CODE, "Failed to create file %s: %s",
+			dest, err)
 	}
 	defer destFile.Close()
 	if _, err = io.Copy(destFile, srcFile); err != nil {
-		osmo_errors.SetExitCode(osmo_errors.FILE_FAILED_CODE)
-		panic(fmt.Sprintf("Copy from %s to %s failed: %s", src, dest, err))
+		return osmo_errors.Errorf(osmo_errors.FILE_FAILED_CODE, "Copy from %s to %s failed: %s",
+			src, dest, err)
+	}
+	return nil
+}
+
+// copyInputConfig copies the config to download the input, task inputs use the service config
+func copyInputConfig(input data.InputType, userConfig string, serviceConfig string,
+	configLoc string) error {
+	if _, isTypeTask := input.(data.TaskInput); isTypeTask {
+		return copyFile(serviceConfig, configLoc)
 	}
+	return copyFile(userConfig, configLoc)
 }
 
-func downloadInputs(c net.Conn, inputs common.ArrayFlags, inputPath string,
+// queryInputSizes returns the size of each input, or nil if it is unknown until it is downloaded
+func queryInputSizes(ctx context.Context, inputs []data.InputOutput, cmdArgs args.CtrlArgs,
+	osmoChan chan string) ([]*int64, error) {
+	sizes := make([]*int64, len(inputs))
+	for i, input := range inputs {
+		inputInfo, isTypeInput := input.(data.InputType)
+		if !isTypeInput {
+			continue
+		}
+		err := copyInputConfig(inputInfo, cmdArgs.UserConfig, cmdArgs.ServiceConfig,
+			cmdArgs.ConfigLoc)
+		if err != nil {
+			return nil, err
+		}
+		size, known, err := data.QueryInputSize(ctx, inputInfo, osmoChan)
+		if err != nil {
+			return nil, err
+		}
+		if !known {
+			osmoChan <- fmt.Sprintf("Size of %s is unknown until it is downloaded",
+				input.GetLogInfo())
+			continue
+		}
+		sizes[i] = &size
+	}
+	return sizes, nil
+}
+
+// checkInputsDiskSpace checks that the input folder has space for the inputs and the output
+// folder has the minimum free space
+func checkInputsDiskSpace(sizes []*int64, cmdArgs args.CtrlArgs, osmoChan chan string) error {
+	var inputBytes int64
+	for _, size := range sizes {
+		if size != nil {
+			inputBytes += *size
+		}
+	}
+	return data.CheckDiskSpace([]data.DiskRequirement{
+		{Path: cmdArgs.InputPath, Bytes: inputBytes},
+		{Path: cmdArgs.OutputPath, Bytes: cmdArgs.PreflightMinFreeSpace},
+	}, osmoChan)
+}
+
+// preflightDiskSpace fails if the inputs do not fit on disk
+func preflightDiskSpace(ctx context.Context, inputs []data.InputOutput, cmdArgs args.CtrlArgs,
+	osmoChan chan string) error {
+	osmoChan <- "Checking disk space..."
+	sizes, err := queryInputSizes(ctx, inputs, cmdArgs, osmoChan)
+	if err != nil {
+		return err
+	}
+	return checkInputsDiskSpace(sizes, cmdArgs, osmoChan)
+}
+
+func downloadInputs(ctx context.Context, c net.Conn, inputs []data.InputOutput, inputPath string,
 	osmoChan chan string, metricChan chan metrics.Metric, retryId string,
-	groupName string, taskName string, userConfig string, serviceConfig string, configLoc string) {
+	groupName string, taskName string, userConfig string, serviceConfig string,
+	configLoc string) error {
 
 	inputType := "Downloading"
 	osmoChan <- inputType + " Start"
 
-	for inputIndex, line := range inputs {
-		log.Printf("%s %s", inputType, line)
-		osmoChan <- inputType + " " + data.ParseInputOutput(line).GetLogInfo()
-		inputType := data.ParseInputOutput(line)
-		inputInfo, isTypeInput := inputType.(data.InputType)
+	for inputIndex, input := range inputs {
+		log.Printf("%s %s", inputType, input.GetLogInfo())
+		osmoChan <- inputType + " " + input.GetLogInfo()
+		osmo_errors.SetTarget(input.GetLogInfo())
+		inputInfo, isTypeInput := input.(data.InputType)
 		if !isTypeInput {
-			osmo_errors.SetExitCode(osmo_errors.INVALID_INPUT_CODE)
-			panic("Incorrect Input: Output Received")
+			return osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+				"Incorrect Input: Output Received")
 		}
-		if _, isTypeTask := inputInfo.(data.TaskInput); isTypeTask {
-			copyFile(serviceConfig, configLoc)
-		} else {
-			copyFile(userConfig, configLoc)
+		if err := copyInputConfig(inputInfo, userConfig, serviceConfig, configLoc); err != nil {
+			return err
 		}
 
-		inputInfo.Download(c, inputPath, osmoChan,
+		err := inputInfo.Download(ctx, c, inputPath, osmoChan,
 			metricChan, retryId, groupName, taskName, inputIndex)
+		if err != nil {
+			return err
+		}
 	}
+	osmo_errors.SetTarget("")
 	log.Println("All Inputs Gathered")
 	osmoChan <- "All Inputs Gathered"
+	return nil
 }
 
-func uploadOutputs(c net.Conn, outputs common.ArrayFlags,
+func uploadOutputs(ctx context.Context, c net.Conn, outputs []data.InputOutput,
 	outputPath string, metadataFile string, osmoChan chan string,
 	metricChan chan metrics.Metric, retryId string, groupName string,
-	taskName string, userConfig string, serviceConfig string, configLoc string) {
+	taskName string, userConfig string, serviceConfig string, configLoc string) error {
 
 	osmoChan <- "Upload Start"
 
@@ -1114,50 +1541,52 @@ func uploadOutputs(c net.Conn, outputs common.ArrayFlags,
 	if isEmpty {
 		log.Println("No Files in Output Folder")
 		osmoChan <- "No Files in Output Folder"
-		return
+		return nil
 	}
 
-	for outputIndex, line := range outputs {
-		outputType := data.ParseInputOutput(line)
-		log.Printf("Uploading %s", line)
+	for outputIndex, outputType := range outputs {
+		log.Printf("Uploading %s", outputType.GetLogInfo())
 		osmoChan <- "Uploading " + outputType.GetLogInfo()
+		osmo_errors.SetTarget(outputType.GetLogInfo())
 
 		outputInfo, isTypeOutput := outputType.(data.OutputType)
 		if !isTypeOutput {
-			osmo_errors.SetExitCode(osmo_errors.INVALID_INPUT_CODE)
-			panic("Incorrect Output: Input Received")
+			return osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+				"Incorrect Output: Input Received")
 		}
 
+		config := userConfig
 		_, isTypeTask := outputInfo.(*data.TaskOutput)
 		_, isTypeKpi := outputInfo.(*data.KpiOutput)
 		if isTypeTask || isTypeKpi {
-			copyFile(serviceConfig, configLoc)
-		} else {
-			copyFile(userConfig, configLoc)
+			config = serviceConfig
+		}
+		if err := copyFile(config, configLoc); err != nil {
+			return err
 		}
 
 		if kpiInfo, isTypeKpi := outputInfo.(*data.KpiOutput); isTypeKpi {
 			kpiPath := outputPath + kpiInfo.Path
 			if _, err := os.Stat(kpiPath); errors.Is(err, os.ErrNotExist) {
 				osmoChan <- fmt.Sprintf("KPI file: %s does not exist", kpiPath)
-			} else {
-				// kpi file exists
-				outputInfo.UploadFolder(c, outputPath, osmoChan, metricChan, retryId, groupName,
-					taskName, outputType.GetUrlIdentifier(), outputIndex)
+				continue
 			}
-
-		} else {
-			outputInfo.UploadFolder(c, outputPath, osmoChan, metricChan, retryId, groupName,
-				taskName, outputType.GetUrlIdentifier(), outputIndex)
+		}
+		err := outputInfo.UploadFolder(ctx, c, outputPath, osmoChan, metricChan, retryId,
+			groupName, taskName, outputType.GetUrlIdentifier(), outputIndex)
+		if err != nil {
+			return err
 		}
 	}
 
+	osmo_errors.SetTarget("")
 	osmoChan <- "All Outputs Uploaded"
+	return nil
 }
 
-// Block until barrier has been met
-func barrier(osmoChan chan string, startExecChan chan bool,
-	barrierName string, logQueue *common.CircularBuffer) {
+// Block until barrier has been met or ctx is done
+func barrier(ctx context.Context, osmoChan chan string, startExecChan chan bool,
+	barrierName string, logQueue *common.PriorityLogQueue) error {
 
 	osmoChan <- "Waiting for group ready ..."
 	barrierMutex.Lock()
@@ -1167,35 +1596,36 @@ func barrier(osmoChan chan string, startExecChan chan bool,
 	ticker := time.NewTicker(BARRIER_TICKER_DURATION)
 	defer ticker.Stop()
 
-	threadsafeEnqueue(logQueue, barrierReq)
+	threadsafeEnqueue(logQueue, string(messages.Barrier), common.PriorityControl, barrierReq)
 	for {
 		select {
 		case <-startExecChan:
 			osmoChan <- "Group ready"
-			return
+			return nil
+		case <-ctx.Done():
+			// Stop answering barrier actions of the service
+			barrierMutex.Lock()
+			barrierReq = ""
+			barrierMutex.Unlock()
+			return fmt.Errorf("Stopped waiting for group %s: %w", barrierName, ctx.Err())
 		case <-ticker.C:
 			barrierMutex.Lock()
 			localBarrierReq := barrierReq
 			barrierMutex.Unlock()
 			if localBarrierReq != "" {
-				threadsafeEnqueue(logQueue, localBarrierReq)
+				threadsafeEnqueue(logQueue, string(messages.Barrier), common.PriorityControl,
+					localBarrierReq)
 				log.Println("Resent barrier request")
 			}
 		}
 	}
 }
 
-func sendCtrlFailed(unixConn net.Conn, failed *bool) {
+func sendCtrlFailed(userConn *messages.Conn, failed *bool) {
 	if *failed {
-		ctrlFailed, err := json.Marshal(messages.CtrlFailedRequest())
-		if err != nil {
-			osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-			panic(fmt.Sprintf("Failed to marshal request: %v\n", err))
-		}
-
-		if _, err := unixConn.Write(ctrlFailed); err != nil {
-			osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-			panic(fmt.Sprintf("Failed to send request: %v\n", err))
+		if err := userConn.Send(messages.CtrlFailedRequest()); err != nil {
+			osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+				"Failed to send the ctrl failed request: %w", err))
 		}
 	}
 }
@@ -1209,9 +1639,29 @@ func init() {
 }
 
 func main() {
+	// Save the termination record on exit, including the reason of a panic
+	defer osmo_errors.HandleExit()
+
 	cmdArgs := args.CtrlParse()
-	logQueue := common.NewCircularBuffer(cmdArgs.LogsBufferSize)
-	restartChan := make(chan bool)
+
+	// Canceled on termination to stop all in-flight work
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGTERM)
+	defer stopSignals()
+	// Canceled with the failure of a goroutine, which main then fails with
+	ctx, failAsync := context.WithCancelCause(ctx)
+	defer failAsync(nil)
+	// Canceled once the user command has finished to stop its port forwards and exec sessions
+	execCtx, cancelExec := context.WithCancel(ctx)
+	defer cancelExec()
+
+	logQueue := common.NewPriorityLogQueue(cmdArgs.LogsBufferSize, cmdArgs.LogsRateLimit,
+		cmdArgs.LogsBurst)
+	logRedactor = createLogRedactor(cmdArgs)
+	osmo_errors.SetRedactor(func(text string) string {
+		return logRedactor.Redact("termination", text)
+	})
+	userLogFormat = cmdArgs.LogFormat
 	osmoChan := make(chan string)
 	downloadChan := make(chan string)
 	uploadChan := make(chan string)
@@ -1221,6 +1671,8 @@ func main() {
 	stopPutLogs := make(chan bool)
 	stopSendLogs := make(chan bool)
 	data.DataTimeout = cmdArgs.DataTimeout
+	data.RetryPolicies = cmdArgs.RetryPolicies
+	data.ProgressInterval = cmdArgs.ProgressInterval
 	failedCtrl := true
 	data.WebsocketConnection = data.WebsocketConnectionInfo{
 		IsBroken: false, DisconnectStartTime: time.Now(), Timeout: cmdArgs.Timeout}
@@ -1230,25 +1682,27 @@ func main() {
 	// Oldest possible time to trigger a fetch for refresh token
 	tokenExpiration = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	// Save the exit code to the termination file in case of panic
-	defer osmo_errors.SaveExitCode()
+	// The dry run does not connect to osmo-user nor write a termination record
+	if cmdArgs.DryRun {
+		os.Exit(dryRun(ctx, cmdArgs))
+	}
 
 	if err := os.RemoveAll(cmdArgs.SocketPath); err != nil {
-		osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-		panic(err)
+		osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"Failed to remove the socket: %w", err))
 	}
 
 	listener, err := net.Listen("unix", cmdArgs.SocketPath)
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-		panic(fmt.Sprintf("listen error: %s", err))
+		osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"listen error: %w", err))
 	}
 	defer listener.Close()
 
 	{
 		if err := os.Chmod(cmdArgs.SocketPath, 0777); err != nil {
-			osmo_errors.SetExitCode(osmo_errors.MISC_FAILED_CODE)
-			panic(err)
+			osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.MISC_FAILED_CODE,
+				"Failed to change the socket permissions: %w", err))
 		}
 	}
 
@@ -1258,53 +1712,112 @@ func main() {
 
 	unixConn, err := listener.Accept()
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-		panic(fmt.Sprintf("accept error: %s", err))
+		osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"accept error: %w", err))
 	}
 	defer unixConn.Close()
-	defer sendCtrlFailed(unixConn, &failedCtrl)
 
-	log.Printf("Client connected [%s]", unixConn.RemoteAddr().Network())
+	userConn, err := messages.Accept(unixConn, messages.HandshakeTimeout, USER_RESUME_TIMEOUT)
+	if err != nil {
+		osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"handshake error: %w", err))
+	}
+	defer userConn.Close()
+	defer sendCtrlFailed(userConn, &failedCtrl)
+
+	log.Printf("Client connected [%s] with protocol version %d",
+		unixConn.RemoteAddr().Network(), userConn.Version())
+
+	// Later connections of the user introduce themselves after protocol version 1
+	var execConns chan net.Conn
+	if userConn.Version() > messages.LegacyProtocolVersion {
+		unixListener.SetDeadline(time.Time{})
+		execConns = make(chan net.Conn)
+		go acceptUserConns(listener, userConn, execConns)
+	}
+
+	if cmdArgs.LocalDir != "" {
+		// The local service replaces the workflow service, so no token is refreshed
+		localService, err = NewLocalService(cmdArgs)
+		if err != nil {
+			log.Println(err)
+			osmo_errors.Fail(err)
+		}
+		webConn = localService
+	} else {
+		if cmdArgs.Preflight {
+			if err := preflightService(ctx, cmdArgs); err != nil {
+				log.Println(err)
+				osmo_errors.Fail(err)
+			}
+		}
 
-	// Start a websocket connection to Workflow Service
-	connWorkflowService(cmdArgs.WorkflowServiceUrl.String(), cmdArgs)
+		// Start a websocket connection to Workflow Service
+		err := connWorkflowService(ctx, cmdArgs.WorkflowServiceUrl.String(), cmdArgs)
+		if err != nil {
+			log.Println(err)
+			osmo_errors.Fail(err)
+		}
+	}
 	defer webConn.Close() // Conn should stay alive until the process exits
 
 	waitGoRoutines.Add(2)
 	go putLogs(cmdArgs.LogSource, osmoChan, downloadChan,
 		uploadChan, stopPutLogs, metricChan, logQueue)
 
-	go pingPang(cmdArgs.Timeout, cmdArgs.WorkflowServiceUrl.String(), osmoChan, startExecChan,
-		restartChan, metricChan, unixConn, &logsFinished, cmdArgs, listener, logQueue)
+	go pingPang(ctx, execCtx, cmdArgs.Timeout, cmdArgs.WorkflowServiceUrl.String(), osmoChan,
+		startExecChan, metricChan, userConn, &logsFinished, cmdArgs, listener, execConns,
+		logQueue, failAsync)
 
 	go sendLogs(cmdArgs.LogSource, logQueue, logsPeriodMs, stopSendLogs)
 
-	sigintCatch := make(chan os.Signal, 1)
-	signal.Notify(sigintCatch, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigintCatch
-		os.Exit(1)
-	}()
-
-	// Validate data auth access before starting downloads/uploads
-	if err := data.ValidateInputsOutputsAccess(
-		cmdArgs.Inputs,
-		cmdArgs.Outputs,
-		cmdArgs.UserConfig,
-		osmoChan,
-	); err != nil {
-		osmo_errors.SetExitCode(osmo_errors.DATA_UNAUTHORIZED_CODE)
+	// Flush the logs before failing, so that the reason of the failure reaches the service
+	fail := func(err error) {
+		// The failure of a goroutine is the reason that main was canceled
+		var asyncErr *osmo_errors.ExitError
+		if errors.As(context.Cause(ctx), &asyncErr) {
+			err = asyncErr
+		}
 		stopPutLogs <- true
 		stopSendLogs <- true
 		waitGoRoutines.Wait()
-		panic(fmt.Sprintf("Data unauthorized: %v", err))
+		log.Println(enqueueLog(logQueue, cmdArgs.LogSource, err.Error(), messages.OSMOCtrl))
+		flushLogs(cmdArgs.LogSource, logQueue, FAIL_FLUSH_TIMEOUT)
+		osmo_errors.Fail(err)
+	}
+
+	// Validate all input and output specs before any data is transferred
+	inputs, outputs, problems := data.ParseInputsOutputs(cmdArgs.Inputs, cmdArgs.Outputs)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			osmoChan <- "Invalid data spec: " + problem
+		}
+		fail(osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Found %d problems in the input and output specs", len(problems)))
+	}
+
+	// Validate data auth access before starting downloads/uploads
+	allItems := append(append([]data.InputOutput{}, inputs...), outputs...)
+	if err := data.ValidateDataAccess(ctx, allItems, cmdArgs.UserConfig, osmoChan); err != nil {
+		fail(fmt.Errorf("Data unauthorized: %w", err))
+	}
+
+	// Fail before downloading if the inputs do not fit on disk
+	if cmdArgs.Preflight {
+		if err := preflightDiskSpace(ctx, inputs, cmdArgs, osmoChan); err != nil {
+			fail(err)
+		}
 	}
 
 	// Send files to be downloaded
+	osmo_errors.SetPhase(osmo_errors.DOWNLOAD_PHASE)
 	inputStartTime := time.Now().Format("2006-01-02 15:04:05.000")
-	downloadInputs(unixConn, cmdArgs.Inputs, cmdArgs.InputPath,
+	err = downloadInputs(ctx, unixConn, inputs, cmdArgs.InputPath,
 		downloadChan, metricChan, cmdArgs.RetryId, cmdArgs.GroupName,
 		cmdArgs.LogSource, cmdArgs.UserConfig, cmdArgs.ServiceConfig, cmdArgs.ConfigLoc)
+	if err != nil {
+		fail(err)
+	}
 	inputEndTime := time.Now().Format("2006-01-02 15:04:05.000")
 	downloadTimes := metrics.GroupMetrics{
 		RetryId:    cmdArgs.RetryId,
@@ -1316,59 +1829,116 @@ func main() {
 
 	// Synchronize tasks if in a group
 	if cmdArgs.Barrier != "" {
-		barrier(osmoChan, startExecChan, cmdArgs.Barrier, logQueue)
+		if err := barrier(ctx, osmoChan, startExecChan, cmdArgs.Barrier, logQueue); err != nil {
+			fail(err)
+		}
 	}
 
-	err = json.NewEncoder(unixConn).Encode(messages.ExecStartRequest(cmdArgs.OutputPath))
+	osmo_errors.SetPhase(osmo_errors.EXEC_PHASE)
+	err = userConn.Send(messages.ExecStartRequest(cmdArgs.OutputPath,
+		cmdArgs.RetryPolicies[common.CheckpointOperation]))
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.UNIX_MESSAGE_FAILED_CODE)
-		panic(fmt.Sprintf("Failed to send request: %v\n", err))
+		fail(osmo_errors.Errorf(osmo_errors.UNIX_MESSAGE_FAILED_CODE,
+			"Failed to send the exec start request: %w", err))
 	}
 
 	// Exec has begun so failure no longer needs to be sent
 	failedCtrl = false
 
+	// Watch the size of the outputs and checkpoints while the user command runs
+	stopQuota := make(chan bool)
+	quotaDone := make(chan bool)
+	quotaExceeded := false
+	if cmdArgs.OutputQuota > 0 {
+		paths := append([]string{cmdArgs.OutputPath}, cmdArgs.OutputQuotaPaths...)
+		quota := data.OutputQuota{
+			Paths:        append(paths, data.CheckpointPaths(cmdArgs.Checkpoints)...),
+			LimitBytes:   cmdArgs.OutputQuota,
+			WarnPercents: cmdArgs.OutputQuotaWarn,
+			Interval:     cmdArgs.OutputQuotaInterval,
+		}
+		go func() {
+			defer close(quotaDone)
+			if data.WatchOutputQuota(quota, osmoChan, stopQuota) && cmdArgs.OutputQuotaStop {
+				quotaExceeded = true
+				osmoChan <- "Stopping the user command because the output quota was exceeded"
+				if err := userConn.Send(messages.UserKillRequest()); err != nil {
+					osmoChan <- fmt.Sprintf("Failed to send kill request: %v", err)
+				}
+			}
+		}()
+	} else {
+		close(quotaDone)
+	}
+
+	// On termination, stop the user command and unblock the wait for it to finish
+	stopExecOnCancel := context.AfterFunc(ctx, func() {
+		if err := userConn.Send(messages.UserKillRequest()); err != nil {
+			log.Printf("Failed to send kill request: %v", err)
+		}
+		userConn.Interrupt()
+	})
+
 	// Get Message that Exec has finished
 	log.Println("Exec start")
-	decoder := json.NewDecoder(unixConn)
 execLogs:
 	for {
-		// Decode the response
-		var response messages.Request
-		if err := decoder.Decode(&response); err != nil {
-			osmoChan <- fmt.Sprintf("Failed to parse response: %v\n", err)
+		response, err := userConn.Receive()
+		if err != nil {
+			// The read is interrupted on termination
+			if ctx.Err() == nil {
+				osmoChan <- fmt.Sprintf("Failed to parse response: %v\n", err)
+			}
 			break execLogs
 		}
 
 		switch response.Type {
 		case messages.ExecFailed:
-			threadsafeEnqueue(logQueue,
-				messages.CreateLog(cmdArgs.LogSource, response.MessageErr, messages.StdErr))
+			enqueueLog(logQueue, cmdArgs.LogSource, response.MessageErr, messages.StdErr)
 			break execLogs
 		case messages.ExecFinished:
 			break execLogs
 		case messages.UserRsyncStatus:
 			rsyncStatus.SetRunning(response.RsyncRunning)
-		case messages.UserStopFinished:
-			restartChan <- true
+		case messages.UserCheckpoint:
+			putCheckpointMetrics(metricChan, cmdArgs, response.Checkpoint)
+		case messages.UserResources:
+			putResourceMetrics(metricChan, cmdArgs, response.ResourceUsage)
+		case messages.UserRestart:
+			enqueueLog(logQueue, cmdArgs.LogSource, response.MessageOps, messages.OSMOCtrl)
+			putRestartMetrics(metricChan, cmdArgs, response.Restart)
+		case messages.UserReadiness:
+			enqueueLog(logQueue, cmdArgs.LogSource, response.MessageOps, messages.OSMOCtrl)
+			threadsafeEnqueue(logQueue, string(messages.Readiness), common.PriorityControl,
+				messages.CreateReadiness(response.Ready))
 		case messages.MessageOut:
-			threadsafeEnqueue(logQueue,
-				messages.CreateLog(cmdArgs.LogSource, response.MessageOut, messages.StdOut))
+			enqueueTaggedLog(logQueue, cmdArgs.LogSource, response.MessageOut, messages.StdOut,
+				response.Tag)
 		case messages.MessageErr:
-			threadsafeEnqueue(logQueue,
-				messages.CreateLog(cmdArgs.LogSource, response.MessageErr, messages.StdErr))
+			enqueueTaggedLog(logQueue, cmdArgs.LogSource, response.MessageErr, messages.StdErr,
+				response.Tag)
 		case messages.MessageOps:
-			threadsafeEnqueue(logQueue,
-				messages.CreateLog(cmdArgs.LogSource, response.MessageOps, messages.OSMOCtrl))
+			enqueueLog(logQueue, cmdArgs.LogSource, response.MessageOps, messages.OSMOCtrl)
 		}
 	}
 	log.Println("Exec finished")
+	stopExecOnCancel()
+	cancelExec()
+	close(stopQuota)
+	<-quotaDone // quotaExceeded is final once the watcher has returned
+	if ctx.Err() != nil {
+		fail(fmt.Errorf("Terminated while the user command was running: %w", ctx.Err()))
+	}
 
 	// Send files to be uploaded
+	osmo_errors.SetPhase(osmo_errors.UPLOAD_PHASE)
 	outputStartTime := time.Now().Format("2006-01-02 15:04:05.000")
-	uploadOutputs(unixConn, cmdArgs.Outputs, cmdArgs.OutputPath, cmdArgs.MetadataFile,
+	err = uploadOutputs(ctx, unixConn, outputs, cmdArgs.OutputPath, cmdArgs.MetadataFile,
 		uploadChan, metricChan, cmdArgs.RetryId, cmdArgs.GroupName, cmdArgs.LogSource,
 		cmdArgs.UserConfig, cmdArgs.ServiceConfig, cmdArgs.ConfigLoc)
+	if err != nil {
+		fail(err)
+	}
 	outputEndTime := time.Now().Format("2006-01-02 15:04:05.000")
 	uploadTimes := metrics.GroupMetrics{
 		RetryId:    cmdArgs.RetryId,
@@ -1376,11 +1946,18 @@ execLogs:
 		EndTime:    outputEndTime,
 		MetricType: "output_upload"}
 	metricChan <- uploadTimes
+	if quotaExceeded {
+		fail(osmo_errors.Errorf(osmo_errors.OUTPUT_QUOTA_EXCEEDED_CODE,
+			"The user command was stopped because the output quota was exceeded"))
+	}
 
+	osmo_errors.SetPhase(osmo_errors.FINISH_PHASE)
 	logMsg := messages.CreateLog(cmdArgs.LogSource, "", messages.LogDone)
 	for !logsFinished {
-		threadsafeEnqueue(logQueue, logMsg)
-		time.Sleep(5 * time.Second)
+		threadsafeEnqueue(logQueue, string(messages.LogDone), common.PriorityDone, logMsg)
+		if err := common.SleepContext(ctx, 5*time.Second); err != nil {
+			fail(fmt.Errorf("Terminated while finishing the logs: %w", err))
+		}
 	}
 
 	log.Println("Stopping logs")
@@ -0,0 +1,155 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/args"
+	"go.corp.nvidia.com/osmo/runtime/pkg/data"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+// DryRunCheck is the result of a phase of the dry run
+type DryRunCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// DryRunData describes an input or output of the task
+type DryRunData struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	// Only known for task and url inputs
+	SizeInBytes *int64 `json:"size_in_bytes,omitempty"`
+}
+
+// DryRunReport is printed to stdout by the dry run
+type DryRunReport struct {
+	Passed      bool          `json:"passed"`
+	Inputs      []DryRunData  `json:"inputs"`
+	Outputs     []DryRunData  `json:"outputs"`
+	Checkpoints int           `json:"checkpoints"`
+	Checks      []DryRunCheck `json:"checks"`
+}
+
+func (r *DryRunReport) addCheck(name string, err error) {
+	check := DryRunCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		check.Code = int(osmo_errors.CodeOf(err))
+		check.Message = err.Error()
+		r.Passed = false
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// exitCode returns the exit code of the first failed check
+func (r *DryRunReport) exitCode() int {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return check.Code
+		}
+	}
+	return 0
+}
+
+func describeData(items []data.InputOutput) []DryRunData {
+	described := make([]DryRunData, 0, len(items))
+	for _, item := range items {
+		described = append(described,
+			DryRunData{Name: item.GetLogInfo(), Url: item.GetUrlIdentifier()})
+	}
+	return described
+}
+
+// dryRun validates the configuration of the task without connecting to osmo-user, and prints
+// a report. It returns the exit code of the first failed check.
+func dryRun(ctx context.Context, cmdArgs args.CtrlArgs) int {
+	// Messages are logged to stderr, so that stdout only has the report
+	osmoChan := make(chan string)
+	go func() {
+		for message := range osmoChan {
+			log.Println(message)
+		}
+	}()
+	defer close(osmoChan)
+
+	report := DryRunReport{Passed: true, Checkpoints: len(cmdArgs.Checkpoints)}
+	inputs, outputs, problems := data.ParseInputsOutputs(cmdArgs.Inputs, cmdArgs.Outputs)
+	problems = append(problems, data.ParseCheckpoints(cmdArgs.Checkpoints)...)
+	if len(problems) > 0 {
+		report.addCheck("specs", osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Found %d problems in the specs: %s", len(problems), strings.Join(problems, "; ")))
+		return printDryRunReport(report)
+	}
+	report.addCheck("specs", nil)
+	report.Inputs = describeData(inputs)
+	report.Outputs = describeData(outputs)
+
+	// The websocket is only dialed once the service accepts the refresh token. There is no
+	// service to check in local mode.
+	if cmdArgs.LocalDir == "" {
+		err := preflightService(ctx, cmdArgs)
+		report.addCheck("service", err)
+		if err == nil {
+			report.addCheck("websocket", dialServiceOnce(ctx, cmdArgs))
+		}
+	}
+
+	report.addCheck("access", data.ValidateInputsOutputsAccess(ctx, cmdArgs.Inputs,
+		cmdArgs.Outputs, cmdArgs.UserConfig, osmoChan))
+
+	sizes, err := queryInputSizes(ctx, inputs, cmdArgs, osmoChan)
+	report.addCheck("sizes", err)
+	if err == nil {
+		for i, size := range sizes {
+			report.Inputs[i].SizeInBytes = size
+		}
+		report.addCheck("disk", checkInputsDiskSpace(sizes, cmdArgs, osmoChan))
+	}
+	return printDryRunReport(report)
+}
+
+// dialServiceOnce checks that the websocket connection to the service can be opened
+func dialServiceOnce(ctx context.Context, cmdArgs args.CtrlArgs) error {
+	dialer := serviceDialer()
+	conn, _, err := dialer.DialContext(ctx, cmdArgs.WorkflowServiceUrl.String(),
+		serviceHeaders(cmdArgs))
+	if err != nil {
+		return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+			"Failed to connect to websocket %s: %s", cmdArgs.WorkflowServiceUrl.String(), err)
+	}
+	return conn.Close()
+}
+
+func printDryRunReport(report DryRunReport) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("Failed to print the dry run report: %v", err)
+		return int(osmo_errors.MISC_FAILED_CODE)
+	}
+	return report.exitCode()
+}
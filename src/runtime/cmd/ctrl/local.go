@@ -0,0 +1,439 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/args"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+
+	"github.com/gorilla/websocket"
+)
+
+const LOCAL_BARRIER_POLL_DURATION = time.Second
+
+// Exec clients of the local port are raw TCP streams, so the initial size of their terminal is
+// sent on their behalf
+const localExecTerminalSize = `{"rows": 24, "cols": 80}`
+
+// Set in local mode, where it replaces the workflow service and the router
+var localService *LocalService
+
+type localRequest struct {
+	messageType int
+	data        []byte
+}
+
+// LocalService is the connection to the service in local mode. The logs and metrics sent to
+// it are written as JSONL files, barriers are met once the group size of tasks sharing its
+// directory, workflow and retry id have reached them, the readiness of the user command is the
+// presence of a ready file, and the clients of the local exec and port forward ports are
+// connected as router connections.
+type LocalService struct {
+	dir       string
+	task      string
+	groupSize int
+	logs      *os.File
+	metrics   *os.File
+	listeners []net.Listener
+
+	// Barriers of the workflow and retry id, so that a new run does not meet the old rounds
+	barrierDir string
+
+	// Service requests read by pingPang
+	requests  chan localRequest
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mutex    sync.Mutex
+	rounds   map[string]int        // Number of times the task has reached each barrier
+	waiting  map[string]bool       // Barriers the task is waiting for
+	conns    map[string]RouterConn // Connections waiting for connectRouter
+	nextKey  int
+	logsDone bool
+}
+
+// NewLocalService creates the files of the task in the local directory and listens on the
+// exec and port forward ports
+func NewLocalService(cmdArgs args.CtrlArgs) (*LocalService, error) {
+	task := cmdArgs.LogSource
+	if task == "" {
+		task = "task"
+	}
+	taskDir := filepath.Join(cmdArgs.LocalDir, task)
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		return nil, osmo_errors.Errorf(osmo_errors.FILE_FAILED_CODE,
+			"Unable to create the local directory %s: %s", taskDir, err)
+	}
+
+	service := &LocalService{
+		dir:  cmdArgs.LocalDir,
+		task: task,
+		barrierDir: filepath.Join(cmdArgs.LocalDir, "barriers", cmdArgs.Workflow,
+			cmdArgs.RetryId),
+		groupSize: cmdArgs.LocalGroupSize,
+		requests:  make(chan localRequest, 16),
+		closed:    make(chan struct{}),
+		rounds:    make(map[string]int),
+		waiting:   make(map[string]bool),
+		conns:     make(map[string]RouterConn),
+	}
+	var err error
+	openFlags := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+	if service.logs, err = os.OpenFile(filepath.Join(taskDir, "logs.jsonl"), openFlags,
+		0644); err != nil {
+		service.Close()
+		return nil, osmo_errors.NewExitError(osmo_errors.FILE_FAILED_CODE, err)
+	}
+	if service.metrics, err = os.OpenFile(filepath.Join(taskDir, "metrics.jsonl"), openFlags,
+		0644); err != nil {
+		service.Close()
+		return nil, osmo_errors.NewExitError(osmo_errors.FILE_FAILED_CODE, err)
+	}
+
+	if cmdArgs.LocalExecPort > 0 {
+		listener, err := service.listen(cmdArgs.LocalExecPort)
+		if err != nil {
+			return nil, err
+		}
+		go service.serveExec(listener, cmdArgs.LocalExecCommand)
+	}
+	for _, forward := range cmdArgs.LocalPortForwards {
+		listener, err := service.listen(forward.LocalPort)
+		if err != nil {
+			return nil, err
+		}
+		conn := &localForwardConn{
+			Listener: listener,
+			clients:  make(chan []byte),
+			done:     make(chan struct{}),
+		}
+		request := ServiceRequest{
+			Action:   ActionPortForward,
+			Key:      service.register("portforward", conn),
+			TaskPort: forward.TaskPort,
+		}
+		go service.servePortForward(conn)
+		go service.request(websocket.BinaryMessage, request)
+	}
+	log.Printf("Local mode: writing logs and metrics to %s", taskDir)
+	return service, nil
+}
+
+func (s *LocalService) listen(port int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		s.Close()
+		return nil, osmo_errors.Errorf(osmo_errors.MISC_FAILED_CODE,
+			"Unable to listen on local port %d: %s", port, err)
+	}
+	s.listeners = append(s.listeners, listener)
+	log.Printf("Local mode: listening on %s", listener.Addr())
+	return listener, nil
+}
+
+// register keeps the connection until it is connected with the returned key
+func (s *LocalService) register(prefix string, conn RouterConn) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextKey++
+	key := fmt.Sprintf("%s-%d", prefix, s.nextKey)
+	s.conns[key] = conn
+	return key
+}
+
+// connectRouter returns the connection registered with the key
+func (s *LocalService) connectRouter(key string) (RouterConn, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	conn, ok := s.conns[key]
+	if !ok {
+		return nil, fmt.Errorf("No local connection with key %s", key)
+	}
+	delete(s.conns, key)
+	return conn, nil
+}
+
+// request queues a service request for pingPang
+func (s *LocalService) request(messageType int, request ServiceRequest) {
+	requestJson, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("Local mode: failed to marshal request: %v", err)
+		return
+	}
+	select {
+	case s.requests <- localRequest{messageType, requestJson}:
+	case <-s.closed:
+	}
+}
+
+func (s *LocalService) serveExec(listener net.Listener, command string) {
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		key := s.register("exec",
+			&localClientConn{Conn: client, pending: []byte(localExecTerminalSize)})
+		log.Printf("Local mode: exec session %s from %s", key, client.RemoteAddr())
+		s.request(websocket.BinaryMessage,
+			ServiceRequest{Action: ActionExec, Key: key, EntryCommand: command})
+	}
+}
+
+func (s *LocalService) servePortForward(conn *localForwardConn) {
+	for {
+		client, err := conn.Accept()
+		if err != nil {
+			return
+		}
+		message, err := json.Marshal(PortForwardMessage{
+			Key:  s.register("portforward", &localClientConn{Conn: client}),
+			Type: PortForwardTCP,
+		})
+		if err != nil {
+			client.Close()
+			continue
+		}
+		select {
+		case conn.clients <- message:
+		case <-conn.done:
+			client.Close()
+			return
+		}
+	}
+}
+
+// WriteJSON handles a message sent to the service
+func (s *LocalService) WriteJSON(v interface{}) error {
+	message, ok := v.(string)
+	if !ok {
+		messageJson, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		message = string(messageJson)
+	}
+
+	var header struct {
+		IOType messages.IOType
+	}
+	if err := json.Unmarshal([]byte(message), &header); err != nil {
+		return err
+	}
+	switch header.IOType {
+	case messages.IOType(metrics.Metrics):
+		return s.writeLine(s.metrics, message)
+	case messages.Barrier:
+		var barrierRequest messages.BarrierRequest
+		if err := json.Unmarshal([]byte(message), &barrierRequest); err != nil {
+			return err
+		}
+		return s.reachBarrier(barrierRequest.Name)
+	case messages.Readiness:
+		var readinessRequest messages.ReadinessRequest
+		if err := json.Unmarshal([]byte(message), &readinessRequest); err != nil {
+			return err
+		}
+		return s.setReady(readinessRequest.Ready)
+	case messages.LogDone:
+		s.mutex.Lock()
+		logsDone := s.logsDone
+		s.logsDone = true
+		s.mutex.Unlock()
+		if !logsDone {
+			s.request(websocket.TextMessage, ServiceRequest{Action: ActionLogDone})
+		}
+		return nil
+	default:
+		return s.writeLine(s.logs, message)
+	}
+}
+
+// setReady creates the ready file of the task while the user command is ready
+func (s *LocalService) setReady(ready bool) error {
+	readyFile := filepath.Join(s.dir, s.task, "ready")
+	if !ready {
+		if err := os.Remove(readyFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(readyFile, nil, 0644)
+}
+
+func (s *LocalService) writeLine(file *os.File, line string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err := file.WriteString(line + "\n")
+	return err
+}
+
+// reachBarrier marks the task as ready in the next round of the barrier. Resent requests are
+// ignored while the task waits for the group.
+func (s *LocalService) reachBarrier(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.waiting[name] {
+		return nil
+	}
+
+	roundDir := filepath.Join(s.barrierDir, name, strconv.Itoa(s.rounds[name]))
+	if err := os.MkdirAll(roundDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(roundDir, s.task), nil, 0644); err != nil {
+		return err
+	}
+	s.rounds[name]++
+	s.waiting[name] = true
+	go s.waitBarrier(name, roundDir)
+	return nil
+}
+
+// waitBarrier requests the barrier action once the group size of tasks are ready
+func (s *LocalService) waitBarrier(name string, roundDir string) {
+	ticker := time.NewTicker(LOCAL_BARRIER_POLL_DURATION)
+	defer ticker.Stop()
+	for {
+		entries, err := os.ReadDir(roundDir)
+		if err != nil {
+			log.Printf("Local mode: failed to read barrier %s: %v", name, err)
+		} else if len(entries) >= s.groupSize {
+			s.mutex.Lock()
+			delete(s.waiting, name)
+			s.mutex.Unlock()
+			s.request(websocket.BinaryMessage, ServiceRequest{Action: ActionBarrier})
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// WriteControl is a no-op since the local service is never disconnected
+func (s *LocalService) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	return nil
+}
+
+// ReadMessage blocks until a service request is queued or the service is closed
+func (s *LocalService) ReadMessage() (int, []byte, error) {
+	select {
+	case request := <-s.requests:
+		return request.messageType, request.data, nil
+	case <-s.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (s *LocalService) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		for _, listener := range s.listeners {
+			listener.Close()
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, conn := range s.conns {
+			conn.Close()
+		}
+		if s.logs != nil {
+			s.logs.Close()
+		}
+		if s.metrics != nil {
+			s.metrics.Close()
+		}
+	})
+	return nil
+}
+
+// localClientConn is a client of a local port, read and written as binary router messages
+type localClientConn struct {
+	net.Conn
+	pending []byte // Read before the data of the client
+}
+
+func (c *localClientConn) ReadMessage() (int, []byte, error) {
+	if c.pending != nil {
+		pending := c.pending
+		c.pending = nil
+		return websocket.BinaryMessage, pending, nil
+	}
+	buffer := make([]byte, BUFFERSIZE)
+	n, err := c.Read(buffer)
+	if err != nil {
+		return 0, nil, err
+	}
+	return websocket.BinaryMessage, buffer[:n], nil
+}
+
+func (c *localClientConn) WriteMessage(messageType int, data []byte) error {
+	_, err := c.Write(data)
+	return err
+}
+
+// localForwardConn is the router connection of a local port forward, which reads a port
+// forward message for each client of the port
+type localForwardConn struct {
+	net.Listener
+	clients   chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *localForwardConn) ReadMessage() (int, []byte, error) {
+	select {
+	case message := <-c.clients:
+		return websocket.TextMessage, message, nil
+	case <-c.done:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *localForwardConn) WriteMessage(messageType int, data []byte) error {
+	return nil
+}
+
+func (c *localForwardConn) LocalAddr() net.Addr {
+	return c.Addr()
+}
+
+func (c *localForwardConn) RemoteAddr() net.Addr {
+	return c.Addr()
+}
+
+func (c *localForwardConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return c.Listener.Close()
+}
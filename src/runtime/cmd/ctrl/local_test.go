@@ -0,0 +1,79 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/args"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+)
+
+// ---------------------------------------------------------------------------
+// Local barriers
+// ---------------------------------------------------------------------------
+
+func newTestLocalService(t *testing.T, dir string, task string, retryId string) *LocalService {
+	t.Helper()
+	service, err := NewLocalService(args.CtrlArgs{LocalDir: dir, LogSource: task,
+		LocalGroupSize: 2, Workflow: "wf", RetryId: retryId})
+	if err != nil {
+		t.Fatalf("NewLocalService: %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+func reachTestBarrier(t *testing.T, service *LocalService) {
+	t.Helper()
+	if err := service.WriteJSON(messages.CreateBarrier("group", 0)); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+}
+
+// barrierMet returns whether the barrier action is requested before the timeout
+func barrierMet(service *LocalService, timeout time.Duration) bool {
+	select {
+	case <-service.requests:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestLocalService_BarrierIgnoresPreviousRun(t *testing.T) {
+	dir := t.TempDir()
+	// Both tasks of the previous run reached the barrier
+	reachTestBarrier(t, newTestLocalService(t, dir, "a", "0"))
+	reachTestBarrier(t, newTestLocalService(t, dir, "b", "0"))
+
+	retried := newTestLocalService(t, dir, "a", "1")
+	reachTestBarrier(t, retried)
+	if barrierMet(retried, LOCAL_BARRIER_POLL_DURATION/2) {
+		t.Fatal("the barrier was met by the tasks of the previous run")
+	}
+
+	other := newTestLocalService(t, dir, "b", "1")
+	reachTestBarrier(t, other)
+	if !barrierMet(retried, 3*LOCAL_BARRIER_POLL_DURATION) ||
+		!barrierMet(other, 3*LOCAL_BARRIER_POLL_DURATION) {
+		t.Fatal("the barrier was not met once both tasks of the run reached it")
+	}
+}
@@ -0,0 +1,201 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+)
+
+// Maximum time between two restarts of a sidecar
+const maxSidecarBackoff = 30 * time.Second
+
+// A sidecar running for this long is restarted without backoff
+const sidecarStableDuration = time.Minute
+
+// Canceled by killUserCommand to stop the pre-start hooks
+var preHooksCtx, cancelPreHooks = context.WithCancel(context.Background())
+
+// createTaggedLogsStreams streams the output of a hook or sidecar with its tag
+func createTaggedLogsStreams(outChan chan messages.Request, errChan chan messages.Request,
+	tag string) (func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool),
+	func(*bufio.Scanner, *sync.WaitGroup)) {
+	streamOutLogs := func(cmd *exec.Cmd, scanner *bufio.Scanner,
+		waitStreamLogs *sync.WaitGroup, timeoutChan chan bool) {
+		defer waitStreamLogs.Done()
+		for scanner.Scan() {
+			request := messages.MessageOutRequest(scanner.Text())
+			request.Tag = tag
+			outChan <- request
+		}
+		timeoutChan <- false
+	}
+	streamErrLogs := func(scanner *bufio.Scanner, waitStreamLogs *sync.WaitGroup) {
+		defer waitStreamLogs.Done()
+		for scanner.Scan() {
+			request := messages.MessageErrRequest(scanner.Text())
+			request.Tag = tag
+			errChan <- request
+		}
+	}
+	return streamOutLogs, streamErrLogs
+}
+
+// shellCommand runs the command with the shell in its own process group. Once ctx is done, the
+// group is sent SIGTERM, and the shell is killed if it has not exited after waitDelay.
+func shellCommand(ctx context.Context, command string, env []string,
+	waitDelay time.Duration) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// exitCodeOf returns the exit code reported to the post-exit hooks
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// runPreHooks runs the pre-start hooks in order and returns the failure of the first hook
+// which fails
+func runPreHooks(hooks []string, outChan chan messages.Request,
+	errChan chan messages.Request) (string, error) {
+	for i, hook := range hooks {
+		tag := fmt.Sprintf("pre-hook-%d", i)
+		log.Printf("Running %s: %s", tag, hook)
+		streamOut, streamErr := createTaggedLogsStreams(outChan, errChan, tag)
+		_, err := common.RunCommand(shellCommand(preHooksCtx, hook, nil, time.Second),
+			streamOut, streamErr)
+		if err != nil {
+			return fmt.Sprintf("Pre-start hook %d failed with error: %v\n", i, err), err
+		}
+	}
+	return "", nil
+}
+
+// runPostHooks runs all post-exit hooks with the exit code of the user command. Their failures
+// are logged without changing the result of the task.
+func runPostHooks(hooks []string, exitCode int, outChan chan messages.Request,
+	errChan chan messages.Request) {
+	env := []string{fmt.Sprintf("OSMO_EXIT_CODE=%d", exitCode)}
+	for i, hook := range hooks {
+		tag := fmt.Sprintf("post-hook-%d", i)
+		log.Printf("Running %s: %s", tag, hook)
+		streamOut, streamErr := createTaggedLogsStreams(outChan, errChan, tag)
+		_, err := common.RunCommand(shellCommand(context.Background(), hook, env, time.Second),
+			streamOut, streamErr)
+		if err != nil {
+			request := messages.MessageErrRequest(
+				fmt.Sprintf("Post-exit hook %d failed with error: %v", i, err))
+			request.Tag = tag
+			errChan <- request
+		}
+	}
+}
+
+// runPreStopHook runs the pre-stop hook before the user command is stopped. It is ended once
+// the grace period of the stop is over.
+func runPreStopHook(hook string, gracePeriod time.Duration, outChan chan messages.Request,
+	errChan chan messages.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	tag := "pre-stop-hook"
+	log.Printf("Running %s: %s", tag, hook)
+	streamOut, streamErr := createTaggedLogsStreams(outChan, errChan, tag)
+	_, err := common.RunCommand(shellCommand(ctx, hook, nil, time.Second), streamOut,
+		streamErr)
+	if err != nil {
+		request := messages.MessageErrRequest(
+			fmt.Sprintf("Pre-stop hook failed with error: %v", err))
+		request.Tag = tag
+		errChan <- request
+	}
+}
+
+// startSidecars supervises the sidecars until the returned function is called, which stops
+// them and waits for them to exit
+func startSidecars(sidecars []string, stopTimeout time.Duration, outChan chan messages.Request,
+	errChan chan messages.Request) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var waitSidecars sync.WaitGroup
+	for i, sidecar := range sidecars {
+		waitSidecars.Add(1)
+		go superviseSidecar(ctx, fmt.Sprintf("sidecar-%d", i), sidecar, stopTimeout, outChan,
+			errChan, &waitSidecars)
+	}
+	return func() {
+		cancel()
+		waitSidecars.Wait()
+	}
+}
+
+// superviseSidecar restarts the sidecar with an exponential backoff whenever it exits, until
+// ctx is done
+func superviseSidecar(ctx context.Context, tag string, command string,
+	stopTimeout time.Duration, outChan chan messages.Request, errChan chan messages.Request,
+	waitSidecars *sync.WaitGroup) {
+	defer waitSidecars.Done()
+	streamOut, streamErr := createTaggedLogsStreams(outChan, errChan, tag)
+	for restarts := 0; ; restarts++ {
+		log.Printf("Starting %s: %s", tag, command)
+		startTime := time.Now()
+		_, err := common.RunCommand(shellCommand(ctx, command, nil, stopTimeout), streamOut,
+			streamErr)
+		if ctx.Err() != nil {
+			log.Printf("Stopped %s", tag)
+			return
+		}
+
+		if time.Since(startTime) >= sidecarStableDuration {
+			restarts = 0
+		}
+		backoff := time.Duration(1<<min(restarts, 5)) * time.Second
+		backoff = min(backoff, maxSidecarBackoff)
+		request := messages.MessageErrRequest(
+			fmt.Sprintf("Sidecar exited with code %d. Restarting in %v", exitCodeOf(err),
+				backoff))
+		request.Tag = tag
+		errChan <- request
+		if common.SleepContext(ctx, backoff) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/args"
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+)
+
+// startProbes probes the user command until the returned function is called, once the command
+// has ended. Readiness changes are reported to ctrl, and a failed liveness probe terminates the
+// command so that the restart policy can restart it.
+func startProbes(cmdArgs args.ExecArgs, outChan chan messages.Request,
+	errChan chan messages.Request, statusChan chan messages.Request) func() {
+	stop := make(chan bool)
+	var waitProbes sync.WaitGroup
+	if cmdArgs.ReadinessProbe != nil {
+		waitProbes.Add(1)
+		go probeReadiness(*cmdArgs.ReadinessProbe, cmdArgs, statusChan, stop, &waitProbes)
+	}
+	if cmdArgs.LivenessProbe != nil {
+		waitProbes.Add(1)
+		go probeLiveness(*cmdArgs.LivenessProbe, cmdArgs, outChan, errChan, statusChan, stop,
+			&waitProbes)
+	}
+	return func() {
+		close(stop)
+		waitProbes.Wait()
+	}
+}
+
+// runProbe runs the probe every period after the initial delay and passes the number of
+// failures in a row to report, until stop is closed or report returns false
+func runProbe(probe common.Probe, cmdArgs args.ExecArgs, stop chan bool,
+	report func(failures int, err error) bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	timer := time.NewTimer(cmdArgs.ProbeInitialDelay)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		err := probe.Check(ctx, cmdArgs.ProbeTimeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			failures++
+		} else {
+			failures = 0
+		}
+		if !report(failures, err) {
+			return
+		}
+		timer.Reset(cmdArgs.ProbePeriod)
+	}
+}
+
+// probeReadiness reports the user command as not ready until the probe passes, and again once
+// it fails as many times in a row as the failure threshold or the command ends
+func probeReadiness(probe common.Probe, cmdArgs args.ExecArgs,
+	statusChan chan messages.Request, stop chan bool, waitProbes *sync.WaitGroup) {
+	defer waitProbes.Done()
+	ready := false
+	statusChan <- messages.UserReadinessRequest(
+		fmt.Sprintf("User command is not ready until readiness probe %s passes", probe), false)
+	runProbe(probe, cmdArgs, stop, func(failures int, err error) bool {
+		if err == nil && !ready {
+			ready = true
+			statusChan <- messages.UserReadinessRequest(
+				fmt.Sprintf("User command is ready: readiness probe %s passed", probe), true)
+		} else if failures >= cmdArgs.ProbeFailureThreshold && ready {
+			ready = false
+			statusChan <- messages.UserReadinessRequest(
+				fmt.Sprintf("User command is not ready: readiness probe %s failed %d times: %v",
+					probe, failures, err), false)
+		}
+		return true
+	})
+	if ready {
+		statusChan <- messages.UserReadinessRequest(
+			"User command is not ready: it has ended", false)
+	}
+}
+
+// probeLiveness terminates the user command once the probe fails as many times in a row as the
+// failure threshold
+func probeLiveness(probe common.Probe, cmdArgs args.ExecArgs, outChan chan messages.Request,
+	errChan chan messages.Request, statusChan chan messages.Request, stop chan bool,
+	waitProbes *sync.WaitGroup) {
+	defer waitProbes.Done()
+	runProbe(probe, cmdArgs, stop, func(failures int, err error) bool {
+		if failures < cmdArgs.ProbeFailureThreshold {
+			return true
+		}
+		statusChan <- messages.MessageOpsRequest(fmt.Sprintf("Liveness probe %s failed %d "+
+			"times: %v. Stopping the user command", probe, failures, err))
+		if command := userCommand.Load(); command != nil {
+			err := terminateUserCommand(command, stop, cmdArgs, outChan, errChan)
+			if err != nil {
+				log.Printf("Error sending stop signal: %s", err)
+			}
+		}
+		return false
+	})
+}
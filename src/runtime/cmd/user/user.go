@@ -32,6 +32,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -39,6 +40,7 @@ import (
 	"go.corp.nvidia.com/osmo/runtime/pkg/common"
 	"go.corp.nvidia.com/osmo/runtime/pkg/data"
 	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 	"go.corp.nvidia.com/osmo/runtime/pkg/rsync"
 
 	"github.com/creack/pty"
@@ -48,7 +50,20 @@ import (
 type Exit struct{ Code int }
 
 var waitUserCommands sync.WaitGroup
-var userCommand *exec.Cmd = nil
+
+// The running user command, read by the stop, kill, probe and sampling goroutines. It is only
+// set once the command is started, so that its process is visible to them.
+var userCommand atomic.Pointer[exec.Cmd]
+
+// Closed once runCommandWithReturnValues returns, after the user command and its restarts
+var userCommandDone chan bool
+var userCommandDoneMutex sync.Mutex
+
+// Set once ctrl stops or kills the user command, which is then not restarted by the policy
+var userCommandStopping atomic.Bool
+
+// Ends the wait before a restart by the policy
+var cancelRestart = make(chan bool, 1)
 
 // Executes all defered functions and exits with exit code
 func handleExit() {
@@ -89,10 +104,71 @@ func createErrLogsStream(errChan chan messages.Request) func(*bufio.Scanner, *sy
 	return streamErrLogs
 }
 
-func userExec(entryCommand string, socketPath string, historyFilePath string) {
+// scanFramedLogs passes the scanned tokens through the framer, sending idle entries until the
+// scanner is done
+func scanFramedLogs(scanner *bufio.Scanner, framer *common.LogFramer,
+	flushInterval time.Duration) {
+	tick := flushInterval / 2
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	done := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				framer.FlushIdle()
+			}
+		}
+	}()
+	for scanner.Scan() {
+		framer.Write(scanner.Text())
+	}
+	close(done)
+	framer.Flush()
+}
+
+func createFramedOutLogsStream(outChan chan messages.Request,
+	config common.LogFramerConfig) func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool) {
+	streamOutLogs := func(cmd *exec.Cmd, scanner *bufio.Scanner,
+		waitStreamLogs *sync.WaitGroup, timeoutChan chan bool) {
+		defer waitStreamLogs.Done()
+		framer := common.NewLogFramer(config, func(entry string) {
+			outChan <- messages.MessageOutRequest(entry)
+		})
+		scanFramedLogs(scanner, framer, config.FlushInterval)
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+		timeoutChan <- false
+	}
+	return streamOutLogs
+}
+
+func createFramedErrLogsStream(errChan chan messages.Request,
+	config common.LogFramerConfig) func(*bufio.Scanner, *sync.WaitGroup) {
+	streamErrLogs := func(scanner *bufio.Scanner, waitStreamLogs *sync.WaitGroup) {
+		defer waitStreamLogs.Done()
+		framer := common.NewLogFramer(config, func(entry string) {
+			errChan <- messages.MessageErrRequest(entry)
+		})
+		scanFramedLogs(scanner, framer, config.FlushInterval)
+		if err := scanner.Err(); err != nil {
+			errChan <- messages.MessageErrRequest(fmt.Sprintf("Error: %s", err))
+		}
+	}
+	return streamErrLogs
+}
+
+func userExec(userConn *messages.Conn, entryCommand string, socketPath string,
+	historyFilePath string) {
 	log.Printf("User Exec: Entry Command: %s", entryCommand)
 
-	conn, err := net.Dial("unix", socketPath)
+	conn, err := userConn.DialExec(socketPath)
 	if err != nil {
 		log.Println("User Exec: fail to connect to osmo-ctrl", err)
 		return
@@ -221,43 +297,57 @@ func userExec(entryCommand string, socketPath string, historyFilePath string) {
 }
 
 func receiveUserRequests(
-	unixConn net.Conn, outChan chan messages.Request, errChan chan messages.Request,
-	cmdArgs args.ExecArgs, execFinished *bool,
+	userConn *messages.Conn, outChan chan messages.Request, errChan chan messages.Request,
+	statusChan chan messages.Request, cmdArgs args.ExecArgs, execFinished *bool,
 	cmdMsg *string, cmdErr *error) {
 	for {
-		retryCount := 0
-		var response messages.Request
-		for {
-			decoder := json.NewDecoder(unixConn)
-			err := decoder.Decode(&response)
-			if err != nil {
-				if *execFinished {
-					return
-				}
-				log.Printf("Failed to parse response for user request: %v", err)
-				retryCount++
-				if retryCount >= 3 {
-					log.Println("Cannot connect to Ctrl Container. Exiting...")
-					return
-				}
-				// Connection failed, retry again
-				continue
+		response, err := userConn.Receive()
+		if err != nil {
+			if *execFinished {
+				return
 			}
-			// Connection succeeded and retrieved response, breaking to process response
-			break
+			log.Printf("Failed to receive user request: %v", err)
+			if !reconnectCtrl(userConn, cmdArgs.SocketPath) {
+				log.Println("Cannot connect to Ctrl Container. Exiting...")
+				return
+			}
+			continue
 		}
 		switch response.Type {
 		case messages.UserExecStart:
 			log.Println("Starting user exec...")
-			go userExec(response.Command, cmdArgs.SocketPath, cmdArgs.HistoryFilePath)
+			go userExec(userConn, response.Command, cmdArgs.SocketPath,
+				cmdArgs.HistoryFilePath)
 		case messages.UserStop:
-			log.Println("Killing user command...")
-			stopUserCommand(unixConn)
+			log.Println("Stopping user command...")
+			// The stop waits for the grace period, during which a kill must still be received
+			go stopUserCommand(userConn, response.Id, cmdArgs, outChan, errChan)
+		case messages.UserKill:
+			log.Println("Killing user command without restart...")
+			killUserCommand()
 		case messages.UserStart:
 			log.Println("Starting user command...")
-			go runCommandWithReturnValues(outChan, errChan, cmdArgs, cmdMsg, cmdErr)
+			go runCommandWithReturnValues(outChan, errChan, statusChan, cmdArgs, cmdMsg,
+				cmdErr)
+		}
+	}
+}
+
+// reconnectCtrl resumes the connection to ctrl, which is only possible in protocol version 2
+func reconnectCtrl(userConn *messages.Conn, path string) bool {
+	if !userConn.HasCapability(messages.CapabilityResume) {
+		return false
+	}
+	for retryCount := 0; retryCount < 3; retryCount++ {
+		err := userConn.Redial(func() (net.Conn, error) { return net.Dial("unix", path) })
+		if err == nil {
+			log.Println("Reconnected to Ctrl Container")
+			return true
 		}
+		log.Printf("Failed to reconnect to Ctrl Container: %v", err)
+		time.Sleep(time.Second)
 	}
+	return false
 }
 
 func connDataSidecar(path string, timeout time.Duration) net.Conn {
@@ -279,58 +369,255 @@ func connDataSidecar(path string, timeout time.Duration) net.Conn {
 	return unixConn
 }
 
-func stopUserCommand(unixConn net.Conn) {
-	if userCommand == nil {
-		return
+// runningUserCommand returns the channel closed once the user command and its restarts have
+// ended, or nil if none are running
+func runningUserCommand() chan bool {
+	userCommandDoneMutex.Lock()
+	done := userCommandDone
+	userCommandDoneMutex.Unlock()
+	if done == nil {
+		return nil
 	}
+	select {
+	case <-done:
+		return nil
+	default:
+		return done
+	}
+}
 
-	waitUserCommands.Add(1)
-	pgid, err := syscall.Getpgid(userCommand.Process.Pid)
-	if err == nil {
-		err = syscall.Kill(-pgid, syscall.SIGKILL)
+// signalProcessGroup sends the signal to the process group of the command
+func signalProcessGroup(command *exec.Cmd, signal syscall.Signal) error {
+	if command.Process == nil {
+		return fmt.Errorf("Process is not started")
 	}
+	pgid, err := syscall.Getpgid(command.Process.Pid)
 	if err != nil {
-		log.Printf("Error sending kill signal: %s", err)
-		waitUserCommands.Done()
+		return err
 	}
+	return syscall.Kill(-pgid, signal)
+}
 
-	// Wait for current command to be killed
-	for {
-		if userCommand == nil {
-			break
+// terminateUserCommand runs the pre-stop hook and sends the stop signal to the user command,
+// which is killed if it has not ended within the grace period. ended is closed once the
+// command has ended.
+func terminateUserCommand(command *exec.Cmd, ended chan bool, cmdArgs args.ExecArgs,
+	outChan chan messages.Request, errChan chan messages.Request) error {
+	deadline := time.Now().Add(cmdArgs.StopGracePeriod)
+	if cmdArgs.PreStopHook != "" {
+		runPreStopHook(cmdArgs.PreStopHook, cmdArgs.StopGracePeriod, outChan, errChan)
+	}
+	select {
+	case <-ended:
+		return nil
+	default:
+	}
+	if err := signalProcessGroup(command, cmdArgs.StopSignal); err != nil {
+		return err
+	}
+	select {
+	case <-ended:
+	case <-time.After(time.Until(deadline)):
+		log.Printf("User command did not stop within %v. Killing it...",
+			cmdArgs.StopGracePeriod)
+		if err := signalProcessGroup(command, syscall.SIGKILL); err != nil {
+			log.Printf("Error sending kill signal: %s", err)
 		}
-		time.Sleep(time.Second)
 	}
+	return nil
+}
+
+// stopUserCommand runs the pre-stop hook and sends the stop signal to the user command, which
+// is killed if it has not exited within the grace period. It replies to the stop request once
+// the command has ended. A pending restart by the policy is canceled.
+func stopUserCommand(userConn *messages.Conn, requestId uint64, cmdArgs args.ExecArgs,
+	outChan chan messages.Request, errChan chan messages.Request) {
+	done := runningUserCommand()
+	if done == nil {
+		return
+	}
+
+	waitUserCommands.Add(1)
+	userCommandStopping.Store(true)
+	select {
+	case cancelRestart <- true:
+	default:
+	}
+	if command := userCommand.Load(); command != nil {
+		if err := terminateUserCommand(command, done, cmdArgs, outChan, errChan); err != nil {
+			log.Printf("Error sending stop signal: %s", err)
+			waitUserCommands.Done()
+		}
+	}
+
+	// Wait for current command to end
+	<-done
 
 	log.Println("StopUserCommand sends UserStopFinishedRequest to Ctrl")
-	if err := json.NewEncoder(unixConn).Encode(messages.UserStopFinishedRequest()); err != nil {
+	if err := userConn.Reply(requestId, messages.UserStopFinishedRequest()); err != nil {
 		panic(fmt.Sprintf("Failed to send request: %v\n", err))
 	}
 }
 
+// killUserCommand ends the user command for good. Unlike stopUserCommand, the main routine is
+// not held back for a restart, so the command finishes as failed.
+func killUserCommand() {
+	cancelPreHooks()
+	userCommandStopping.Store(true)
+	select {
+	case cancelRestart <- true:
+	default:
+	}
+	command := userCommand.Load()
+	if command == nil || command.Process == nil {
+		return
+	}
+	if err := signalProcessGroup(command, syscall.SIGKILL); err != nil {
+		log.Printf("Error sending kill signal: %s", err)
+	}
+}
+
+// runCommandWithReturnValues runs the user command and restarts it as long as the restart
+// policy allows
 func runCommandWithReturnValues(
 	outChan chan messages.Request, errChan chan messages.Request,
-	cmdArgs args.ExecArgs, msg *string, err *error) {
+	statusChan chan messages.Request, cmdArgs args.ExecArgs, msg *string, err *error) {
 
 	defer waitUserCommands.Done()
-	userCommand = exec.Command(cmdArgs.Command, cmdArgs.Args...)
-	userCommand.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	*msg, *err = common.RunCommand(userCommand,
-		createOutLogsStream(outChan), createErrLogsStream(errChan))
-	userCommand = nil
+	done := make(chan bool)
+	userCommandDoneMutex.Lock()
+	userCommandDone = done
+	userCommandDoneMutex.Unlock()
+	defer close(done)
+	userCommandStopping.Store(false)
+	select {
+	case <-cancelRestart:
+	default:
+	}
+	for restarts := 0; ; restarts++ {
+		stopProbes := startProbes(cmdArgs, outChan, errChan, statusChan)
+		runUserCommand(outChan, errChan, cmdArgs, msg, err)
+		stopProbes()
+		if userCommandStopping.Load() || !restartAllowed(cmdArgs.RestartPolicy, *err) {
+			return
+		}
+		exitCode := exitCodeOf(*err)
+		if cmdArgs.MaxRestarts >= 0 && restarts >= cmdArgs.MaxRestarts {
+			message := fmt.Sprintf("User command exited with code %d. No restarts left after "+
+				"%d restarts", exitCode, restarts)
+			statusChan <- messages.MessageOpsRequest(message)
+			return
+		}
+
+		backoff := cmdArgs.RestartBackoff.Backoff(restarts)
+		message := fmt.Sprintf("User command exited with code %d. Restart %d in %v", exitCode,
+			restarts+1, backoff.Round(time.Millisecond))
+		if cmdArgs.MaxRestarts >= 0 {
+			message = fmt.Sprintf("User command exited with code %d. Restart %d of %d in %v",
+				exitCode, restarts+1, cmdArgs.MaxRestarts, backoff.Round(time.Millisecond))
+		}
+		statusChan <- messages.UserRestartRequest(message, messages.CommandRestart{
+			Time:     time.Now(),
+			Restart:  restarts + 1,
+			ExitCode: exitCode,
+			Backoff:  backoff,
+		})
+		select {
+		case <-time.After(backoff):
+		case <-cancelRestart:
+			return
+		}
+	}
+}
+
+// restartAllowed reports whether the policy restarts the user command which ended with err
+func restartAllowed(policy args.RestartPolicy, err error) bool {
+	switch policy {
+	case args.RestartAlways:
+		return true
+	case args.RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// runUserCommand runs the user command once and stores its result in msg and err
+func runUserCommand(
+	outChan chan messages.Request, errChan chan messages.Request,
+	cmdArgs args.ExecArgs, msg *string, err *error) {
+
+	command := exec.Command(cmdArgs.Command, cmdArgs.Args...)
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmdArgs.LogFraming {
+		*msg, *err = common.RunCommandWithSplit(command, common.ScanLogLines,
+			publishOnStart(createFramedOutLogsStream(outChan, cmdArgs.LogFramerConfig)),
+			createFramedErrLogsStream(errChan, cmdArgs.LogFramerConfig))
+	} else {
+		*msg, *err = common.RunCommand(command,
+			publishOnStart(createOutLogsStream(outChan)), createErrLogsStream(errChan))
+	}
+	userCommand.Store(nil)
+}
+
+// publishOnStart sets userCommand when the stdout stream of the command is started, which
+// happens after the command is started
+func publishOnStart(streamOut func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup,
+	chan bool)) func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool) {
+	return func(cmd *exec.Cmd, scanner *bufio.Scanner, waitStreamLogs *sync.WaitGroup,
+		timeoutChan chan bool) {
+		userCommand.Store(cmd)
+		streamOut(cmd, scanner, waitStreamLogs, timeoutChan)
+	}
+}
+
+// sampleResourceUsage reports the resource usage of the user command at every interval until
+// stopUsage is closed
+func sampleResourceUsage(interval time.Duration, outputFolder string,
+	usageChan chan messages.Request, stopUsage chan bool, waitUsage *sync.WaitGroup) {
+
+	defer waitUsage.Done()
+	sampler := common.NewResourceSampler(outputFolder)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopUsage:
+			return
+		case <-ticker.C:
+			command := userCommand.Load()
+			if command == nil || command.Process == nil {
+				continue
+			}
+			// The user command leads its own process group
+			usage := sampler.Sample(command.Process.Pid)
+			select {
+			case usageChan <- messages.UserResourcesRequest(usage):
+			case <-stopUsage:
+				return
+			}
+		}
+	}
 }
 
 func putUnixLogs(
-	unixConn net.Conn, outChan chan messages.Request,
-	errChan chan messages.Request, opsChan chan string, stopChan chan bool) {
+	userConn *messages.Conn, outChan chan messages.Request,
+	errChan chan messages.Request, opsChan chan string, checkpointChan chan messages.Request,
+	usageChan chan messages.Request, statusChan chan messages.Request, stopChan chan bool) {
 	for {
 		select {
 		case outMessage := <-outChan:
-			messages.EncodeMessage(unixConn, outMessage.MessageOut, outMessage)
+			messages.EncodeMessage(userConn, outMessage.MessageOut, outMessage)
 		case errMessage := <-errChan:
-			messages.EncodeMessage(unixConn, errMessage.MessageErr, errMessage)
+			messages.EncodeMessage(userConn, errMessage.MessageErr, errMessage)
 		case opsMessage := <-opsChan:
-			messages.EncodeMessage(unixConn, opsMessage, messages.MessageOpsRequest(opsMessage))
+			messages.EncodeMessage(userConn, opsMessage, messages.MessageOpsRequest(opsMessage))
+		case checkpointMessage := <-checkpointChan:
+			messages.EncodeMessage(userConn, "Checkpoint round finished", checkpointMessage)
+		case usageMessage := <-usageChan:
+			messages.EncodeMessage(userConn, "Resource usage sampled", usageMessage)
+		case statusMessage := <-statusChan:
+			messages.EncodeMessage(userConn, statusMessage.MessageOps, statusMessage)
 		case <-stopChan:
 			log.Printf("Go routine for sending to unixConn is done")
 			return
@@ -384,7 +671,11 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	cmdArgs := args.ExecParse()
+	cmdArgs, err := args.ExecParse()
+	if err != nil {
+		log.Println(err)
+		panic(Exit{int(osmo_errors.CodeOf(err))})
+	}
 
 	// Set PATH environment variable to include OSMO user binary path
 	// This cannot be done from Kubernetes spec because $PATH expansion is
@@ -404,12 +695,16 @@ func main() {
 
 	// Start a unix socket connection to Data Sidecar
 	unixConn := connDataSidecar(cmdArgs.SocketPath, cmdArgs.UnixTimeout)
-	defer unixConn.Close()
+	userConn, err := messages.Connect(unixConn)
+	if err != nil {
+		panic(fmt.Sprintf("Failed the handshake with Ctrl Container: %v\n", err))
+	}
+	defer userConn.Close()
+	log.Printf("Connected to Ctrl Container with protocol version %d", userConn.Version())
 
-	var response messages.Request
+	var response messages.Message
 	for {
-		decoder := json.NewDecoder(unixConn)
-		err := decoder.Decode(&response)
+		response, err = userConn.Receive()
 		if err != nil {
 			panic(fmt.Sprintf("Failed to parse response: %v\n", err))
 		}
@@ -417,6 +712,9 @@ func main() {
 		if response.Type == messages.CtrlFailed {
 			return
 		} else if response.Type == messages.ExecStart {
+			if response.CheckpointRetry != nil {
+				data.RetryPolicies[common.CheckpointOperation] = *response.CheckpointRetry
+			}
 			break
 		} else {
 			log.Printf("Ignore unexpected Type: %s", response.Type)
@@ -428,13 +726,17 @@ func main() {
 	outChan := make(chan messages.Request)
 	errChan := make(chan messages.Request)
 	opsChan := make(chan string)
+	checkpointChan := make(chan messages.Request)
+	usageChan := make(chan messages.Request)
+	statusChan := make(chan messages.Request)
 	stopChan := make(chan bool)
-	go putUnixLogs(unixConn, outChan, errChan, opsChan, stopChan)
+	go putUnixLogs(userConn, outChan, errChan, opsChan, checkpointChan, usageChan, statusChan,
+		stopChan)
 
 	var cmdMsg string
 	var cmdErr error = nil
 	var waitCheckpoint sync.WaitGroup
-	stopCheckpoint := false
+	stopCheckpoint := make(chan bool)
 
 	// Start a goroutine to run rsync if enabled
 	if cmdArgs.EnableRsync {
@@ -446,28 +748,73 @@ func main() {
 				cmdArgs.RsyncReadLimit,
 				cmdArgs.RsyncWriteLimit,
 				cmdArgs.RsyncPathAllowList,
-				unixConn,
+				userConn,
 			); err != nil {
 				log.Printf("Rsync failed with error: %v", err)
 			}
 		}()
 	}
 
+	// Let the user command request on-demand checkpoints
+	if len(cmdArgs.Checkpoint) > 0 {
+		os.Setenv("OSMO_CHECKPOINT_SOCKET",
+			filepath.Join(cmdArgs.RunLocation, data.CheckpointTriggerSocketName))
+		os.Setenv("OSMO_CHECKPOINT_TRIGGER_DIR",
+			filepath.Join(cmdArgs.RunLocation, data.CheckpointTriggerDirName))
+	}
+
 	// Start a goroutine to receive user requests
-	go receiveUserRequests(unixConn, outChan, errChan, cmdArgs, &execFinished,
+	go receiveUserRequests(userConn, outChan, errChan, statusChan, cmdArgs, &execFinished,
 		&cmdMsg, &cmdErr)
-	waitUserCommands.Add(1)
-	// Start the user command
-	go runCommandWithReturnValues(outChan, errChan, cmdArgs, &cmdMsg, &cmdErr)
+	// The user command is not started if a pre-start hook fails
+	cmdMsg, cmdErr = runPreHooks(cmdArgs.PreHooks, outChan, errChan)
+	commandStarted := cmdErr == nil
+	stopSidecars := func() {}
+	if commandStarted {
+		stopSidecars = startSidecars(cmdArgs.Sidecars, cmdArgs.SidecarStopTimeout, outChan,
+			errChan)
+		waitUserCommands.Add(1)
+		// Start the user command
+		go runCommandWithReturnValues(outChan, errChan, statusChan, cmdArgs, &cmdMsg,
+			&cmdErr)
+	}
+	// Sample the resource usage of the user command
+	stopUsage := make(chan bool)
+	var waitUsage sync.WaitGroup
+	if cmdArgs.ResourceUsageInterval > 0 {
+		waitUsage.Add(1)
+		go sampleResourceUsage(cmdArgs.ResourceUsageInterval, response.OutputFolder, usageChan,
+			stopUsage, &waitUsage)
+	}
 	// Begin checkpointing
-	for _, checkpoint := range cmdArgs.Checkpoint {
+	var checkpointTriggers []*data.CheckpointTrigger
+	for i, checkpoint := range cmdArgs.Checkpoint {
+		if problems := data.ValidateCheckpoint(checkpoint); len(problems) > 0 {
+			for _, problem := range problems {
+				opsChan <- fmt.Sprintf("Invalid checkpoint %d (%s): %s", i, checkpoint, problem)
+			}
+			continue
+		}
+		trigger := data.NewCheckpointTrigger(i, checkpoint)
+		checkpointTriggers = append(checkpointTriggers, trigger)
 		waitCheckpoint.Add(1)
-		go data.Checkpoint(opsChan, checkpoint, &waitCheckpoint, &stopCheckpoint)
+		go data.Checkpoint(ctx, opsChan, checkpointChan, checkpoint, trigger,
+			cmdArgs.CheckpointSettleDelay, &waitCheckpoint, stopCheckpoint)
 	}
+	go data.ServeCheckpointTriggers(ctx, cmdArgs.RunLocation, checkpointTriggers, opsChan)
 	waitUserCommands.Wait()
 	execFinished = true
-	stopCheckpoint = true
+	stopSidecars()
+	// Post-exit hooks only run for a user command which was started
+	if commandStarted {
+		runPostHooks(cmdArgs.PostHooks, exitCodeOf(cmdErr), outChan, errChan)
+	} else if len(cmdArgs.PostHooks) > 0 {
+		opsChan <- "Skipping post-exit hooks since the user command was not started"
+	}
+	close(stopCheckpoint)
 	waitCheckpoint.Wait()
+	close(stopUsage)
+	waitUsage.Wait()
 	stopChan <- true
 
 	// Make sure all output files are readable (add read bit for ugo)
@@ -484,7 +831,7 @@ func main() {
 	if cmdErr != nil {
 		log.Println(cmdErr)
 
-		if err := json.NewEncoder(unixConn).Encode(messages.ExecFailedRequest(cmdMsg)); err != nil {
+		if err := userConn.Send(messages.ExecFailedRequest(cmdMsg)); err != nil {
 			panic(fmt.Sprintf("Failed to send request: %v\n", err))
 		}
 		if exitErr, ok := cmdErr.(*exec.ExitError); ok {
@@ -492,7 +839,7 @@ func main() {
 		}
 		panic(fmt.Sprintf("Exec failed with error: %v\n", cmdErr))
 	} else {
-		if err := json.NewEncoder(unixConn).Encode(messages.ExecFinishedRequest()); err != nil {
+		if err := userConn.Send(messages.ExecFinishedRequest()); err != nil {
 			panic(fmt.Sprintf("Failed to send request: %v\n", err))
 		}
 	}
@@ -23,17 +23,22 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 )
 
 // Parse and process command line arguments
 func CtrlParse() CtrlArgs {
-	var inputs, outputs common.ArrayFlags
+	var inputs, outputs, checkpoints, quotaPaths, redactPatterns, redactEnv,
+		retryPolicies, localPortForwards common.ArrayFlags
 	flag.Var(&inputs, "inputs", "Pod inputs.")
 	flag.Var(&outputs, "outputs", "Pod outputs.")
+	flag.Var(&checkpoints, "checkpoint",
+		"Checkpoint information, validated by the dry run and counted against the output quota.")
 	workflow := flag.String("workflow", "", "Workflow id.")
 	barrier := flag.String("barrier", "", "Barrier name for synchronization. Default to no synchronization.")
 	logSource := flag.String("logSource", "", "Source of the messages.")
@@ -59,8 +64,53 @@ func CtrlParse() CtrlArgs {
 	retryId := flag.String("retryId", "0", "Retry ID of the task. Default to 0.")
 	logsPeriod := flag.Int("logsPeriod", 100, "How often OSMO control should push logs to the "+
 		"service (in milliseconds)")
-	logsBufferSize := flag.Int("logsBufferSize", 10000, "The capacity of circular buffer for "+
-		"storing messages.")
+	logsBufferSize := flag.Int("logsBufferSize", 10000, "The number of queued messages kept "+
+		"for each droppable log priority.")
+	logsRateLimit := flag.Float64("logsRateLimit", 0, "Maximum lines per second sent for each "+
+		"log stream. 0 disables the rate limit.")
+	logsBurst := flag.Float64("logsBurst", 1000, "Number of lines a log stream can send at once "+
+		"above the rate limit.")
+	outputQuota := flag.Int64("outputQuota", 0,
+		"Size quota (MiB) of the outputs, checkpoints and quota paths. 0 disables the quota.")
+	outputQuotaWarn := flag.String("outputQuotaWarn", "75,90",
+		"Comma separated percentages of the output quota at which to log a warning.")
+	outputQuotaStop := flag.Bool("outputQuotaStop", false,
+		"Stop the user command when the output quota is exceeded.")
+	flag.Var(&quotaPaths, "outputQuotaPath", "Additional path counted against the output quota.")
+	outputQuotaInterval := flag.Int("outputQuotaInterval", 30,
+		"Time (s) between output quota checks.")
+	flag.Var(&redactPatterns, "redactPattern",
+		"Regex of text to mask in task logs, in addition to the default patterns.")
+	flag.Var(&redactEnv, "redactEnv", "Name pattern of environment variables with values to mask "+
+		"in task logs, in addition to the default patterns.")
+	logFormat := flag.String("logFormat", string(common.LogFormatText), "Format of the user log "+
+		"lines parsed into levels and fields: text, json, logfmt or auto.")
+	flag.Var(&retryPolicies, "retryPolicy", "Retry policy setting of a data operation as "+
+		"<download|upload|auth|checkpoint>:<key>=<value>. Keys are attempts, initialBackoff, "+
+		"maxBackoff, multiplier, deadline, retryAll, exitCodes, waitExitCodes, output and "+
+		"servicePoll.")
+	progressInterval := flag.Int("progressInterval", 10, "Time (s) between progress metrics of "+
+		"the inputs and outputs being transferred. 0 disables the progress metrics.")
+	preflight := flag.Bool("preflight", true, "Check the disk space, the service connectivity "+
+		"and the refresh token before downloading the inputs.")
+	preflightTimeout := flag.Int("preflightTimeout", 120, "Time (s) to wait for the service to "+
+		"be reachable during the preflight checks.")
+	preflightMinFreeSpace := flag.Int64("preflightMinFreeSpace", 1024, "Free space (MiB) required "+
+		"on the output folder, in addition to the size of the inputs if it shares their disk.")
+	dryRun := flag.Bool("dryRun", false, "Validate the inputs, outputs and checkpoints, their "+
+		"access and sizes, and the service connection, then print a JSON report and exit "+
+		"without running the user command.")
+	localDir := flag.String("local", "", "Directory of the local service. When set, ctrl runs "+
+		"without the workflow service: logs and metrics are written as JSONL files to the "+
+		"directory and barriers are met by the tasks sharing it, the workflow and the retry id.")
+	localGroupSize := flag.Int("localGroupSize", 1, "Number of tasks meeting at a barrier in "+
+		"local mode.")
+	localExecPort := flag.Int("localExecPort", 0, "TCP port on 127.0.0.1 serving exec sessions "+
+		"in local mode. 0 disables exec.")
+	localExecCommand := flag.String("localExecCommand", "/bin/bash", "Command run by the exec "+
+		"sessions in local mode.")
+	flag.Var(&localPortForwards, "localPortForward", "Forward a TCP port on 127.0.0.1 to a port "+
+		"of the task in local mode, as <localPort>:<taskPort>.")
 	flag.Parse()
 
 	// logSource is also the name of the task in the workflow
@@ -95,9 +145,47 @@ func CtrlParse() CtrlArgs {
 		finalLogsBufferSize = 1
 	}
 
+	finalLogsBurst := *logsBurst
+	if finalLogsBurst < 1 {
+		finalLogsBurst = 1
+	}
+
+	var quotaWarn []int
+	for _, value := range strings.Split(*outputQuotaWarn, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		percent, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || percent <= 0 {
+			osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+				"Invalid output quota warning percentage: %s", value))
+		}
+		quotaWarn = append(quotaWarn, percent)
+	}
+
+	parsedLogFormat, err := common.ParseLogFormat(*logFormat)
+	if err != nil {
+		osmo_errors.Fail(osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err))
+	}
+
+	parsedRetryPolicies, err := common.ParseRetryPolicies(retryPolicies)
+	if err != nil {
+		osmo_errors.Fail(osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err))
+	}
+
+	parsedPortForwards, err := parseLocalPortForwards(localPortForwards)
+	if err != nil {
+		osmo_errors.Fail(osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err))
+	}
+	if *localGroupSize < 1 {
+		osmo_errors.Fail(osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Invalid local group size: %d", *localGroupSize))
+	}
+
 	parsedArgs := CtrlArgs{
 		Inputs:             inputs,
 		Outputs:            outputs,
+		Checkpoints:        checkpoints,
 		InputPath:          input,
 		OutputPath:         output,
 		SocketPath:         *socketPath,
@@ -120,6 +208,58 @@ func CtrlParse() CtrlArgs {
 		DataTimeout:        dataDuration,
 		LogsPeriod:         finalLogsPeriod,
 		LogsBufferSize:     finalLogsBufferSize,
+		LogsRateLimit:      *logsRateLimit,
+		LogsBurst:          finalLogsBurst,
+
+		// Output quota flags
+		OutputQuota:         *outputQuota * 1024 * 1024,
+		OutputQuotaWarn:     quotaWarn,
+		OutputQuotaStop:     *outputQuotaStop,
+		OutputQuotaPaths:    quotaPaths,
+		OutputQuotaInterval: time.Duration(*outputQuotaInterval) * time.Second,
+
+		// Log redaction flags
+		RedactPatterns: redactPatterns,
+		RedactEnv:      redactEnv,
+
+		LogFormat: parsedLogFormat,
+
+		RetryPolicies: parsedRetryPolicies,
+
+		ProgressInterval: time.Duration(*progressInterval) * time.Second,
+
+		// Preflight flags
+		Preflight:             *preflight,
+		PreflightTimeout:      time.Duration(*preflightTimeout) * time.Second,
+		PreflightMinFreeSpace: *preflightMinFreeSpace * 1024 * 1024,
+
+		DryRun: *dryRun,
+
+		// Local service flags
+		LocalDir:          *localDir,
+		LocalGroupSize:    *localGroupSize,
+		LocalExecPort:     *localExecPort,
+		LocalExecCommand:  *localExecCommand,
+		LocalPortForwards: parsedPortForwards,
 	}
 	return parsedArgs
 }
+
+// parseLocalPortForwards parses the <localPort>:<taskPort> forwards of the local mode
+func parseLocalPortForwards(values []string) ([]LocalPortForward, error) {
+	var forwards []LocalPortForward
+	for _, value := range values {
+		localPort, taskPort, found := strings.Cut(value, ":")
+		if !found {
+			return nil, fmt.Errorf("Invalid local port forward %s, expected <localPort>:<taskPort>",
+				value)
+		}
+		local, localErr := strconv.Atoi(localPort)
+		task, taskErr := strconv.Atoi(taskPort)
+		if localErr != nil || taskErr != nil || local <= 0 || task <= 0 {
+			return nil, fmt.Errorf("Invalid ports in local port forward %s", value)
+		}
+		forwards = append(forwards, LocalPortForward{LocalPort: local, TaskPort: task})
+	}
+	return forwards, nil
+}
@@ -20,14 +20,21 @@ package args
 
 import (
 	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 )
 
-// Parse and process command line arguments
-func ExecParse() ExecArgs {
-	var commands, args, checkpoint common.ArrayFlags
+// Parse and process command line arguments. Invalid flags are returned as an error with the
+// invalid input exit code.
+func ExecParse() (ExecArgs, error) {
+	var commands, args, checkpoint, preHooks, postHooks, sidecars common.ArrayFlags
 	flag.Var(&commands, "commands", "Pod commands.")
 	flag.Var(&args, "args", "Pod args.")
 	flag.Var(&checkpoint, "checkpoint", "Checkpoint information.")
@@ -37,10 +44,58 @@ func ExecParse() ExecArgs {
 	historyFilePath := flag.String(
 		"historyFilePath", "/osmo/data/.bash_history", "History file path.")
 	runLocation := flag.String("runLocation", "/osmo/run", "Run location.")
+	checkpointSettleDelay := flag.Int("checkpointSettleDelay", 0,
+		"Time (s) a checkpoint file must be unmodified before it is uploaded.")
+	resourceUsageInterval := flag.Int("resourceUsageInterval", 60,
+		"Time (s) between resource usage samples of the user command. 0 disables sampling.")
+	logFraming := flag.Bool("logFraming", true, "Group stack traces into single log entries and "+
+		"collapse lines rewritten with carriage returns.")
+	logMultilinePattern := flag.String("logMultilinePattern", common.DefaultContinuationPattern,
+		"Regex of lines appended to the previous log entry.")
+	logTracebackPattern := flag.String("logTracebackPattern", common.DefaultTracebackPattern,
+		"Regex of the first line of stack traces ended by the line naming the exception.")
+	logMaxLines := flag.Int("logMaxLines", 200, "Maximum number of lines in a log entry.")
+	logFlushInterval := flag.Int("logFlushInterval", 500,
+		"Time (ms) without new lines after which a pending log entry is sent.")
+	logProgressInterval := flag.Int("logProgressInterval", 5,
+		"Minimum time (s) between two updates of a line rewritten with carriage returns.")
 	enableRsync := flag.Bool("enableRsync", false, "Enable rsync.")
 	rsyncReadLimit := flag.Int("rsyncReadLimit", 0, "Read limit in bytes per second.")
 	rsyncWriteLimit := flag.Int("rsyncWriteLimit", 0, "Write limit in bytes per second.")
 	rsyncAllowedPaths := flag.String("rsyncPathAllowList", "", "Allowed paths for rsync.")
+	flag.Var(&preHooks, "preHook", "Shell command run before the user command. The user "+
+		"command is not started if a hook fails.")
+	flag.Var(&postHooks, "postHook", "Shell command run after the user command, with its exit "+
+		"code in OSMO_EXIT_CODE. It is skipped if the user command was not started.")
+	flag.Var(&sidecars, "sidecar", "Shell command of a process running alongside the user "+
+		"command. It is restarted if it exits and stopped once the user command finishes.")
+	sidecarStopTimeout := flag.Int("sidecarStopTimeout", 10, "Time (s) sidecars have to exit "+
+		"after SIGTERM before they are killed.")
+	restartPolicy := flag.String("restartPolicy", string(RestartNever), "When the user command "+
+		"is restarted after it exits: never, on-failure or always.")
+	maxRestarts := flag.Int("maxRestarts", 3, "Maximum number of restarts of the user command. "+
+		"Negative for no limit.")
+	restartBackoff := flag.Int("restartBackoff", 1, "Time (s) before the first restart of the "+
+		"user command, doubled for every further restart.")
+	restartMaxBackoff := flag.Int("restartMaxBackoff", 60,
+		"Maximum time (s) before a restart of the user command.")
+	stopSignal := flag.String("stopSignal", "SIGTERM", "Signal sent to the user command when "+
+		"ctrl stops it, as a name or a number.")
+	stopGracePeriod := flag.Int("stopGracePeriod", 30, "Time (s) the user command has to exit "+
+		"after the pre-stop hook starts before it is killed.")
+	preStopHook := flag.String("preStopHook", "", "Shell command run before the stop signal "+
+		"is sent to the user command.")
+	readinessProbe := flag.String("readinessProbe", "", "Probe of when the user command is "+
+		"ready: http:<port>[<path>], tcp:<port> or exec:<command>.")
+	livenessProbe := flag.String("livenessProbe", "", "Probe of whether the user command is "+
+		"alive, which is restarted once the probe fails: http:<port>[<path>], tcp:<port> or "+
+		"exec:<command>.")
+	probeInitialDelay := flag.Int("probeInitialDelay", 0,
+		"Time (s) after the user command starts before it is probed.")
+	probePeriod := flag.Int("probePeriod", 10, "Time (s) between two probes.")
+	probeTimeout := flag.Int("probeTimeout", 1, "Time (s) after which a probe fails.")
+	probeFailureThreshold := flag.Int("probeFailureThreshold", 3,
+		"Number of failed probes in a row after which a probe counts as failed.")
 	cliAutoCompleteScriptPath := flag.String(
 		"cliAutoCompleteScriptPath",
 		"/osmo/usr/bin/osmo_cli/osmo/autocomplete.bash",
@@ -56,6 +111,39 @@ func ExecParse() ExecArgs {
 
 	unixDuration := time.Duration(*unixTimeout) * time.Minute
 
+	continuationPattern, err := regexp.Compile(*logMultilinePattern)
+	if err != nil {
+		return ExecArgs{}, osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Invalid log multiline pattern: %w", err)
+	}
+	tracebackPattern, err := regexp.Compile(*logTracebackPattern)
+	if err != nil {
+		return ExecArgs{}, osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Invalid log traceback pattern: %w", err)
+	}
+	switch RestartPolicy(*restartPolicy) {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return ExecArgs{}, osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Invalid restart policy: %s", *restartPolicy)
+	}
+	parsedStopSignal, err := parseSignal(*stopSignal)
+	if err != nil {
+		return ExecArgs{}, osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err)
+	}
+	parsedReadinessProbe, err := parseOptionalProbe(*readinessProbe)
+	if err != nil {
+		return ExecArgs{}, osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err)
+	}
+	parsedLivenessProbe, err := parseOptionalProbe(*livenessProbe)
+	if err != nil {
+		return ExecArgs{}, osmo_errors.NewExitError(osmo_errors.INVALID_INPUT_CODE, err)
+	}
+	if *probePeriod <= 0 || *probeTimeout <= 0 || *probeFailureThreshold <= 0 {
+		return ExecArgs{}, osmo_errors.Errorf(osmo_errors.INVALID_INPUT_CODE,
+			"Probe period, timeout and failure threshold must be positive")
+	}
+
 	parsedArgs := ExecArgs{
 		Command:         command,
 		Args:            args,
@@ -66,13 +154,90 @@ func ExecParse() ExecArgs {
 		HistoryFilePath: *historyFilePath,
 		RunLocation:     *runLocation,
 
+		// Checkpoint flags
+		CheckpointSettleDelay: time.Duration(*checkpointSettleDelay) * time.Second,
+
+		ResourceUsageInterval: time.Duration(*resourceUsageInterval) * time.Second,
+
+		// Log framing flags
+		LogFraming: *logFraming,
+		LogFramerConfig: common.LogFramerConfig{
+			ContinuationPattern: continuationPattern,
+			TracebackPattern:    tracebackPattern,
+			MaxLines:            *logMaxLines,
+			FlushInterval:       time.Duration(*logFlushInterval) * time.Millisecond,
+			ProgressInterval:    time.Duration(*logProgressInterval) * time.Second,
+		},
+
 		// Rsync flags
 		EnableRsync:        *enableRsync,
 		RsyncReadLimit:     *rsyncReadLimit,
 		RsyncWriteLimit:    *rsyncWriteLimit,
 		RsyncPathAllowList: *rsyncAllowedPaths,
 
+		// Hook and sidecar flags
+		PreHooks:           preHooks,
+		PostHooks:          postHooks,
+		Sidecars:           sidecars,
+		SidecarStopTimeout: time.Duration(*sidecarStopTimeout) * time.Second,
+
+		// Restart flags
+		RestartPolicy: RestartPolicy(*restartPolicy),
+		MaxRestarts:   *maxRestarts,
+		RestartBackoff: common.RetryPolicy{
+			InitialBackoff: time.Duration(*restartBackoff) * time.Second,
+			MaxBackoff:     time.Duration(*restartMaxBackoff) * time.Second,
+			Multiplier:     2,
+		},
+
+		// Stop flags
+		StopSignal:      parsedStopSignal,
+		StopGracePeriod: time.Duration(*stopGracePeriod) * time.Second,
+		PreStopHook:     *preStopHook,
+
+		// Probe flags
+		ReadinessProbe:        parsedReadinessProbe,
+		LivenessProbe:         parsedLivenessProbe,
+		ProbeInitialDelay:     time.Duration(*probeInitialDelay) * time.Second,
+		ProbePeriod:           time.Duration(*probePeriod) * time.Second,
+		ProbeTimeout:          time.Duration(*probeTimeout) * time.Second,
+		ProbeFailureThreshold: *probeFailureThreshold,
+
 		CliAutoCompleteScriptPath: *cliAutoCompleteScriptPath,
 	}
-	return parsedArgs
+	return parsedArgs, nil
+}
+
+// parseOptionalProbe parses a probe flag, which is empty if the probe is disabled
+func parseOptionalProbe(value string) (*common.Probe, error) {
+	if value == "" {
+		return nil, nil
+	}
+	probe, err := common.ParseProbe(value)
+	if err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
+
+// Signals which can be given by name
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// parseSignal parses a signal given by name, with or without the SIG prefix, or by number
+func parseSignal(value string) (syscall.Signal, error) {
+	if number, err := strconv.Atoi(value); err == nil && number > 0 {
+		return syscall.Signal(number), nil
+	}
+	if signal, ok := signalNames[strings.TrimPrefix(strings.ToUpper(value), "SIG")]; ok {
+		return signal, nil
+	}
+	return 0, fmt.Errorf("Invalid stop signal: %s", value)
 }
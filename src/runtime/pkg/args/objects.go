@@ -20,6 +20,7 @@ package args
 
 import (
 	"net/url"
+	"syscall"
 	"time"
 
 	"go.corp.nvidia.com/osmo/runtime/pkg/common"
@@ -35,18 +36,64 @@ type ExecArgs struct {
 	HistoryFilePath string
 	RunLocation     string
 
+	// Checkpoint flags
+	CheckpointSettleDelay time.Duration
+
+	// Time between resource usage samples, zero disables sampling
+	ResourceUsageInterval time.Duration
+
+	// Log framing flags
+	LogFraming      bool
+	LogFramerConfig common.LogFramerConfig
+
 	// Rsync flags
 	EnableRsync        bool
 	RsyncReadLimit     int
 	RsyncWriteLimit    int
 	RsyncPathAllowList string
 
+	// Hook and sidecar flags
+	PreHooks           common.ArrayFlags
+	PostHooks          common.ArrayFlags
+	Sidecars           common.ArrayFlags
+	SidecarStopTimeout time.Duration
+
+	// Restart flags
+	RestartPolicy RestartPolicy
+	// Negative for no limit
+	MaxRestarts int
+	// Only the backoff of the policy is used
+	RestartBackoff common.RetryPolicy
+
+	// Stop flags
+	StopSignal      syscall.Signal
+	StopGracePeriod time.Duration
+	PreStopHook     string
+
+	// Probe flags, nil probes are disabled
+	ReadinessProbe        *common.Probe
+	LivenessProbe         *common.Probe
+	ProbeInitialDelay     time.Duration
+	ProbePeriod           time.Duration
+	ProbeTimeout          time.Duration
+	ProbeFailureThreshold int
+
 	CliAutoCompleteScriptPath string
 }
 
+// RestartPolicy decides whether osmo-user restarts the user command once it exits
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
 type CtrlArgs struct {
 	Inputs             common.ArrayFlags
 	Outputs            common.ArrayFlags
+	Checkpoints        common.ArrayFlags
 	InputPath          string
 	OutputPath         string
 	SocketPath         string
@@ -70,4 +117,47 @@ type CtrlArgs struct {
 	DataTimeout        time.Duration
 	LogsPeriod         int
 	LogsBufferSize     int
+	LogsRateLimit      float64
+	LogsBurst          float64
+
+	// Output quota flags
+	OutputQuota         int64
+	OutputQuotaWarn     []int
+	OutputQuotaStop     bool
+	OutputQuotaPaths    common.ArrayFlags
+	OutputQuotaInterval time.Duration
+
+	// Log redaction flags
+	RedactPatterns common.ArrayFlags
+	RedactEnv      common.ArrayFlags
+
+	// Format of the user log lines parsed into structured logs
+	LogFormat common.LogFormat
+
+	// Retry policies of the data operations
+	RetryPolicies map[common.OperationClass]common.RetryPolicy
+
+	// Time between progress metrics of transfers, zero disables them
+	ProgressInterval time.Duration
+
+	// Preflight flags
+	Preflight             bool
+	PreflightTimeout      time.Duration
+	PreflightMinFreeSpace int64
+
+	// Validate the task and print a report instead of running it
+	DryRun bool
+
+	// Local service flags, used instead of the workflow service when LocalDir is set
+	LocalDir          string
+	LocalGroupSize    int
+	LocalExecPort     int
+	LocalExecCommand  string
+	LocalPortForwards []LocalPortForward
+}
+
+// LocalPortForward forwards a local TCP port to a port of the task in local mode
+type LocalPortForward struct {
+	LocalPort int
+	TaskPort  int
 }
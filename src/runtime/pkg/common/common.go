@@ -20,7 +20,7 @@ package common
 
 import (
 	"bufio"
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -30,6 +30,7 @@ import (
 	"path/filepath"
 	"sort"
 	"sync"
+	"time"
 
 	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 )
@@ -145,72 +146,37 @@ func CheckIfFileExists(filename string, osmoChan chan string) bool {
 	return true
 }
 
-// CircularBuffer represents a circular buffer.
-type CircularBuffer struct {
-	data  []string
-	head  int
-	tail  int
-	count int
-}
-
-// NewCircularBuffer creates a new circular buffer with the given size.
-func NewCircularBuffer(size int) *CircularBuffer {
-	return &CircularBuffer{
-		data: make([]string, size),
+// Max and Min are only implemented natively in go1.21
+func min(a int, b int) int {
+	if a < b {
+		return a
 	}
+	return b
 }
 
-// IsFull checks if the circular buffer is full.
-func (cb *CircularBuffer) IsFull() bool {
-	return cb.count == len(cb.data)
-}
-
-// IsEmpty checks if the circular buffer is empty.
-func (cb *CircularBuffer) IsEmpty() bool {
-	return cb.count == 0
-}
-
-// Push adds an element to the circular buffer.
-func (cb *CircularBuffer) Push(value string) error {
-	if cb.IsFull() {
-		// Overwrite the oldest element
-		cb.head = (cb.head + 1) % len(cb.data)
-	} else {
-		cb.count++
-	}
-	cb.data[cb.tail] = value
-	cb.tail = (cb.tail + 1) % len(cb.data)
-	return nil
-}
+// Size of the chunks a log line longer than the scanner buffer is split into
+const logChunkSize = 32 * 1024
 
-// Pop removes and returns the oldest element from the circular buffer.
-func (cb *CircularBuffer) Pop() (string, error) {
-	if cb.IsEmpty() {
-		return "", errors.New("Circular buffer is empty")
+// splitLogChunks splits lines at newlines and cuts lines that do not fit in a chunk
+func splitLogChunks(data []byte, atEOF bool) (int, []byte, error) {
+	currSize, byteArray, err := bufio.ScanLines(data, atEOF)
+	if currSize != 0 {
+		return currSize, byteArray, err
 	}
-	value := cb.data[cb.head]
-	cb.head = (cb.head + 1) % len(cb.data)
-	cb.count--
-	return value, nil
-}
-
-// Peek returns the oldest element without removing it from the circular buffer.
-func (cb *CircularBuffer) Peek() (string, error) {
-	if cb.IsEmpty() {
-		return "", errors.New("Circular buffer is empty")
+	if len(data) > logChunkSize {
+		return logChunkSize, data[:logChunkSize], nil
 	}
-	return cb.data[cb.head], nil
+	return 0, nil, nil
 }
 
-// Max and Min are only implemented natively in go1.21
-func min(a int, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+func RunCommand(cmd *exec.Cmd,
+	streamOutCommand func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool),
+	streamErrCommand func(*bufio.Scanner, *sync.WaitGroup)) (string, error) {
+	return RunCommandWithSplit(cmd, splitLogChunks, streamOutCommand, streamErrCommand)
 }
 
-func RunCommand(cmd *exec.Cmd,
+// RunCommandWithSplit runs the command with its output split into tokens by splitFunc
+func RunCommandWithSplit(cmd *exec.Cmd, splitFunc bufio.SplitFunc,
 	streamOutCommand func(*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool),
 	streamErrCommand func(*bufio.Scanner, *sync.WaitGroup)) (string, error) {
 	var waitStreamLogs sync.WaitGroup
@@ -235,19 +201,6 @@ func RunCommand(cmd *exec.Cmd,
 	stdoutScanner.Buffer(make([]byte, bufio.MaxScanTokenSize), bufio.MaxScanTokenSize)
 	stderrScanner.Buffer(make([]byte, bufio.MaxScanTokenSize), bufio.MaxScanTokenSize)
 
-	// Define a split function to split the input into chunks of up to 64KB
-	chunkSize := 32 * 1024 // 32KB
-	splitFunc := func(data []byte, atEOF bool) (int, []byte, error) {
-		currSize, byteArray, err := bufio.ScanLines(data, atEOF)
-		if currSize != 0 {
-			return currSize, byteArray, err
-		}
-		if len(data) > chunkSize {
-			return chunkSize, data[:chunkSize], nil
-		}
-		return 0, nil, nil
-	}
-
 	stdoutScanner.Split(splitFunc)
 	stderrScanner.Split(splitFunc)
 
@@ -268,6 +221,18 @@ func RunCommand(cmd *exec.Cmd,
 	return fmt.Sprintf("Command failed with error: %v\n", err), err
 }
 
+// SleepContext waits for the duration and returns the error of ctx if it is canceled first
+func SleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func Min(a, b int) int {
 	if a < b {
 		return a
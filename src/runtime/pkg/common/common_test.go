@@ -19,10 +19,12 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestArrayFlags_String_Empty(t *testing.T) {
@@ -268,119 +270,6 @@ func TestCheckIfFileExists_MissingFileReturnsFalseAndSendsMessage(t *testing.T)
 	}
 }
 
-func TestNewCircularBuffer_StartsEmpty(t *testing.T) {
-	buf := NewCircularBuffer(3)
-	if !buf.IsEmpty() {
-		t.Errorf("expected new buffer to be empty")
-	}
-	if buf.IsFull() {
-		t.Errorf("expected new buffer to not be full")
-	}
-}
-
-func TestCircularBuffer_PushThenPeekReturnsOldest(t *testing.T) {
-	buf := NewCircularBuffer(3)
-	if err := buf.Push("a"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := buf.Push("b"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	val, err := buf.Peek()
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if val != "a" {
-		t.Errorf("expected 'a', got %q", val)
-	}
-}
-
-func TestCircularBuffer_PeekDoesNotRemove(t *testing.T) {
-	buf := NewCircularBuffer(2)
-	if err := buf.Push("x"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if _, err := buf.Peek(); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if buf.IsEmpty() {
-		t.Errorf("Peek should not remove element")
-	}
-}
-
-func TestCircularBuffer_PopReturnsAndRemovesOldest(t *testing.T) {
-	buf := NewCircularBuffer(3)
-	if err := buf.Push("first"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := buf.Push("second"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	val, err := buf.Pop()
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if val != "first" {
-		t.Errorf("expected 'first', got %q", val)
-	}
-	next, err := buf.Peek()
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if next != "second" {
-		t.Errorf("expected 'second' after pop, got %q", next)
-	}
-}
-
-func TestCircularBuffer_PopEmptyReturnsError(t *testing.T) {
-	buf := NewCircularBuffer(2)
-	_, err := buf.Pop()
-	if err == nil {
-		t.Errorf("expected error when popping empty buffer")
-	}
-}
-
-func TestCircularBuffer_PeekEmptyReturnsError(t *testing.T) {
-	buf := NewCircularBuffer(2)
-	_, err := buf.Peek()
-	if err == nil {
-		t.Errorf("expected error when peeking empty buffer")
-	}
-}
-
-func TestCircularBuffer_IsFullAfterFilling(t *testing.T) {
-	buf := NewCircularBuffer(2)
-	if err := buf.Push("a"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := buf.Push("b"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !buf.IsFull() {
-		t.Errorf("expected buffer to be full")
-	}
-}
-
-func TestCircularBuffer_OverwritesOldestWhenFull(t *testing.T) {
-	buf := NewCircularBuffer(2)
-	if err := buf.Push("a"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := buf.Push("b"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if err := buf.Push("c"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	val, err := buf.Peek()
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if val != "b" {
-		t.Errorf("expected oldest 'b' after overwrite, got %q", val)
-	}
-}
-
 func TestMin_FirstSmaller(t *testing.T) {
 	if got := Min(1, 5); got != 1 {
 		t.Errorf("expected 1, got %d", got)
@@ -459,3 +348,21 @@ func TestLongestCommonPathPrefix_DifferentDepths(t *testing.T) {
 		t.Errorf("expected %q, got %q", "/a/b/", got)
 	}
 }
+
+func TestSleepContext_ReturnsNilAfterDuration(t *testing.T) {
+	if err := SleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestSleepContext_ReturnsErrorWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := SleepContext(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return immediately, waited %v", elapsed)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Continuation lines of stack traces: indented frames, chained exceptions and elided frames
+const DefaultContinuationPattern = `^(\s+\S|Caused by:|During handling of the above exception|` +
+	`The above exception was the direct cause|\.\.\. \d+ more)`
+
+// First lines of stack traces that end with an unindented line naming the exception
+const DefaultTracebackPattern = `^Traceback \(most recent call last\):`
+
+// ScanLogLines is a bufio.SplitFunc that also splits lines at carriage returns. Tokens ended by
+// a carriage return keep it, so that LogFramer can tell progress updates from complete lines.
+func ScanLogLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i+1], nil
+		}
+		if atEOF {
+			return i + 1, data[:i+1], nil
+		}
+		// Wait for the next byte to tell a carriage return from a CRLF
+	}
+	if len(data) > logChunkSize {
+		return logChunkSize, data[:logChunkSize], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+type LogFramerConfig struct {
+	// Lines matching the pattern are appended to the previous entry
+	ContinuationPattern *regexp.Regexp
+	// Entries starting with a line matching the pattern also take the first line that is not
+	// a continuation
+	TracebackPattern *regexp.Regexp
+	// Maximum number of lines in an entry
+	MaxLines int
+	// Time without new lines after which an entry is sent
+	FlushInterval time.Duration
+	// Minimum time between two progress updates
+	ProgressInterval time.Duration
+}
+
+// LogFramer groups stack traces into single entries and collapses the updates of lines
+// rewritten with carriage returns, such as progress bars, to their latest value.
+type LogFramer struct {
+	config LogFramerConfig
+	emit   func(string)
+	mutex  sync.Mutex
+
+	lines     []string
+	traceback bool
+	lastWrite time.Time
+
+	progress     string
+	progressSent time.Time
+
+	// Replaced in tests
+	now func() time.Time
+}
+
+// NewLogFramer creates a framer that passes every entry to emit
+func NewLogFramer(config LogFramerConfig, emit func(string)) *LogFramer {
+	if config.MaxLines <= 0 {
+		config.MaxLines = 1
+	}
+	return &LogFramer{config: config, emit: emit, now: time.Now}
+}
+
+// Write adds a token split by ScanLogLines
+func (f *LogFramer) Write(token string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.now()
+
+	if strings.HasSuffix(token, "\r") {
+		f.flushLines()
+		f.progress = strings.TrimSuffix(token, "\r")
+		if f.progress != "" && now.Sub(f.progressSent) >= f.config.ProgressInterval {
+			f.flushProgress(now)
+		}
+		return
+	}
+	// A complete line supersedes the pending progress update
+	f.progress = ""
+
+	f.lastWrite = now
+	if len(f.lines) > 0 && len(f.lines) < f.config.MaxLines {
+		if f.isContinuation(token) {
+			f.lines = append(f.lines, token)
+			return
+		}
+		if f.traceback {
+			f.lines = append(f.lines, token)
+			f.flushLines()
+			return
+		}
+	}
+	f.flushLines()
+	f.lines = append(f.lines, token)
+	f.traceback = f.config.TracebackPattern != nil && f.config.TracebackPattern.MatchString(token)
+}
+
+func (f *LogFramer) isContinuation(line string) bool {
+	return f.config.ContinuationPattern != nil && f.config.ContinuationPattern.MatchString(line)
+}
+
+// FlushIdle sends the pending entry once no line has been added to it for the flush interval,
+// and the pending progress update once the progress interval has passed
+func (f *LogFramer) FlushIdle() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.now()
+	if len(f.lines) > 0 && now.Sub(f.lastWrite) >= f.config.FlushInterval {
+		f.flushLines()
+	}
+	if f.progress != "" && now.Sub(f.progressSent) >= f.config.ProgressInterval {
+		f.flushProgress(now)
+	}
+}
+
+// Flush sends everything pending
+func (f *LogFramer) Flush() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.flushLines()
+	if f.progress != "" {
+		f.flushProgress(f.now())
+	}
+}
+
+func (f *LogFramer) flushLines() {
+	if len(f.lines) == 0 {
+		return
+	}
+	f.emit(strings.Join(f.lines, "\n"))
+	f.lines = nil
+	f.traceback = false
+}
+
+func (f *LogFramer) flushProgress(now time.Time) {
+	f.emit(f.progress)
+	f.progress = ""
+	f.progressSent = now
+}
@@ -0,0 +1,159 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bufio"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scanLogTokens(t *testing.T, input string) []string {
+	t.Helper()
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(ScanLogLines)
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestScanLogLines(t *testing.T) {
+	got := scanLogTokens(t, "a\r\nb\n\r10%\r50%\r100%\ndone")
+	want := []string{"a", "b", "\r", "10%\r", "50%\r", "100%", "done"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("x", logChunkSize+10)
+	got = scanLogTokens(t, long)
+	if len(got) != 2 || len(got[0]) != logChunkSize || len(got[1]) != 10 {
+		t.Errorf("long line should be cut into chunks, got %d tokens", len(got))
+	}
+}
+
+type framerFixture struct {
+	framer  *LogFramer
+	entries []string
+	now     time.Time
+}
+
+func newFramerFixture(maxLines int) *framerFixture {
+	fixture := &framerFixture{now: time.Unix(1000, 0)}
+	fixture.framer = NewLogFramer(LogFramerConfig{
+		ContinuationPattern: regexp.MustCompile(DefaultContinuationPattern),
+		TracebackPattern:    regexp.MustCompile(DefaultTracebackPattern),
+		MaxLines:            maxLines,
+		FlushInterval:       time.Second,
+		ProgressInterval:    5 * time.Second,
+	}, func(entry string) {
+		fixture.entries = append(fixture.entries, entry)
+	})
+	fixture.framer.now = func() time.Time { return fixture.now }
+	return fixture
+}
+
+func (f *framerFixture) write(tokens ...string) {
+	for _, token := range tokens {
+		f.framer.Write(token)
+	}
+}
+
+func TestLogFramer_GroupsPythonTraceback(t *testing.T) {
+	fixture := newFramerFixture(100)
+	fixture.write(
+		"starting",
+		"Traceback (most recent call last):",
+		`  File "train.py", line 3, in <module>`,
+		"    main()",
+		"ValueError: boom",
+		"next line")
+	fixture.framer.Flush()
+
+	want := []string{
+		"starting",
+		"Traceback (most recent call last):\n" +
+			"  File \"train.py\", line 3, in <module>\n    main()\nValueError: boom",
+		"next line",
+	}
+	if !reflect.DeepEqual(fixture.entries, want) {
+		t.Errorf("entries = %q, want %q", fixture.entries, want)
+	}
+}
+
+func TestLogFramer_GroupsJavaStackTrace(t *testing.T) {
+	fixture := newFramerFixture(100)
+	fixture.write(
+		"Exception in thread \"main\" java.lang.IllegalStateException",
+		"\tat Main.run(Main.java:10)",
+		"Caused by: java.io.IOException",
+		"\t... 3 more")
+	fixture.framer.Flush()
+
+	if len(fixture.entries) != 1 || strings.Count(fixture.entries[0], "\n") != 3 {
+		t.Errorf("stack trace should be a single entry, got %q", fixture.entries)
+	}
+}
+
+func TestLogFramer_LimitsLinesAndFlushesIdleEntries(t *testing.T) {
+	fixture := newFramerFixture(2)
+	fixture.write("first", "  second", "  third")
+	if !reflect.DeepEqual(fixture.entries, []string{"first\n  second"}) {
+		t.Errorf("entries = %q, want the first two lines", fixture.entries)
+	}
+
+	fixture.framer.FlushIdle()
+	if len(fixture.entries) != 1 {
+		t.Fatalf("entry should wait for the flush interval, got %q", fixture.entries)
+	}
+	fixture.now = fixture.now.Add(time.Second)
+	fixture.framer.FlushIdle()
+	if !reflect.DeepEqual(fixture.entries, []string{"first\n  second", "  third"}) {
+		t.Errorf("entries = %q", fixture.entries)
+	}
+}
+
+func TestLogFramer_CollapsesCarriageReturns(t *testing.T) {
+	fixture := newFramerFixture(100)
+	fixture.write("epoch 1", "10%\r", "20%\r", "30%\r")
+	if !reflect.DeepEqual(fixture.entries, []string{"epoch 1", "10%"}) {
+		t.Errorf("entries = %q, want the first update only", fixture.entries)
+	}
+
+	fixture.now = fixture.now.Add(5 * time.Second)
+	fixture.framer.FlushIdle()
+	if !reflect.DeepEqual(fixture.entries, []string{"epoch 1", "10%", "30%"}) {
+		t.Errorf("entries = %q, want the latest update after the interval", fixture.entries)
+	}
+
+	// The final line replaces the updates still throttled
+	fixture.write("40%\r", "90%\r", "100%")
+	fixture.framer.Flush()
+	want := []string{"epoch 1", "10%", "30%", "100%"}
+	if !reflect.DeepEqual(fixture.entries, want) {
+		t.Errorf("entries = %q, want %q", fixture.entries, want)
+	}
+}
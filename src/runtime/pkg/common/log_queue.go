@@ -0,0 +1,213 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"errors"
+	"time"
+)
+
+// LogPriority orders the messages sent to the service. Lower values are sent first.
+type LogPriority int
+
+const (
+	// Barrier requests, never dropped or rate limited
+	PriorityControl LogPriority = iota
+	// Messages of osmo-ctrl, downloads, uploads and metrics
+	PriorityCtrl
+	PriorityStderr
+	PriorityStdout
+	// The end of logs marker, never dropped and only sent once every other message is sent
+	PriorityDone
+	numLogPriorities
+)
+
+type logEntry struct {
+	stream  string
+	message string
+}
+
+// logRing is a bounded FIFO that overwrites its oldest entry when full. A size of 0 means
+// unbounded.
+type logRing struct {
+	entries []logEntry
+	head    int
+	count   int
+	size    int
+}
+
+func (r *logRing) push(entry logEntry) (logEntry, bool) {
+	if r.size == 0 {
+		r.entries = append(r.entries, entry)
+		r.count++
+		return logEntry{}, false
+	}
+	if r.entries == nil {
+		r.entries = make([]logEntry, r.size)
+	}
+	tail := (r.head + r.count) % r.size
+	if r.count == r.size {
+		overwritten := r.entries[tail]
+		r.entries[tail] = entry
+		r.head = (r.head + 1) % r.size
+		return overwritten, true
+	}
+	r.entries[tail] = entry
+	r.count++
+	return logEntry{}, false
+}
+
+func (r *logRing) peek() logEntry {
+	return r.entries[r.head]
+}
+
+func (r *logRing) pop() {
+	r.entries[r.head] = logEntry{}
+	r.count--
+	if r.size == 0 {
+		r.entries = r.entries[1:]
+		return
+	}
+	r.head = (r.head + 1) % r.size
+}
+
+// TokenBucket allows Rate events per second with bursts of up to Burst events
+type TokenBucket struct {
+	Rate   float64
+	Burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst float64, now time.Time) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst, tokens: burst, last: now}
+}
+
+// Allow takes a token if one is available
+func (b *TokenBucket) Allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.Rate
+	if b.tokens > b.Burst {
+		b.tokens = b.Burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// PriorityLogQueue buffers messages for the service by priority, so that control messages are
+// never lost behind floods of user logs. Every stream except control messages has its own rate
+// limit, and dropped messages are counted per stream. It is not safe for concurrent use.
+type PriorityLogQueue struct {
+	queues  [numLogPriorities]logRing
+	buckets map[string]*TokenBucket
+	rate    float64
+	burst   float64
+	dropped map[string]int
+	// Replaced in tests
+	now func() time.Time
+}
+
+// NewPriorityLogQueue creates a queue holding up to size messages per droppable priority.
+// A rate of 0 disables the rate limit.
+func NewPriorityLogQueue(size int, rate float64, burst float64) *PriorityLogQueue {
+	queue := &PriorityLogQueue{
+		buckets: map[string]*TokenBucket{},
+		rate:    rate,
+		burst:   burst,
+		dropped: map[string]int{},
+		now:     time.Now,
+	}
+	for priority := PriorityCtrl; priority <= PriorityStdout; priority++ {
+		queue.queues[priority].size = size
+	}
+	return queue
+}
+
+func (q *PriorityLogQueue) droppable(priority LogPriority) bool {
+	return priority != PriorityControl && priority != PriorityDone
+}
+
+// Push adds a message of the stream and returns false if it was rate limited. When its priority
+// is full, the oldest message of that priority is dropped instead.
+func (q *PriorityLogQueue) Push(stream string, priority LogPriority, message string) bool {
+	if q.droppable(priority) && q.rate > 0 {
+		bucket, ok := q.buckets[stream]
+		if !ok {
+			bucket = NewTokenBucket(q.rate, q.burst, q.now())
+			q.buckets[stream] = bucket
+		}
+		if !bucket.Allow(q.now()) {
+			q.dropped[stream]++
+			return false
+		}
+	}
+	if overwritten, dropped := q.queues[priority].push(logEntry{stream, message}); dropped {
+		q.dropped[overwritten.stream]++
+	}
+	return true
+}
+
+// next returns the priority of the next message to send
+func (q *PriorityLogQueue) next() (LogPriority, bool) {
+	for priority := PriorityControl; priority < numLogPriorities; priority++ {
+		if q.queues[priority].count > 0 {
+			return priority, true
+		}
+	}
+	return 0, false
+}
+
+// Peek returns the next message to send without removing it
+func (q *PriorityLogQueue) Peek() (string, error) {
+	priority, ok := q.next()
+	if !ok {
+		return "", errors.New("Log queue is empty")
+	}
+	return q.queues[priority].peek().message, nil
+}
+
+// Pop removes the message returned by Peek
+func (q *PriorityLogQueue) Pop() {
+	if priority, ok := q.next(); ok {
+		q.queues[priority].pop()
+	}
+}
+
+func (q *PriorityLogQueue) IsEmpty() bool {
+	_, ok := q.next()
+	return !ok
+}
+
+// TakeDropped returns the number of dropped messages per stream since the last call
+func (q *PriorityLogQueue) TakeDropped() map[string]int {
+	if len(q.dropped) == 0 {
+		return nil
+	}
+	dropped := q.dropped
+	q.dropped = map[string]int{}
+	return dropped
+}
+
+// AddDropped adds to the number of dropped messages of a stream
+func (q *PriorityLogQueue) AddDropped(stream string, count int) {
+	q.dropped[stream] += count
+}
@@ -0,0 +1,105 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func drainLogQueue(queue *PriorityLogQueue) []string {
+	var messages []string
+	for !queue.IsEmpty() {
+		message, _ := queue.Peek()
+		messages = append(messages, message)
+		queue.Pop()
+	}
+	return messages
+}
+
+func TestPriorityLogQueue_SendsByPriority(t *testing.T) {
+	queue := NewPriorityLogQueue(10, 0, 0)
+	queue.Push("LOG_DONE", PriorityDone, "done")
+	queue.Push("STDOUT", PriorityStdout, "out")
+	queue.Push("STDERR", PriorityStderr, "err")
+	queue.Push("OSMO_CTRL", PriorityCtrl, "ctrl")
+	queue.Push("BARRIER", PriorityControl, "barrier")
+	queue.Push("STDOUT", PriorityStdout, "out2")
+
+	got := drainLogQueue(queue)
+	want := []string{"barrier", "ctrl", "err", "out", "out2", "done"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if _, err := queue.Peek(); err == nil {
+		t.Error("Peek on an empty queue should fail")
+	}
+}
+
+func TestPriorityLogQueue_OverflowOnlyDropsItsPriority(t *testing.T) {
+	queue := NewPriorityLogQueue(2, 0, 0)
+	queue.Push("BARRIER", PriorityControl, "barrier")
+	for _, message := range []string{"a", "b", "c", "d"} {
+		queue.Push("STDOUT", PriorityStdout, message)
+	}
+	queue.Push("OSMO_CTRL", PriorityCtrl, "ctrl")
+
+	got := drainLogQueue(queue)
+	want := []string{"barrier", "ctrl", "c", "d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %v, want %v", got, want)
+	}
+	if dropped := queue.TakeDropped(); !reflect.DeepEqual(dropped, map[string]int{"STDOUT": 2}) {
+		t.Errorf("dropped = %v, want 2 STDOUT lines", dropped)
+	}
+	if dropped := queue.TakeDropped(); dropped != nil {
+		t.Errorf("dropped counts should reset, got %v", dropped)
+	}
+}
+
+func TestPriorityLogQueue_RateLimitsEachStream(t *testing.T) {
+	now := time.Unix(0, 0)
+	queue := NewPriorityLogQueue(100, 1, 2)
+	queue.now = func() time.Time { return now }
+
+	var allowed []bool
+	for i := 0; i < 3; i++ {
+		allowed = append(allowed, queue.Push("STDOUT", PriorityStdout, "out"))
+	}
+	if !reflect.DeepEqual(allowed, []bool{true, true, false}) {
+		t.Errorf("allowed = %v, want the burst of 2 to pass", allowed)
+	}
+	// Streams have separate buckets and control messages are never limited
+	if !queue.Push("STDERR", PriorityStderr, "err") {
+		t.Error("STDERR should not be limited by STDOUT")
+	}
+	for i := 0; i < 5; i++ {
+		if !queue.Push("BARRIER", PriorityControl, "barrier") {
+			t.Fatal("control messages should not be rate limited")
+		}
+	}
+	now = now.Add(time.Second)
+	if !queue.Push("STDOUT", PriorityStdout, "out") {
+		t.Error("a token should be refilled after a second")
+	}
+	if dropped := queue.TakeDropped(); !reflect.DeepEqual(dropped, map[string]int{"STDOUT": 1}) {
+		t.Errorf("dropped = %v", dropped)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ProbeType selects how a probe checks the user command
+type ProbeType string
+
+const (
+	ProbeHTTP ProbeType = "http"
+	ProbeTCP  ProbeType = "tcp"
+	ProbeExec ProbeType = "exec"
+)
+
+// Probe checks whether the user command is healthy. HTTP and TCP probes connect to a port of
+// the task, exec probes run a shell command.
+type Probe struct {
+	Type    ProbeType
+	Port    int
+	Path    string
+	Command string
+}
+
+// ParseProbe parses a probe of the form http:<port>[<path>], tcp:<port> or exec:<command>
+func ParseProbe(value string) (Probe, error) {
+	probeType, target, found := strings.Cut(value, ":")
+	if !found || target == "" {
+		return Probe{}, fmt.Errorf("Probe %s is not <type>:<target>", value)
+	}
+	switch ProbeType(probeType) {
+	case ProbeHTTP:
+		port, path := target, "/"
+		if index := strings.Index(target, "/"); index >= 0 {
+			port, path = target[:index], target[index:]
+		}
+		parsedPort, err := parseProbePort(port)
+		if err != nil {
+			return Probe{}, fmt.Errorf("Invalid port in probe %s: %w", value, err)
+		}
+		return Probe{Type: ProbeHTTP, Port: parsedPort, Path: path}, nil
+	case ProbeTCP:
+		parsedPort, err := parseProbePort(target)
+		if err != nil {
+			return Probe{}, fmt.Errorf("Invalid port in probe %s: %w", value, err)
+		}
+		return Probe{Type: ProbeTCP, Port: parsedPort}, nil
+	case ProbeExec:
+		return Probe{Type: ProbeExec, Command: target}, nil
+	}
+	return Probe{}, fmt.Errorf("Unknown type %s of probe %s", probeType, value)
+}
+
+func parseProbePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("port %d is out of range", port)
+	}
+	return port, nil
+}
+
+func (p Probe) String() string {
+	switch p.Type {
+	case ProbeHTTP:
+		return fmt.Sprintf("http:%d%s", p.Port, p.Path)
+	case ProbeTCP:
+		return fmt.Sprintf("tcp:%d", p.Port)
+	}
+	return fmt.Sprintf("exec:%s", p.Command)
+}
+
+// Check runs the probe once. HTTP probes pass on a status below 400, TCP probes once the port
+// accepts connections and exec probes when the command exits with 0.
+func (p Probe) Check(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(p.Port))
+	switch p.Type {
+	case ProbeHTTP:
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			"http://"+address+p.Path, nil)
+		if err != nil {
+			return err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("HTTP status %d", response.StatusCode)
+		}
+		return nil
+	case ProbeTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case ProbeExec:
+		// The process group is killed so that children do not hold the output open
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", p.Command)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		output, err := cmd.CombinedOutput()
+		if err != nil && len(bytes.TrimSpace(output)) > 0 {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+		}
+		return err
+	}
+	return fmt.Errorf("Unknown probe type %s", p.Type)
+}
@@ -0,0 +1,131 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseProbe(t *testing.T) {
+	for value, want := range map[string]Probe{
+		"http:8080":               {Type: ProbeHTTP, Port: 8080, Path: "/"},
+		"http:8080/healthz":       {Type: ProbeHTTP, Port: 8080, Path: "/healthz"},
+		"tcp:6006":                {Type: ProbeTCP, Port: 6006},
+		"exec:test -f /tmp/ready": {Type: ProbeExec, Command: "test -f /tmp/ready"},
+	} {
+		probe, err := ParseProbe(value)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+			continue
+		}
+		if probe != want {
+			t.Errorf("%s = %+v, want %+v", value, probe, want)
+		}
+		if probe.String() != value && value != "http:8080" {
+			t.Errorf("%s is printed as %s", value, probe.String())
+		}
+	}
+}
+
+func TestParseProbe_RejectsInvalidProbes(t *testing.T) {
+	for _, value := range []string{
+		"8080",
+		"http:",
+		"http:port/healthz",
+		"tcp:0",
+		"tcp:70000",
+		"grpc:8080",
+	} {
+		if _, err := ParseProbe(value); err == nil {
+			t.Errorf("%s should be rejected", value)
+		}
+	}
+}
+
+func serverPort(t *testing.T, address string) int {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestProbeCheck_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			if request.URL.Path != "/healthz" {
+				writer.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+	defer server.Close()
+	port := serverPort(t, server.Listener.Addr().String())
+
+	healthy := Probe{Type: ProbeHTTP, Port: port, Path: "/healthz"}
+	if err := healthy.Check(context.Background(), time.Second); err != nil {
+		t.Errorf("healthy probe failed: %v", err)
+	}
+	unhealthy := Probe{Type: ProbeHTTP, Port: port, Path: "/"}
+	if err := unhealthy.Check(context.Background(), time.Second); err == nil {
+		t.Errorf("probe should fail on status 503")
+	}
+}
+
+func TestProbeCheck_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := Probe{Type: ProbeTCP, Port: serverPort(t, listener.Addr().String())}
+	if err := probe.Check(context.Background(), time.Second); err != nil {
+		t.Errorf("probe of an open port failed: %v", err)
+	}
+	listener.Close()
+	if err := probe.Check(context.Background(), time.Second); err == nil {
+		t.Errorf("probe of a closed port should fail")
+	}
+}
+
+func TestProbeCheck_Exec(t *testing.T) {
+	if err := (Probe{Type: ProbeExec, Command: "true"}).Check(context.Background(),
+		time.Second); err != nil {
+		t.Errorf("probe of a successful command failed: %v", err)
+	}
+	if err := (Probe{Type: ProbeExec, Command: "exit 1"}).Check(context.Background(),
+		time.Second); err == nil {
+		t.Errorf("probe of a failing command should fail")
+	}
+	start := time.Now()
+	if err := (Probe{Type: ProbeExec, Command: "sleep 5"}).Check(context.Background(),
+		100*time.Millisecond); err == nil {
+		t.Errorf("probe exceeding its timeout should fail")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("probe was not ended by its timeout")
+	}
+}
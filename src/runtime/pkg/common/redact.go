@@ -0,0 +1,180 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RedactedText string = "[REDACTED]"
+	// Shorter secrets are not masked since they would also match unrelated text
+	minSecretLength int = 6
+)
+
+// DefaultRedactPatterns match AWS access key ids, bearer tokens and private key blocks
+var DefaultRedactPatterns = []string{
+	`\b(AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`,
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|$)`,
+}
+
+// DefaultSecretEnvPatterns match the names of environment variables whose values are masked
+var DefaultSecretEnvPatterns = []string{
+	"*SECRET*", "*TOKEN*", "*PASSWORD*", "*ACCESS_KEY*", "*PRIVATE_KEY*",
+}
+
+var secretConfigKeys = []string{"key", "secret", "token", "password", "credential"}
+
+var privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+var privateKeyEnd = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+
+// Redactor masks secrets in log lines. Private key blocks span several lines, so the redactor
+// remembers per stream whether it is inside of one.
+type Redactor struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+
+	mutex        sync.Mutex
+	inPrivateKey map[string]bool
+}
+
+func NewRedactor(patterns []string) (*Redactor, error) {
+	redactor := &Redactor{inPrivateKey: map[string]bool{}}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, compiled)
+	}
+	return redactor, nil
+}
+
+// AddSecret masks every occurrence of value
+func (r *Redactor) AddSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.secrets = append(r.secrets, value)
+	// Longest first so a secret containing another one is masked entirely
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// AddSecretsFromEnv masks the values of environment variables with names matching the patterns
+func (r *Redactor) AddSecretsFromEnv(namePatterns []string) {
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		for _, pattern := range namePatterns {
+			if matched, _ := filepath.Match(pattern, strings.ToUpper(name)); matched {
+				r.AddSecret(value)
+				break
+			}
+		}
+	}
+}
+
+// AddSecretsFromConfig masks the credential values of an OSMO config file. Values are found by
+// the name of their key, e.g. access_key or token.
+func (r *Redactor) AddSecretsFromConfig(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config any
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	r.addConfigSecrets("", config)
+	return nil
+}
+
+func (r *Redactor) addConfigSecrets(key string, value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for childKey, child := range typed {
+			r.addConfigSecrets(childKey, child)
+		}
+	case []any:
+		for _, child := range typed {
+			r.addConfigSecrets(key, child)
+		}
+	case string:
+		lowerKey := strings.ToLower(key)
+		for _, secretKey := range secretConfigKeys {
+			if strings.Contains(lowerKey, secretKey) {
+				r.AddSecret(typed)
+				return
+			}
+		}
+	}
+}
+
+// Redact returns the line of the stream with all secrets masked. A framed log entry holds
+// several lines, each of which is redacted on its own.
+func (r *Redactor) Redact(stream string, line string) string {
+	if r == nil {
+		return line
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !strings.Contains(line, "\n") {
+		return r.redactLine(stream, line)
+	}
+	lines := strings.Split(line, "\n")
+	for i := range lines {
+		lines[i] = r.redactLine(stream, lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// redactLine masks the secrets of a single line. The caller holds the mutex.
+func (r *Redactor) redactLine(stream string, line string) string {
+	if r.inPrivateKey[stream] {
+		if !privateKeyEnd.MatchString(line) {
+			return RedactedText
+		}
+		r.inPrivateKey[stream] = false
+		line = RedactedText + line[privateKeyEnd.FindStringIndex(line)[1]:]
+	}
+	if begin := privateKeyBegin.FindStringIndex(line); begin != nil &&
+		!privateKeyEnd.MatchString(line[begin[1]:]) {
+		r.inPrivateKey[stream] = true
+	}
+
+	for _, secret := range r.secrets {
+		line = strings.ReplaceAll(line, secret, RedactedText)
+	}
+	for _, pattern := range r.patterns {
+		line = pattern.ReplaceAllString(line, RedactedText)
+	}
+	return line
+}
@@ -0,0 +1,184 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProcRoot   string = "/proc"
+	CgroupRoot string = "/sys/fs/cgroup"
+	// Linux reports process CPU times in clock ticks, which is 100 per second on all supported
+	// architectures
+	clockTicksPerSecond float64 = 100
+)
+
+// ResourceUsage is a sample of the resources used by the user command. Process fields cover the
+// process group of the command, cgroup fields cover the whole container.
+type ResourceUsage struct {
+	Time                  time.Time
+	Processes             int
+	CpuSeconds            float64
+	RssBytes              int64
+	OpenFiles             int
+	CgroupCpuSeconds      float64
+	CgroupMemoryBytes     int64
+	CgroupMemoryPeakBytes int64
+	OomKills              int64
+	OutputSizeBytes       int64
+	NetworkRxBytes        int64
+	NetworkTxBytes        int64
+}
+
+// ResourceSampler samples cgroup v2 and /proc statistics. The roots can be changed for tests.
+type ResourceSampler struct {
+	ProcRoot   string
+	CgroupRoot string
+	OutputPath string
+}
+
+func NewResourceSampler(outputPath string) ResourceSampler {
+	return ResourceSampler{ProcRoot: ProcRoot, CgroupRoot: CgroupRoot, OutputPath: outputPath}
+}
+
+// Sample returns the current usage of the process group pgid. Statistics that cannot be read,
+// for example because cgroup v1 is used, are left at zero.
+func (s ResourceSampler) Sample(pgid int) ResourceUsage {
+	usage := ResourceUsage{Time: time.Now().UTC()}
+	if pgid > 0 {
+		s.sampleProcessGroup(pgid, &usage)
+	}
+	s.sampleCgroup(&usage)
+	s.sampleNetwork(&usage)
+	if s.OutputPath != "" {
+		usage.OutputSizeBytes, _ = CalculateFolderSize(s.OutputPath)
+	}
+	return usage
+}
+
+func (s ResourceSampler) sampleProcessGroup(pgid int, usage *ResourceUsage) {
+	entries, err := os.ReadDir(s.ProcRoot)
+	if err != nil {
+		return
+	}
+	pageSize := int64(os.Getpagesize())
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(s.ProcRoot, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		fields := parseProcStat(string(stat))
+		// Fields after the command name, starting with the state (field 3 in proc(5))
+		if len(fields) < 22 {
+			continue
+		}
+		if group, err := strconv.Atoi(fields[2]); err != nil || group != pgid {
+			continue
+		}
+		usage.Processes++
+		utime, _ := strconv.ParseFloat(fields[11], 64)
+		stime, _ := strconv.ParseFloat(fields[12], 64)
+		usage.CpuSeconds += (utime + stime) / clockTicksPerSecond
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+		usage.RssBytes += rss * pageSize
+		if fds, err := os.ReadDir(filepath.Join(s.ProcRoot, entry.Name(), "fd")); err == nil {
+			usage.OpenFiles += len(fds)
+		}
+	}
+}
+
+// parseProcStat returns the fields of /proc/<pid>/stat following the command name, which may
+// itself contain spaces and parentheses.
+func parseProcStat(stat string) []string {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return nil
+	}
+	return strings.Fields(stat[end+1:])
+}
+
+func (s ResourceSampler) sampleCgroup(usage *ResourceUsage) {
+	cpuStat := readKeyValues(filepath.Join(s.CgroupRoot, "cpu.stat"))
+	usage.CgroupCpuSeconds = float64(cpuStat["usage_usec"]) / 1e6
+	usage.CgroupMemoryBytes = readInt(filepath.Join(s.CgroupRoot, "memory.current"))
+	usage.CgroupMemoryPeakBytes = readInt(filepath.Join(s.CgroupRoot, "memory.peak"))
+	usage.OomKills = readKeyValues(filepath.Join(s.CgroupRoot, "memory.events"))["oom_kill"]
+}
+
+// sampleNetwork sums the traffic of all interfaces in the network namespace except loopback
+func (s ResourceSampler) sampleNetwork(usage *ResourceUsage) {
+	file, err := os.Open(filepath.Join(s.ProcRoot, "net", "dev"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseInt(fields[0], 10, 64)
+		tx, _ := strconv.ParseInt(fields[8], 10, 64)
+		usage.NetworkRxBytes += rx
+		usage.NetworkTxBytes += tx
+	}
+}
+
+// readKeyValues reads a flat keyed cgroup file such as cpu.stat
+func readKeyValues(path string) map[string]int64 {
+	values := map[string]int64{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// readInt reads a single value cgroup file such as memory.current
+func readInt(path string) int64 {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	value, _ := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	return value
+}
@@ -0,0 +1,104 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll %q: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %q: %v", path, err)
+		}
+	}
+}
+
+// procStat builds a /proc/<pid>/stat line with the given process group, CPU ticks and RSS pages
+func procStat(comm string, pgid string, utime string, stime string, rss string) string {
+	fields := []string{"S", "1", pgid, "1", "0", "-1", "0", "0", "0", "0", "0", utime, stime,
+		"0", "0", "20", "0", "1", "0", "100", "4096", rss}
+	line := "42 (" + comm + ")"
+	for _, field := range fields {
+		line += " " + field
+	}
+	return line + "\n"
+}
+
+func TestResourceSampler_SumsProcessGroupAndReadsCgroup(t *testing.T) {
+	proc := t.TempDir()
+	cgroup := t.TempDir()
+	output := t.TempDir()
+	writeFiles(t, proc, map[string]string{
+		"100/stat":  procStat("python", "100", "150", "50", "10"),
+		"100/fd/0":  "",
+		"100/fd/1":  "",
+		"101/stat":  procStat("worker (1)", "100", "100", "0", "5"),
+		"101/fd/0":  "",
+		"200/stat":  procStat("other", "200", "1000", "1000", "1000"),
+		"self/stat": "not a pid",
+		"net/dev": "Inter-|   Receive                            |  Transmit\n" +
+			" face |bytes packets errs drop fifo frame compressed multicast|bytes\n" +
+			"    lo: 999 1 0 0 0 0 0 0 999 1 0 0 0 0 0 0\n" +
+			"  eth0: 1000 1 0 0 0 0 0 0 2000 1 0 0 0 0 0 0\n",
+	})
+	writeFiles(t, cgroup, map[string]string{
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\n",
+		"memory.current": "1048576\n",
+		"memory.peak":    "2097152\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+	})
+	writeFiles(t, output, map[string]string{"result.bin": "12345"})
+
+	sampler := ResourceSampler{ProcRoot: proc, CgroupRoot: cgroup, OutputPath: output}
+	usage := sampler.Sample(100)
+
+	pageSize := int64(os.Getpagesize())
+	if usage.Processes != 2 || usage.CpuSeconds != 3 || usage.RssBytes != 15*pageSize ||
+		usage.OpenFiles != 3 {
+		t.Errorf("process group usage = %+v, want 2 processes, 3s CPU, 15 pages, 3 files", usage)
+	}
+	if usage.CgroupCpuSeconds != 2.5 || usage.CgroupMemoryBytes != 1048576 ||
+		usage.CgroupMemoryPeakBytes != 2097152 || usage.OomKills != 1 {
+		t.Errorf("cgroup usage = %+v", usage)
+	}
+	if usage.NetworkRxBytes != 1000 || usage.NetworkTxBytes != 2000 {
+		t.Errorf("network usage = %d/%d, want loopback excluded", usage.NetworkRxBytes,
+			usage.NetworkTxBytes)
+	}
+	if usage.OutputSizeBytes != 5 {
+		t.Errorf("OutputSizeBytes = %d, want 5", usage.OutputSizeBytes)
+	}
+}
+
+func TestResourceSampler_MissingStatisticsAreZero(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	sampler := ResourceSampler{ProcRoot: missing, CgroupRoot: missing}
+	usage := sampler.Sample(100)
+	if usage.Processes != 0 || usage.CgroupMemoryBytes != 0 || usage.Time.IsZero() {
+		t.Errorf("usage = %+v, want only the time set", usage)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OperationClass groups the data operations sharing a retry policy
+type OperationClass string
+
+const (
+	DownloadOperation   OperationClass = "download"
+	UploadOperation     OperationClass = "upload"
+	AuthCheckOperation  OperationClass = "auth"
+	CheckpointOperation OperationClass = "checkpoint"
+)
+
+var operationClasses = []OperationClass{
+	DownloadOperation, UploadOperation, AuthCheckOperation, CheckpointOperation}
+
+// Exit codes of the OSMO CLI that wait for the service instead of counting as attempts
+const (
+	ServiceUnavailableExitCode int = 10
+	RateLimitedExitCode        int = 75
+)
+
+// RetryPolicy decides whether and when a failed command is retried. Timeouts are always
+// retriable. Failures with a wait exit code are retried without counting as attempts, until the
+// deadline. An unreachable service is polled every initial backoff, while other wait exit codes
+// back off like attempts.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Total time across all attempts, zero for no deadline
+	Deadline time.Duration
+	// Retry every failure instead of only the retriable exit codes and output
+	RetryAllFailures   bool
+	RetriableExitCodes []int
+	// Regexes matched against the output of the failed command
+	RetriableOutput []string
+	WaitExitCodes   []int
+	// Time between checks of a broken connection to the service
+	ServicePollInterval time.Duration
+}
+
+// DefaultRetryPolicy retries timeouts 5 times with a backoff from 1s doubling up to 32s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:         5,
+		InitialBackoff:      time.Second,
+		MaxBackoff:          32 * time.Second,
+		Multiplier:          2,
+		WaitExitCodes:       []int{ServiceUnavailableExitCode, RateLimitedExitCode},
+		ServicePollInterval: 10 * time.Second,
+	}
+}
+
+func DefaultRetryPolicies() map[OperationClass]RetryPolicy {
+	policies := map[OperationClass]RetryPolicy{}
+	for _, operation := range operationClasses {
+		policies[operation] = DefaultRetryPolicy()
+	}
+	// Access checks fail fast, so any failure is retried
+	auth := DefaultRetryPolicy()
+	auth.MaxAttempts = 3
+	auth.RetryAllFailures = true
+	policies[AuthCheckOperation] = auth
+	return policies
+}
+
+// ParseRetryPolicies applies settings of the form <class>:<key>=<value> to the default
+// policies. List values are comma separated and replace the defaults, except for output
+// patterns which are added one per setting.
+func ParseRetryPolicies(settings []string) (map[OperationClass]RetryPolicy, error) {
+	policies := DefaultRetryPolicies()
+	for _, setting := range settings {
+		classAndKey, value, found := strings.Cut(setting, "=")
+		class, key, hasClass := strings.Cut(classAndKey, ":")
+		if !found || !hasClass {
+			return nil, fmt.Errorf("Retry policy setting %s is not <class>:<key>=<value>", setting)
+		}
+		policy, ok := policies[OperationClass(class)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown operation class %s in retry policy setting %s",
+				class, setting)
+		}
+		if err := policy.set(key, value); err != nil {
+			return nil, fmt.Errorf("Invalid retry policy setting %s: %w", setting, err)
+		}
+		policies[OperationClass(class)] = policy
+	}
+	return policies, nil
+}
+
+func (p *RetryPolicy) set(key string, value string) error {
+	var err error
+	switch key {
+	case "attempts":
+		p.MaxAttempts, err = strconv.Atoi(value)
+		if err == nil && p.MaxAttempts < 1 {
+			err = fmt.Errorf("attempts must be at least 1")
+		}
+	case "initialBackoff":
+		p.InitialBackoff, err = time.ParseDuration(value)
+	case "maxBackoff":
+		p.MaxBackoff, err = time.ParseDuration(value)
+	case "multiplier":
+		p.Multiplier, err = strconv.ParseFloat(value, 64)
+		if err == nil && p.Multiplier < 1 {
+			err = fmt.Errorf("multiplier must be at least 1")
+		}
+	case "deadline":
+		p.Deadline, err = time.ParseDuration(value)
+	case "retryAll":
+		p.RetryAllFailures, err = strconv.ParseBool(value)
+	case "exitCodes":
+		p.RetriableExitCodes, err = parseExitCodes(value)
+	case "waitExitCodes":
+		p.WaitExitCodes, err = parseExitCodes(value)
+	case "output":
+		if _, err = regexp.Compile(value); err == nil {
+			p.RetriableOutput = append(p.RetriableOutput, value)
+		}
+	case "servicePoll":
+		p.ServicePollInterval, err = time.ParseDuration(value)
+	default:
+		err = fmt.Errorf("unknown key %s", key)
+	}
+	return err
+}
+
+func parseExitCodes(value string) ([]int, error) {
+	codes := []int{}
+	for _, code := range strings.Split(value, ",") {
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, parsed)
+	}
+	return codes, nil
+}
+
+// Backoff returns the delay before the given retry, randomized with equal jitter
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	maxDelay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry))
+	if maxDelay > float64(p.MaxBackoff) {
+		maxDelay = float64(p.MaxBackoff)
+	}
+	halfDelay := int64(maxDelay / 2)
+	if halfDelay <= 0 {
+		return 0
+	}
+	return time.Duration(halfDelay + rand.Int64N(halfDelay))
+}
+
+// RetryDecision is the outcome of a failed attempt
+type RetryDecision struct {
+	Retry bool
+	Delay time.Duration
+	// Whether the attempts or the deadline ran out, rather than the failure not being retriable
+	Exhausted bool
+	Reason    string
+}
+
+// Retrier tracks the attempts of a command under a retry policy
+type Retrier struct {
+	Policy   RetryPolicy
+	patterns []*regexp.Regexp
+	attempts int
+	waits    int
+	start    time.Time
+	// Replaced in tests
+	now func() time.Time
+}
+
+func NewRetrier(policy RetryPolicy) *Retrier {
+	retrier := &Retrier{Policy: policy, start: time.Now(), now: time.Now}
+	for _, pattern := range policy.RetriableOutput {
+		// Patterns are validated when the policy is parsed
+		if compiled, err := regexp.Compile(pattern); err == nil {
+			retrier.patterns = append(retrier.patterns, compiled)
+		}
+	}
+	return retrier
+}
+
+// Attempts returns the number of attempts made so far
+func (r *Retrier) Attempts() int {
+	return r.attempts + 1
+}
+
+// Next decides whether to retry after a failure with the exit code, or -1 if the command did
+// not exit, and its output
+func (r *Retrier) Next(exitCode int, timedOut bool, output string) RetryDecision {
+	elapsed := r.now().Sub(r.start)
+	if r.Policy.Deadline > 0 && elapsed >= r.Policy.Deadline {
+		return RetryDecision{Exhausted: true,
+			Reason: fmt.Sprintf("deadline of %s exceeded", r.Policy.Deadline)}
+	}
+
+	if !timedOut && slices.Contains(r.Policy.WaitExitCodes, exitCode) {
+		decision := RetryDecision{Retry: true, Delay: r.Policy.InitialBackoff,
+			Reason: describeExitCode(exitCode)}
+		if exitCode != ServiceUnavailableExitCode {
+			decision.Delay = r.Policy.Backoff(r.waits)
+			r.waits++
+		}
+		return r.capToDeadline(decision, elapsed)
+	}
+
+	reason := ""
+	switch {
+	case timedOut:
+		reason = "timed out"
+	case r.Policy.RetryAllFailures:
+		reason = describeExitCode(exitCode)
+	case slices.Contains(r.Policy.RetriableExitCodes, exitCode):
+		reason = "retriable " + describeExitCode(exitCode)
+	default:
+		for _, pattern := range r.patterns {
+			if pattern.MatchString(output) {
+				reason = fmt.Sprintf("output matched %s", pattern)
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return RetryDecision{Reason: describeExitCode(exitCode) + " is not retriable"}
+	}
+
+	r.attempts++
+	if r.attempts >= r.Policy.MaxAttempts {
+		return RetryDecision{Exhausted: true,
+			Reason: fmt.Sprintf("%s after %d attempts", reason, r.attempts)}
+	}
+	decision := RetryDecision{Retry: true, Delay: r.Policy.Backoff(r.attempts - 1),
+		Reason: reason}
+	return r.capToDeadline(decision, elapsed)
+}
+
+func (r *Retrier) capToDeadline(decision RetryDecision, elapsed time.Duration) RetryDecision {
+	if r.Policy.Deadline > 0 && elapsed+decision.Delay > r.Policy.Deadline {
+		decision.Delay = r.Policy.Deadline - elapsed
+	}
+	return decision
+}
+
+func describeExitCode(exitCode int) string {
+	switch exitCode {
+	case -1:
+		return "failure without exit code"
+	case ServiceUnavailableExitCode:
+		return fmt.Sprintf("exit code %d (cannot connect to the service)", exitCode)
+	case RateLimitedExitCode:
+		return fmt.Sprintf("exit code %d (rate limited by the service)", exitCode)
+	}
+	return fmt.Sprintf("exit code %d", exitCode)
+}
@@ -0,0 +1,162 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryPolicies(t *testing.T) {
+	policies, err := ParseRetryPolicies([]string{
+		"download:attempts=8",
+		"download:deadline=1h",
+		"download:exitCodes=1,2",
+		"download:output=(?i)connection reset",
+		"download:output=503",
+		"checkpoint:waitExitCodes=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	download := policies[DownloadOperation]
+	if download.MaxAttempts != 8 || download.Deadline != time.Hour {
+		t.Errorf("download policy = %+v", download)
+	}
+	if !reflect.DeepEqual(download.RetriableExitCodes, []int{1, 2}) ||
+		!reflect.DeepEqual(download.RetriableOutput, []string{"(?i)connection reset", "503"}) {
+		t.Errorf("download retriable failures = %v %v", download.RetriableExitCodes,
+			download.RetriableOutput)
+	}
+	if len(policies[CheckpointOperation].WaitExitCodes) != 0 {
+		t.Errorf("checkpoint wait exit codes should be cleared")
+	}
+	if !reflect.DeepEqual(policies[UploadOperation], DefaultRetryPolicy()) {
+		t.Errorf("upload policy should keep the defaults")
+	}
+	if auth := policies[AuthCheckOperation]; auth.MaxAttempts != 3 || !auth.RetryAllFailures {
+		t.Errorf("auth policy = %+v", auth)
+	}
+}
+
+func TestParseRetryPolicies_RejectsInvalidSettings(t *testing.T) {
+	for _, setting := range []string{
+		"attempts=3",
+		"restore:attempts=3",
+		"download:attempts=0",
+		"download:attempts",
+		"download:multiplier=0.5",
+		"download:output=(",
+		"download:unknown=1",
+	} {
+		if _, err := ParseRetryPolicies([]string{setting}); err == nil {
+			t.Errorf("%s should be rejected", setting)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	for retry, maxDelay := range []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, 32 * time.Second,
+	} {
+		delay := policy.Backoff(retry)
+		if delay < maxDelay/2 || delay >= maxDelay {
+			t.Errorf("Backoff(%d) = %s, want within [%s, %s)", retry, delay, maxDelay/2, maxDelay)
+		}
+	}
+	policy.InitialBackoff = 0
+	if delay := policy.Backoff(3); delay != 0 {
+		t.Errorf("Backoff without initial backoff = %s, want 0", delay)
+	}
+}
+
+func TestRetrier_CountsAttempts(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.RetriableExitCodes = []int{2}
+	policy.RetriableOutput = []string{"connection reset"}
+	retrier := NewRetrier(policy)
+
+	if decision := retrier.Next(0, true, ""); !decision.Retry || decision.Reason != "timed out" {
+		t.Errorf("timeout decision = %+v", decision)
+	}
+	if decision := retrier.Next(2, false, ""); !decision.Retry ||
+		decision.Reason != "retriable exit code 2" {
+		t.Errorf("retriable exit code decision = %+v", decision)
+	}
+	decision := retrier.Next(1, false, "read: connection reset by peer")
+	if decision.Retry || !decision.Exhausted ||
+		!strings.Contains(decision.Reason, "after 3 attempts") {
+		t.Errorf("exhausted decision = %+v", decision)
+	}
+}
+
+func TestRetrier_WaitsWithoutCountingAttempts(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 1
+	retrier := NewRetrier(policy)
+	for i := 0; i < 10; i++ {
+		decision := retrier.Next(RateLimitedExitCode, false, "")
+		if !decision.Retry || !strings.Contains(decision.Reason, "rate limited") {
+			t.Fatalf("rate limited decision = %+v", decision)
+		}
+	}
+	if retrier.Attempts() != 1 {
+		t.Errorf("waits should not count as attempts, got %d", retrier.Attempts())
+	}
+}
+
+func TestRetrier_PollsUnavailableServiceAtInitialBackoff(t *testing.T) {
+	retrier := NewRetrier(DefaultRetryPolicy())
+	for i := 0; i < 10; i++ {
+		decision := retrier.Next(ServiceUnavailableExitCode, false, "")
+		if !decision.Retry || decision.Delay != time.Second {
+			t.Fatalf("service unavailable decision %d = %+v, want a retry in 1s", i, decision)
+		}
+	}
+}
+
+func TestRetrier_StopsOnNonRetriableFailureAndDeadline(t *testing.T) {
+	retrier := NewRetrier(DefaultRetryPolicy())
+	decision := retrier.Next(1, false, "error")
+	if decision.Retry || decision.Exhausted || decision.Reason != "exit code 1 is not retriable" {
+		t.Errorf("non-retriable decision = %+v", decision)
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.Deadline = time.Minute
+	retrier = NewRetrier(policy)
+	now := retrier.start
+	retrier.now = func() time.Time { return now }
+
+	now = now.Add(59*time.Second + 900*time.Millisecond)
+	decision = retrier.Next(0, true, "")
+	if !decision.Retry || decision.Delay > 100*time.Millisecond {
+		t.Errorf("delay should be capped to the deadline, got %+v", decision)
+	}
+	now = now.Add(time.Second)
+	decision = retrier.Next(0, true, "")
+	if decision.Retry || !decision.Exhausted {
+		t.Errorf("deadline decision = %+v", decision)
+	}
+}
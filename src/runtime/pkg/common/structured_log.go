@@ -0,0 +1,204 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LogFormat selects which user log lines are parsed into structured logs
+type LogFormat string
+
+const (
+	LogFormatText   LogFormat = "text"
+	LogFormatJson   LogFormat = "json"
+	LogFormatLogfmt LogFormat = "logfmt"
+	// Both JSON and logfmt lines are parsed
+	LogFormatAuto LogFormat = "auto"
+)
+
+// Fields beyond the limit are dropped to bound the size of log messages
+const MaxLogFields int = 64
+
+var logLevelKeys = []string{"level", "lvl", "severity", "levelname", "log.level"}
+var logMessageKeys = []string{"msg", "message"}
+
+// Names of the levels written by common logging libraries mapped to the standard ones
+var logLevelAliases = map[string]string{
+	"trace":    "DEBUG",
+	"debug":    "DEBUG",
+	"info":     "INFO",
+	"notice":   "INFO",
+	"warn":     "WARNING",
+	"warning":  "WARNING",
+	"err":      "ERROR",
+	"error":    "ERROR",
+	"fatal":    "CRITICAL",
+	"critical": "CRITICAL",
+	"panic":    "CRITICAL",
+}
+
+// StructuredLog is the level and the fields of a parsed log line
+type StructuredLog struct {
+	Level  string
+	Fields map[string]string
+}
+
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch LogFormat(format) {
+	case LogFormatText, LogFormatJson, LogFormatLogfmt, LogFormatAuto:
+		return LogFormat(format), nil
+	}
+	return "", fmt.Errorf("Unknown log format %s", format)
+}
+
+// ParseStructuredLog parses a JSON object or logfmt line. Lines which are not in the format,
+// or which have neither a level nor a message, are not parsed.
+func ParseStructuredLog(line string, format LogFormat) (StructuredLog, bool) {
+	var fields map[string]string
+	ok := false
+	trimmed := strings.TrimSpace(line)
+	if (format == LogFormatJson || format == LogFormatAuto) && strings.HasPrefix(trimmed, "{") {
+		fields, ok = parseJsonLog(trimmed)
+	}
+	if !ok && (format == LogFormatLogfmt || format == LogFormatAuto) {
+		fields, ok = parseLogfmt(trimmed)
+	}
+	if !ok || !(hasAnyKey(fields, logLevelKeys) || hasAnyKey(fields, logMessageKeys)) {
+		return StructuredLog{}, false
+	}
+
+	structured := StructuredLog{Fields: map[string]string{}}
+	for _, key := range logLevelKeys {
+		if level, found := fields[key]; found {
+			structured.Level = normalizeLogLevel(level)
+			delete(fields, key)
+			break
+		}
+	}
+	for _, key := range logMessageKeys {
+		delete(fields, key)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == MaxLogFields {
+			break
+		}
+		structured.Fields[key] = fields[key]
+	}
+	return structured, true
+}
+
+func hasAnyKey(fields map[string]string, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := fields[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLogLevel(level string) string {
+	if alias, ok := logLevelAliases[strings.ToLower(strings.TrimSpace(level))]; ok {
+		return alias
+	}
+	return strings.ToUpper(strings.TrimSpace(level))
+}
+
+// parseJsonLog returns the top level values of a JSON object. Values which are not strings
+// are kept in their JSON encoding.
+func parseJsonLog(line string) (map[string]string, bool) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &object); err != nil {
+		return nil, false
+	}
+	fields := make(map[string]string, len(object))
+	for key, raw := range object {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			fields[key] = text
+		} else {
+			fields[key] = string(raw)
+		}
+	}
+	return fields, true
+}
+
+// parseLogfmt parses a line of key=value pairs where values with spaces are double quoted.
+// The line is rejected if any part of it is not a pair.
+func parseLogfmt(line string) (map[string]string, bool) {
+	fields := map[string]string{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil, false
+		}
+		key := line[start:i]
+		i++
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			unquoted, err := unquoteLogfmt(line[i : end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			value = line[start:i]
+		}
+		fields[key] = value
+	}
+	return fields, len(fields) > 0
+}
+
+func unquoteLogfmt(quoted string) (string, error) {
+	var value string
+	err := json.Unmarshal([]byte(quoted), &value)
+	return value, err
+}
@@ -0,0 +1,126 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStructuredLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		format LogFormat
+		want   StructuredLog
+		ok     bool
+	}{
+		{
+			name:   "json",
+			line:   `{"level": "warn", "msg": "slow step", "logger": "train", "step": 12, "tags": ["a"]}`,
+			format: LogFormatJson,
+			want: StructuredLog{Level: "WARNING", Fields: map[string]string{
+				"logger": "train", "step": "12", "tags": `["a"]`}},
+			ok: true,
+		},
+		{
+			name:   "python logging json",
+			line:   `{"levelname": "ERROR", "message": "failed", "name": "data"}`,
+			format: LogFormatAuto,
+			want:   StructuredLog{Level: "ERROR", Fields: map[string]string{"name": "data"}},
+			ok:     true,
+		},
+		{
+			name:   "logfmt",
+			line:   `level=info msg="epoch done" loss=0.25 note="say \"hi\""`,
+			format: LogFormatLogfmt,
+			want: StructuredLog{Level: "INFO", Fields: map[string]string{
+				"loss": "0.25", "note": `say "hi"`}},
+			ok: true,
+		},
+		{
+			name:   "logfmt in auto",
+			line:   `lvl=debug component=loader`,
+			format: LogFormatAuto,
+			want:   StructuredLog{Level: "DEBUG", Fields: map[string]string{"component": "loader"}},
+			ok:     true,
+		},
+		{
+			name:   "json is not parsed as logfmt",
+			line:   `{"level": "info"}`,
+			format: LogFormatLogfmt,
+		},
+		{
+			name:   "text format",
+			line:   `{"level": "info"}`,
+			format: LogFormatText,
+		},
+		{
+			name:   "plain text",
+			line:   "Epoch 1: loss=0.25",
+			format: LogFormatAuto,
+		},
+		{
+			name:   "pairs without level or message",
+			line:   "loss=0.25 accuracy=0.9",
+			format: LogFormatAuto,
+		},
+		{
+			name:   "invalid json",
+			line:   `{"level": "info"`,
+			format: LogFormatJson,
+		},
+		{
+			name:   "unterminated quote",
+			line:   `level=info msg="epoch`,
+			format: LogFormatLogfmt,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseStructuredLog(test.line, test.format)
+			if ok != test.ok {
+				t.Fatalf("ok = %v, want %v", ok, test.ok)
+			}
+			if ok && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseStructuredLog_LimitsFields(t *testing.T) {
+	line := "level=info"
+	for i := 0; i < MaxLogFields+10; i++ {
+		line += " field" + string(rune('a'+i%26)) + string(rune('a'+i/26)) + "=1"
+	}
+	structured, ok := ParseStructuredLog(line, LogFormatLogfmt)
+	if !ok || len(structured.Fields) != MaxLogFields {
+		t.Errorf("expected %d fields, got %d", MaxLogFields, len(structured.Fields))
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	if format, err := ParseLogFormat("auto"); err != nil || format != LogFormatAuto {
+		t.Errorf("ParseLogFormat(auto) = %v, %v", format, err)
+	}
+	if _, err := ParseLogFormat("xml"); err == nil {
+		t.Error("ParseLogFormat(xml) should fail")
+	}
+}
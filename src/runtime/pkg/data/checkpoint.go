@@ -0,0 +1,642 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2025-2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+const (
+	// Every round overwrites the objects at the checkpoint url
+	CheckpointVersioningNone string = "none"
+	// Every round is written to a new timestamped prefix below the checkpoint url
+	CheckpointVersioningTimestamp string = "timestamp"
+	// Every round is written to a new step numbered prefix below the checkpoint url
+	CheckpointVersioningStep string = "step"
+
+	// CheckpointLatestFileName is written below the checkpoint url after every successful
+	// versioned round and points to the most recent complete version.
+	CheckpointLatestFileName string = "latest"
+)
+
+type checkpointSpec struct {
+	path       string
+	url        string
+	frequency  time.Duration
+	regex      string
+	versioning string
+	keep       int
+}
+
+// parseCheckpointInfo parses a JSON checkpoint spec or the legacy
+// `path;url;frequency;regex[;versioning;keep]`. The legacy retention fields are only present for
+// versioned checkpoints and are parsed from the end, since the regex may itself contain
+// semicolons. All problems of the spec are returned together.
+func parseCheckpointInfo(checkpointInfo string) (checkpointSpec, error) {
+	spec := checkpointSpec{versioning: CheckpointVersioningNone}
+	if isJsonSpec(checkpointInfo) {
+		var entry CheckpointSpecEntry
+		if err := decodeSpec(checkpointInfo, &entry, &entry.Version); err != nil {
+			return spec, err
+		}
+		spec.path = entry.Path
+		spec.url = strings.TrimSuffix(entry.Url, "/")
+		spec.frequency = time.Duration(entry.Frequency) * time.Second
+		spec.regex = entry.Regex
+		spec.keep = entry.Keep
+		if entry.Versioning != "" {
+			spec.versioning = entry.Versioning
+		}
+		return spec, spec.validate()
+	}
+
+	fields := strings.Split(checkpointInfo, ";")
+	if len(fields) < 4 {
+		return spec, fmt.Errorf("spec, expected path;url;frequency;regex: %s", checkpointInfo)
+	}
+	spec.path = fields[0]
+	spec.url = strings.TrimSuffix(fields[1], "/")
+
+	var problems []error
+	frequency, err := strconv.Atoi(fields[2])
+	if err != nil {
+		problems = append(problems, fmt.Errorf("frequency: %s", fields[2]))
+	}
+	spec.frequency = time.Duration(frequency) * time.Second
+
+	rest := fields[3:]
+	if len(rest) >= 3 {
+		versioning := rest[len(rest)-2]
+		keep, err := strconv.Atoi(rest[len(rest)-1])
+		switch versioning {
+		case CheckpointVersioningNone, CheckpointVersioningTimestamp, CheckpointVersioningStep:
+			if err == nil {
+				spec.versioning = versioning
+				spec.keep = keep
+				rest = rest[:len(rest)-2]
+			}
+		}
+	}
+	spec.regex = strings.Join(rest, ";")
+	return spec, errors.Join(append(problems, spec.validate())...)
+}
+
+// ParseCheckpoints returns a description of every problem of the checkpoint specs
+func ParseCheckpoints(checkpoints []string) []string {
+	var problems []string
+	for i, value := range checkpoints {
+		if _, err := parseCheckpointInfo(value); err != nil {
+			problem := strings.ReplaceAll(err.Error(), "\n", "; ")
+			problems = append(problems, fmt.Sprintf("checkpoint %d (%s): %s", i, value, problem))
+		}
+	}
+	return problems
+}
+
+// CheckpointPaths returns the local path of every valid checkpoint spec
+func CheckpointPaths(checkpoints []string) []string {
+	var paths []string
+	for _, value := range checkpoints {
+		if spec, err := parseCheckpointInfo(value); err == nil {
+			paths = append(paths, spec.path)
+		}
+	}
+	return paths
+}
+
+func (spec checkpointSpec) validate() error {
+	var problems []error
+	if spec.path == "" {
+		problems = append(problems, fmt.Errorf("path is required"))
+	}
+	for _, problem := range validateUrl(spec.url) {
+		problems = append(problems, errors.New(problem))
+	}
+	if spec.frequency < 0 {
+		problems = append(problems, fmt.Errorf("frequency must not be negative"))
+	}
+	switch spec.versioning {
+	case CheckpointVersioningNone:
+		if spec.keep != 0 {
+			problems = append(problems, fmt.Errorf("keep requires versioning"))
+		}
+	case CheckpointVersioningTimestamp, CheckpointVersioningStep:
+	default:
+		problems = append(problems, fmt.Errorf("unknown versioning %q", spec.versioning))
+	}
+	if spec.keep < 0 {
+		problems = append(problems, fmt.Errorf("keep must not be negative"))
+	}
+	return errors.Join(problems...)
+}
+
+// ValidateCheckpoint returns every problem of a legacy or JSON checkpoint spec
+func ValidateCheckpoint(checkpointInfo string) []string {
+	if _, err := parseCheckpointInfo(checkpointInfo); err != nil {
+		return strings.Split(err.Error(), "\n")
+	}
+	return nil
+}
+
+type checkpointFileState struct {
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+type checkpointChange struct {
+	// Object key relative to the checkpoint url, identical to the key a full upload would use
+	relPath string
+	absPath string
+	state   checkpointFileState
+}
+
+// checkpointTracker remembers the files uploaded by previous checkpoint rounds so that only
+// new or modified files are uploaded in the next round.
+type checkpointTracker struct {
+	path        string
+	settleDelay time.Duration
+	files       map[string]checkpointFileState
+}
+
+func newCheckpointTracker(path string, settleDelay time.Duration) *checkpointTracker {
+	return &checkpointTracker{
+		path:        path,
+		settleDelay: settleDelay,
+		files:       make(map[string]checkpointFileState),
+	}
+}
+
+// scan returns the files that are new or modified since the last committed round, and the
+// number of files skipped because they were modified within the settle delay. A file whose
+// size or mtime changed but whose content hash did not is not reported as modified. The settle
+// delay is ignored on the final round since the user command is no longer writing.
+func (t *checkpointTracker) scan(final bool) ([]checkpointChange, int, error) {
+	root := filepath.Clean(t.path)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keys are relative to the parent of the checkpoint path, matching `osmo data upload`
+	baseDir := filepath.Dir(root)
+	var changes []checkpointChange
+	unsettled := 0
+	seen := make(map[string]bool)
+
+	visit := func(path string, info fs.FileInfo) error {
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		seen[relPath] = true
+
+		if !final && t.settleDelay > 0 && time.Since(info.ModTime()) < t.settleDelay {
+			unsettled++
+			return nil
+		}
+
+		previous, exists := t.files[relPath]
+		if exists && previous.size == info.Size() && previous.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		digest, size, err := hashFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		state := checkpointFileState{size: size, modTime: info.ModTime(), sha256: digest}
+		if exists && previous.sha256 == digest {
+			// Only the metadata changed
+			t.files[relPath] = state
+			return nil
+		}
+		changes = append(changes, checkpointChange{relPath, path, state})
+		return nil
+	}
+
+	if info.Mode().IsRegular() {
+		err = visit(root, info)
+	} else {
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			// Symlinks are followed for files but not for directories
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			return visit(path, info)
+		})
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Forget deleted files so that they are uploaded again if they reappear
+	for relPath := range t.files {
+		if !seen[relPath] {
+			delete(t.files, relPath)
+		}
+	}
+	return changes, unsettled, nil
+}
+
+// snapshot returns every tracked file with the changes applied, for rounds that upload the full
+// checkpoint instead of only the changes.
+func (t *checkpointTracker) snapshot(changes []checkpointChange) []checkpointChange {
+	baseDir := filepath.Dir(filepath.Clean(t.path))
+	files := append([]checkpointChange{}, changes...)
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		changed[change.relPath] = true
+	}
+	for relPath, state := range t.files {
+		if !changed[relPath] {
+			files = append(files, checkpointChange{relPath, filepath.Join(baseDir, relPath), state})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].relPath < files[j].relPath })
+	return files
+}
+
+// commit records the changes as uploaded
+func (t *checkpointTracker) commit(changes []checkpointChange) {
+	for _, change := range changes {
+		t.files[change.relPath] = change.state
+	}
+}
+
+// stageCheckpointChanges links the changed files into a temporary directory that mirrors the
+// object keys, so a single `<dir>/*` upload only transfers the changed files.
+func stageCheckpointChanges(changes []checkpointChange) (string, error) {
+	stagingDir, err := os.MkdirTemp("", "osmo_checkpoint")
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		stagedPath := filepath.Join(stagingDir, change.relPath)
+		if err := os.MkdirAll(filepath.Dir(stagedPath), os.ModePerm); err != nil {
+			os.RemoveAll(stagingDir)
+			return "", err
+		}
+		if err := os.Symlink(change.absPath, stagedPath); err != nil {
+			os.RemoveAll(stagingDir)
+			return "", err
+		}
+	}
+	return stagingDir, nil
+}
+
+// Version names of versioned checkpoints below the checkpoint url
+var (
+	checkpointStepVersion      = regexp.MustCompile(`^step-(\d+)$`)
+	checkpointTimestampVersion = regexp.MustCompile(`^\d{8}T\d{6}\.\d{3}Z$`)
+)
+
+// checkpointRetention names the versions of a versioned checkpoint and rotates old versions
+// out. It is seeded from the versions already below the checkpoint url, so a restarted or
+// retried task continues the step counter and rotates the versions of earlier attempts too.
+type checkpointRetention struct {
+	versioning string
+	keep       int
+	seeded     bool
+	step       int
+	versions   []string
+}
+
+// seed lists the versions below the url once. Until it succeeds no version is written, since
+// the step counter could otherwise overwrite an existing version.
+func (r *checkpointRetention) seed(ctx context.Context, url string, opsChan chan string) error {
+	if r.seeded {
+		return nil
+	}
+	listed, err := listCheckpointVersions(ctx, url, opsChan)
+	if err != nil {
+		return err
+	}
+	var versions []string
+	for _, version := range listed {
+		if r.versioning == CheckpointVersioningStep {
+			if match := checkpointStepVersion.FindStringSubmatch(version); match != nil {
+				step, _ := strconv.Atoi(match[1])
+				r.step = max(r.step, step)
+				versions = append(versions, version)
+			}
+		} else if checkpointTimestampVersion.MatchString(version) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareCheckpointVersions(versions[i], versions[j]) < 0
+	})
+	r.versions = append(versions, r.versions...)
+	r.seeded = true
+	return nil
+}
+
+// compareCheckpointVersions orders step versions by their step and timestamps by their time
+func compareCheckpointVersions(a, b string) int {
+	matchA := checkpointStepVersion.FindStringSubmatch(a)
+	matchB := checkpointStepVersion.FindStringSubmatch(b)
+	if matchA != nil && matchB != nil {
+		stepA, _ := strconv.Atoi(matchA[1])
+		stepB, _ := strconv.Atoi(matchB[1])
+		return stepA - stepB
+	}
+	return strings.Compare(a, b)
+}
+
+func (r *checkpointRetention) nextVersion() string {
+	if r.versioning == CheckpointVersioningStep {
+		r.step++
+		return fmt.Sprintf("step-%06d", r.step)
+	}
+	return time.Now().UTC().Format("20060102T150405.000Z")
+}
+
+// expired records a completed version and returns the versions beyond the keep-last-N limit
+func (r *checkpointRetention) expired(version string) []string {
+	r.versions = append(r.versions, version)
+	if r.keep <= 0 || len(r.versions) <= r.keep {
+		return nil
+	}
+	return append([]string{}, r.versions[:len(r.versions)-r.keep]...)
+}
+
+func (r *checkpointRetention) forget(version string) {
+	for i, existing := range r.versions {
+		if existing == version {
+			r.versions = append(r.versions[:i], r.versions[i+1:]...)
+			return
+		}
+	}
+}
+
+// listCheckpointVersions returns the distinct version prefixes of the objects below the url
+func listCheckpointVersions(ctx context.Context, url string,
+	opsChan chan string) ([]string, error) {
+	output, err := RunOSMOCommandWithRetry(ctx,
+		[]string{"osmo", "data", "list", url + "/", "--recursive", "--no-pager"},
+		RetryPolicies[common.CheckpointOperation], opsChan, osmo_errors.DOWNLOAD_FAILED_CODE)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var versions []string
+	for _, key := range strings.Split(output.String(), "\n") {
+		key = strings.TrimPrefix(strings.TrimSpace(key), url+"/")
+		for _, segment := range strings.Split(key, "/") {
+			if checkpointStepVersion.MatchString(segment) ||
+				checkpointTimestampVersion.MatchString(segment) {
+				if !seen[segment] {
+					seen[segment] = true
+					versions = append(versions, segment)
+				}
+				break
+			}
+		}
+	}
+	return versions, nil
+}
+
+type checkpointPointer struct {
+	Version       string    `json:"version"`
+	Url           string    `json:"url"`
+	Time          time.Time `json:"time"`
+	SizeInBytes   int64     `json:"size_in_bytes"`
+	NumberOfFiles int       `json:"number_of_files"`
+}
+
+// writeLatestPointer uploads the latest pointer of a versioned checkpoint
+func writeLatestPointer(ctx context.Context, url string, pointer checkpointPointer,
+	opsChan chan string) error {
+	pointerDir, err := os.MkdirTemp("", "osmo_checkpoint_latest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(pointerDir)
+
+	pointerJson, err := json.MarshalIndent(pointer, "", "  ")
+	if err != nil {
+		return err
+	}
+	pointerPath := filepath.Join(pointerDir, CheckpointLatestFileName)
+	if err := os.WriteFile(pointerPath, pointerJson, 0644); err != nil {
+		return err
+	}
+	_, err = uploadData(ctx, url, pointerPath, "", opsChan, "", common.CheckpointOperation)
+	return err
+}
+
+// deleteCheckpointVersion removes an expired version. Failures are reported but do not stop
+// checkpointing, the version is retried in the next rotation.
+func deleteCheckpointVersion(ctx context.Context, url string, opsChan chan string) bool {
+	// The trailing slash keeps step-000001 from also deleting step-000010
+	_, err := RunOSMOCommandWithRetry(ctx, []string{"osmo", "data", "delete", url + "/"},
+		RetryPolicies[common.CheckpointOperation], opsChan, osmo_errors.UPLOAD_FAILED_CODE)
+	if err != nil {
+		opsChan <- fmt.Sprintf("Failed to delete expired checkpoint %s: %s", url, err)
+		return false
+	}
+	opsChan <- fmt.Sprintf("Deleted expired checkpoint %s", url)
+	return true
+}
+
+// uploadCheckpointRound uploads the files changed since the last round and reports the round.
+// Versioned checkpoints upload a full snapshot to a new version, and only once no file is still
+// being written so that every version is complete. The settle delay is ignored for final rounds.
+func uploadCheckpointRound(ctx context.Context, tracker *checkpointTracker, spec checkpointSpec,
+	retention *checkpointRetention, final bool, opsChan chan string,
+	requestChan chan messages.Request) CheckpointResult {
+
+	startTime := time.Now()
+	changes, unsettled, err := tracker.scan(final)
+	if os.IsNotExist(err) {
+		message := fmt.Sprintf("Checkpoint path %s does not exist yet", tracker.path)
+		opsChan <- message
+		return CheckpointResult{Status: CheckpointUnchanged, Message: message}
+	} else if err != nil {
+		message := fmt.Sprintf("Failed to scan checkpoint %s: %s", tracker.path, err)
+		opsChan <- message
+		return CheckpointResult{Status: CheckpointFailed, Message: message}
+	}
+	versioned := spec.versioning != CheckpointVersioningNone
+	if unsettled > 0 {
+		if versioned && len(changes) > 0 {
+			message := fmt.Sprintf(
+				"Deferring checkpoint version, %d checkpoint files still being written", unsettled)
+			opsChan <- message
+			return CheckpointResult{Status: CheckpointDeferred, Message: message}
+		}
+		opsChan <- fmt.Sprintf("Skipping %d checkpoint files still being written", unsettled)
+	}
+	if len(changes) == 0 {
+		message := fmt.Sprintf("No checkpoint changes in %s since last round", tracker.path)
+		opsChan <- message
+		return CheckpointResult{Status: CheckpointUnchanged, Message: message}
+	}
+
+	files := changes
+	destination := spec.url
+	version := ""
+	if versioned {
+		if err := retention.seed(ctx, spec.url, opsChan); err != nil {
+			message := fmt.Sprintf("Failed to list checkpoint versions of %s: %s", spec.url, err)
+			opsChan <- message
+			return CheckpointResult{Status: CheckpointFailed, Message: message}
+		}
+		files = tracker.snapshot(changes)
+		version = retention.nextVersion()
+		destination = spec.url + "/" + version
+	}
+
+	stagingDir, err := stageCheckpointChanges(files)
+	if err != nil {
+		message := fmt.Sprintf("Failed to stage checkpoint %s: %s", tracker.path, err)
+		opsChan <- message
+		return CheckpointResult{Status: CheckpointFailed, Message: message}
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if versioned {
+		opsChan <- fmt.Sprintf("Checkpointing %d files from %s to %s...",
+			len(files), tracker.path, destination)
+	} else {
+		opsChan <- fmt.Sprintf("Checkpointing %d changed files from %s to %s...",
+			len(files), tracker.path, destination)
+	}
+	_, err = uploadData(ctx, destination, stagingDir+"/*", spec.regex, opsChan, "",
+		common.CheckpointOperation)
+	if err != nil {
+		// The changes are not committed, so the next round uploads them again
+		message := fmt.Sprintf("Failed to upload checkpoint %s: %s", tracker.path, err)
+		opsChan <- message
+		return CheckpointResult{Status: CheckpointFailed, Message: message}
+	}
+	tracker.commit(changes)
+
+	var sizeInBytes int64
+	for _, file := range files {
+		sizeInBytes += file.state.size
+	}
+	endTime := time.Now()
+
+	if versioned {
+		// The pointer is only moved once the version is completely uploaded
+		err := writeLatestPointer(ctx, spec.url, checkpointPointer{
+			Version:       version,
+			Url:           destination,
+			Time:          endTime,
+			SizeInBytes:   sizeInBytes,
+			NumberOfFiles: len(files),
+		}, opsChan)
+		if err != nil {
+			opsChan <- fmt.Sprintf("Failed to write latest checkpoint pointer: %s", err)
+		} else {
+			opsChan <- fmt.Sprintf("Latest checkpoint of %s is now %s", spec.url, version)
+		}
+		for _, expired := range retention.expired(version) {
+			if deleteCheckpointVersion(ctx, spec.url+"/"+expired, opsChan) {
+				retention.forget(expired)
+			}
+		}
+	}
+
+	requestChan <- messages.UserCheckpointRequest(messages.CheckpointRound{
+		Url:           destination,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		SizeInBytes:   sizeInBytes,
+		NumberOfFiles: len(files),
+	})
+	return CheckpointResult{
+		Status:        CheckpointUploaded,
+		Url:           destination,
+		SizeInBytes:   sizeInBytes,
+		NumberOfFiles: len(files),
+	}
+}
+
+// Checkpoint uploads the checkpoint at its frequency and when triggered. Once stop is closed,
+// it uploads a final round and returns. Canceling ctx aborts it, including the final round.
+func Checkpoint(ctx context.Context, opsChan chan string, requestChan chan messages.Request,
+	checkpointInfo string, trigger *CheckpointTrigger, settleDelay time.Duration,
+	waitCheckpoint *sync.WaitGroup, stop chan bool) {
+
+	defer waitCheckpoint.Done()
+	var triggerRequests chan chan CheckpointResult
+	if trigger != nil {
+		triggerRequests = trigger.requests
+		defer close(trigger.done)
+	}
+	spec, err := parseCheckpointInfo(checkpointInfo)
+	if err != nil {
+		opsChan <- fmt.Sprintf("Invalid checkpoint %s", err)
+		return
+	}
+	tracker := newCheckpointTracker(spec.path, settleDelay)
+	retention := &checkpointRetention{versioning: spec.versioning, keep: spec.keep}
+
+	// Sleep until the duration has passed or stop is requested
+	timer := time.NewTimer(spec.frequency)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			// Upload the data
+			uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+			timer.Reset(spec.frequency)
+		case result := <-triggerRequests:
+			// The user process asserts the checkpoint is complete, so the settle delay is
+			// skipped. The next timed round is counted from the end of this one.
+			timer.Stop()
+			result <- uploadCheckpointRound(ctx, tracker, spec, retention, true, opsChan,
+				requestChan)
+			timer.Reset(spec.frequency)
+		case <-stop:
+			uploadCheckpointRound(ctx, tracker, spec, retention, true, opsChan, requestChan)
+			opsChan <- fmt.Sprintf("Checkpointing data from %s to %s finished", spec.path,
+				spec.url)
+			return
+		}
+	}
+}
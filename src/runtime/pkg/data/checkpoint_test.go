@@ -0,0 +1,435 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+)
+
+// ---------------------------------------------------------------------------
+// parseCheckpointInfo — legacy and versioned specs
+// ---------------------------------------------------------------------------
+
+func TestParseCheckpointInfo_LegacySpecIsNotVersioned(t *testing.T) {
+	spec, err := parseCheckpointInfo("/ckpt;s3://bucket/ckpt/;30;.*\\.pt")
+	if err != nil {
+		t.Fatalf("parseCheckpointInfo: %v", err)
+	}
+	if spec.path != "/ckpt" || spec.url != "s3://bucket/ckpt" || spec.frequency != 30*time.Second ||
+		spec.regex != ".*\\.pt" || spec.versioning != CheckpointVersioningNone || spec.keep != 0 {
+		t.Errorf("spec = %#v", spec)
+	}
+}
+
+func TestParseCheckpointInfo_RetentionFieldsParsedAfterRegexWithSemicolons(t *testing.T) {
+	spec, err := parseCheckpointInfo("/ckpt;s3://bucket/ckpt;30;a;b;step;3")
+	if err != nil {
+		t.Fatalf("parseCheckpointInfo: %v", err)
+	}
+	if spec.regex != "a;b" || spec.versioning != CheckpointVersioningStep || spec.keep != 3 {
+		t.Errorf("spec = %#v, want regex a;b versioning step keep 3", spec)
+	}
+}
+
+func TestParseCheckpointInfo_RejectsShortSpecAndBadFrequency(t *testing.T) {
+	for _, info := range []string{"/ckpt;s3://bucket/ckpt;30", "/ckpt;s3://bucket/ckpt;soon;"} {
+		if _, err := parseCheckpointInfo(info); err == nil {
+			t.Errorf("expected error for %q", info)
+		}
+	}
+}
+
+func TestParseCheckpoints_ReportsEveryInvalidSpec(t *testing.T) {
+	problems := ParseCheckpoints([]string{
+		"/ckpt;s3://bucket/ckpt;30;",
+		"/ckpt;s3://bucket/ckpt;soon;",
+		"/other;s3://bucket/other;30;;step;-1",
+	})
+
+	if len(problems) != 2 {
+		t.Fatalf("problems = %v, want one for each of the last two specs", problems)
+	}
+	if !strings.HasPrefix(problems[0], "checkpoint 1 ") ||
+		!strings.HasPrefix(problems[1], "checkpoint 2 ") ||
+		strings.Contains(problems[1], "\n") {
+		t.Errorf("problems = %q, want single line problems of checkpoints 1 and 2", problems)
+	}
+}
+
+func TestCheckpointPaths_SkipsInvalidSpecs(t *testing.T) {
+	paths := CheckpointPaths([]string{
+		"/ckpt;s3://bucket/ckpt;30;",
+		"/bad;s3://bucket/bad;soon;",
+		`{"version": 1, "path": "/other", "url": "s3://bucket/other", "frequency": 30}`,
+	})
+
+	if len(paths) != 2 || paths[0] != "/ckpt" || paths[1] != "/other" {
+		t.Errorf("paths = %v, want [/ckpt /other]", paths)
+	}
+}
+
+// changedPaths returns the sorted object keys of the given changes.
+func changedPaths(changes []checkpointChange) []string {
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, change.relPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ---------------------------------------------------------------------------
+// checkpointTracker — new, modified, touched, unsettled and deleted files
+// ---------------------------------------------------------------------------
+
+func TestCheckpointTracker_FirstScanReportsAllFilesWithParentRelativeKeys(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1", "sub/b.pt": "2"})
+
+	tracker := newCheckpointTracker(root, 0)
+	changes, unsettled, err := tracker.scan(false)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	got := changedPaths(changes)
+	want := []string{"ckpt/a.pt", "ckpt/sub/b.pt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") || unsettled != 0 {
+		t.Errorf("changes = %v unsettled = %d, want %v and 0", got, unsettled, want)
+	}
+}
+
+func TestCheckpointTracker_OnlyReportsNewAndModifiedFilesAfterCommit(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"same.pt": "1", "modified.pt": "2"})
+
+	tracker := newCheckpointTracker(root, 0)
+	changes, _, _ := tracker.scan(false)
+	tracker.commit(changes)
+
+	writeTree(t, root, map[string]string{"modified.pt": "22", "new.pt": "3"})
+	changes, _, err := tracker.scan(false)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	got := changedPaths(changes)
+	if strings.Join(got, ",") != "ckpt/modified.pt,ckpt/new.pt" {
+		t.Errorf("changes = %v, want [ckpt/modified.pt ckpt/new.pt]", got)
+	}
+}
+
+func TestCheckpointTracker_TouchedFileWithSameContentIsNotReported(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "payload"})
+
+	tracker := newCheckpointTracker(root, 0)
+	changes, _, _ := tracker.scan(false)
+	tracker.commit(changes)
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "a.pt"), later, later); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	changes, _, _ = tracker.scan(false)
+	if len(changes) != 0 {
+		t.Errorf("expected touched file to be skipped, got %v", changedPaths(changes))
+	}
+}
+
+func TestCheckpointTracker_SettleDelaySkipsRecentFilesUntilFinalRound(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"old.pt": "1", "writing.pt": "2"})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "old.pt"), old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	tracker := newCheckpointTracker(root, time.Minute)
+	changes, unsettled, _ := tracker.scan(false)
+	if got := changedPaths(changes); len(got) != 1 || got[0] != "ckpt/old.pt" || unsettled != 1 {
+		t.Errorf("changes = %v unsettled = %d, want [ckpt/old.pt] and 1", got, unsettled)
+	}
+	tracker.commit(changes)
+
+	changes, unsettled, _ = tracker.scan(true)
+	if got := changedPaths(changes); len(got) != 1 || got[0] != "ckpt/writing.pt" || unsettled != 0 {
+		t.Errorf("final changes = %v unsettled = %d, want [ckpt/writing.pt] and 0", got, unsettled)
+	}
+}
+
+func TestCheckpointTracker_DeletedAndRecreatedFileIsReportedAgain(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1"})
+
+	tracker := newCheckpointTracker(root, 0)
+	changes, _, _ := tracker.scan(false)
+	tracker.commit(changes)
+
+	os.Remove(filepath.Join(root, "a.pt"))
+	tracker.scan(false)
+	writeTree(t, root, map[string]string{"a.pt": "1"})
+
+	changes, _, _ = tracker.scan(false)
+	if len(changes) != 1 {
+		t.Errorf("expected recreated file to be reported, got %v", changedPaths(changes))
+	}
+}
+
+func TestCheckpointTracker_SingleFileUsesBasenameKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.pt")
+	writeTree(t, filepath.Dir(path), map[string]string{"model.pt": "1"})
+
+	changes, _, err := newCheckpointTracker(path, 0).scan(false)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got := changedPaths(changes); len(got) != 1 || got[0] != "model.pt" {
+		t.Errorf("changes = %v, want [model.pt]", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// uploadCheckpointRound — stages only changed files and reports the round
+// ---------------------------------------------------------------------------
+
+func TestUploadCheckpointRound_UploadsChangedFilesAndReportsBytes(t *testing.T) {
+	ctx := context.Background()
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	// The fake osmo lists the staged files (following the symlinks) for each upload
+	logFile := filepath.Join(t.TempDir(), "uploads")
+	body := fmt.Sprintf("#!/bin/sh\ncd \"$(dirname \"$4\")\" && find -L . -type f | sort >> %s\n",
+		logFile)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1234", "b.pt": "56"})
+	tracker := newCheckpointTracker(root, 0)
+	opsChan := make(chan string, 64)
+	requestChan := make(chan messages.Request, 4)
+	spec := checkpointSpec{url: "s3://bucket/ckpt", versioning: CheckpointVersioningNone}
+	retention := &checkpointRetention{versioning: spec.versioning}
+
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+	writeTree(t, root, map[string]string{"b.pt": "78"})
+	os.Chtimes(filepath.Join(root, "b.pt"), time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+
+	uploads, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read upload log: %v", err)
+	}
+	want := "./ckpt/a.pt\n./ckpt/b.pt\n./ckpt/b.pt\n"
+	if string(uploads) != want {
+		t.Errorf("uploaded files = %q, want %q", string(uploads), want)
+	}
+
+	close(requestChan)
+	var sizes []int64
+	for request := range requestChan {
+		if request.Type != messages.UserCheckpoint || request.Checkpoint == nil {
+			t.Fatalf("unexpected request %#v", request)
+		}
+		sizes = append(sizes, request.Checkpoint.SizeInBytes)
+	}
+	if len(sizes) != 2 || sizes[0] != 6 || sizes[1] != 2 {
+		t.Errorf("round sizes = %v, want [6 2] (no report for the empty round)", sizes)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Versioned checkpoints — full snapshots, latest pointer and rotation
+// ---------------------------------------------------------------------------
+
+func TestUploadCheckpointRound_VersionedUploadsSnapshotsPointerAndRotates(t *testing.T) {
+	ctx := context.Background()
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	// The fake osmo logs every command and uri with the files of uploads, and keeps the latest
+	// pointer
+	logDir := t.TempDir()
+	body := fmt.Sprintf("#!/bin/sh\necho \"$2 $3\" >> %[1]s/commands\n"+
+		"if [ \"$2\" = upload ]; then\n"+
+		"  case \"$4\" in *%[2]s) cp \"$4\" %[1]s/;;\n"+
+		"  *) cd \"$(dirname \"$4\")\" && find -L . -type f | sort >> %[1]s/commands;; esac\n"+
+		"fi\n", logDir, CheckpointLatestFileName)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1", "b.pt": "2"})
+	tracker := newCheckpointTracker(root, 0)
+	spec := checkpointSpec{url: "s3://bucket/ckpt", versioning: CheckpointVersioningStep, keep: 1}
+	retention := &checkpointRetention{versioning: spec.versioning, keep: spec.keep}
+	opsChan := make(chan string, 64)
+	requestChan := make(chan messages.Request, 4)
+
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+	writeTree(t, root, map[string]string{"b.pt": "22"})
+	os.Chtimes(filepath.Join(root, "b.pt"), time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, opsChan, requestChan)
+
+	commands, err := os.ReadFile(filepath.Join(logDir, "commands"))
+	if err != nil {
+		t.Fatalf("read command log: %v", err)
+	}
+	// Both versions contain the full checkpoint, and the first version is rotated out
+	want := "list s3://bucket/ckpt/\n" +
+		"upload s3://bucket/ckpt/step-000001\n./ckpt/a.pt\n./ckpt/b.pt\n" +
+		"upload s3://bucket/ckpt\n" +
+		"upload s3://bucket/ckpt/step-000002\n./ckpt/a.pt\n./ckpt/b.pt\n" +
+		"upload s3://bucket/ckpt\n" +
+		"delete s3://bucket/ckpt/step-000001/\n"
+	if string(commands) != want {
+		t.Errorf("commands = %q, want %q", string(commands), want)
+	}
+	if len(retention.versions) != 1 || retention.versions[0] != "step-000002" {
+		t.Errorf("retained versions = %v, want [step-000002]", retention.versions)
+	}
+
+	var pointer checkpointPointer
+	pointerJson, err := os.ReadFile(filepath.Join(logDir, CheckpointLatestFileName))
+	if err != nil {
+		t.Fatalf("read latest pointer: %v", err)
+	}
+	if err := json.Unmarshal(pointerJson, &pointer); err != nil {
+		t.Fatalf("decode latest pointer: %v", err)
+	}
+	if pointer.Version != "step-000002" || pointer.Url != "s3://bucket/ckpt/step-000002" ||
+		pointer.NumberOfFiles != 2 || pointer.SizeInBytes != 3 {
+		t.Errorf("pointer = %#v", pointer)
+	}
+
+	close(requestChan)
+	for request := range requestChan {
+		if !strings.HasPrefix(request.Checkpoint.Url, "s3://bucket/ckpt/step-") {
+			t.Errorf("round url = %s, want a versioned url", request.Checkpoint.Url)
+		}
+	}
+}
+
+func TestUploadCheckpointRound_VersionedContinuesFromRemoteVersions(t *testing.T) {
+	ctx := context.Background()
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	// The fake osmo lists the versions of an earlier attempt and logs every other command
+	logFile := filepath.Join(t.TempDir(), "commands")
+	body := fmt.Sprintf("#!/bin/sh\nif [ \"$2\" = list ]; then\n"+
+		"  printf 'latest\\nstep-000010/a.pt\\nstep-000002/a.pt\\nstep-000009/sub/b.pt\\n'\n"+
+		"  exit 0\nfi\necho \"$2 $3\" >> %s\n", logFile)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1"})
+	spec := checkpointSpec{url: "s3://bucket/ckpt", versioning: CheckpointVersioningStep, keep: 2}
+	retention := &checkpointRetention{versioning: spec.versioning, keep: spec.keep}
+	requestChan := make(chan messages.Request, 4)
+
+	result := uploadCheckpointRound(ctx, newCheckpointTracker(root, 0), spec, retention, false,
+		make(chan string, 64), requestChan)
+	if result.Url != "s3://bucket/ckpt/step-000011" {
+		t.Errorf("version url = %s, want s3://bucket/ckpt/step-000011", result.Url)
+	}
+
+	commands, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read command log: %v", err)
+	}
+	// The versions are rotated in step order, not in listing or lexical order
+	want := "upload s3://bucket/ckpt/step-000011\nupload s3://bucket/ckpt\n" +
+		"delete s3://bucket/ckpt/step-000002/\ndelete s3://bucket/ckpt/step-000009/\n"
+	if string(commands) != want {
+		t.Errorf("commands = %q, want %q", string(commands), want)
+	}
+	wantVersions := []string{"step-000010", "step-000011"}
+	if !slices.Equal(retention.versions, wantVersions) {
+		t.Errorf("retained versions = %v, want %v", retention.versions, wantVersions)
+	}
+}
+
+func TestUploadCheckpointRound_VersionedFailsWhenVersionsCannotBeListed(t *testing.T) {
+	ctx := context.Background()
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	logFile := filepath.Join(t.TempDir(), "commands")
+	body := fmt.Sprintf("#!/bin/sh\necho \"$2\" >> %s\n[ \"$2\" != list ]\n", logFile)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"a.pt": "1"})
+	spec := checkpointSpec{url: "s3://bucket/ckpt", versioning: CheckpointVersioningStep}
+	retention := &checkpointRetention{versioning: spec.versioning}
+
+	result := uploadCheckpointRound(ctx, newCheckpointTracker(root, 0), spec, retention, false,
+		make(chan string, 64), make(chan messages.Request, 4))
+	if result.Status != CheckpointFailed {
+		t.Errorf("status = %s, want %s", result.Status, CheckpointFailed)
+	}
+	if commands, _ := os.ReadFile(logFile); string(commands) != "list\n" {
+		t.Errorf("commands = %q, want only the listing", string(commands))
+	}
+}
+
+func TestUploadCheckpointRound_VersionedDefersWhileFilesAreWritten(t *testing.T) {
+	ctx := context.Background()
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+	logFile := filepath.Join(t.TempDir(), "commands")
+	t.Setenv("PATH", stageFakeOsmo(t, fmt.Sprintf("#!/bin/sh\necho \"$2\" >> %s\n", logFile))+
+		":"+os.Getenv("PATH"))
+
+	root := filepath.Join(t.TempDir(), "ckpt")
+	writeTree(t, root, map[string]string{"old.pt": "1", "writing.pt": "2"})
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(root, "old.pt"), old, old)
+
+	tracker := newCheckpointTracker(root, time.Minute)
+	spec := checkpointSpec{url: "s3://bucket/ckpt", versioning: CheckpointVersioningTimestamp}
+	retention := &checkpointRetention{versioning: spec.versioning}
+	requestChan := make(chan messages.Request, 4)
+
+	uploadCheckpointRound(ctx, tracker, spec, retention, false, make(chan string, 64), requestChan)
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) || len(requestChan) != 0 {
+		t.Fatalf("expected no upload while a file is still being written")
+	}
+
+	uploadCheckpointRound(ctx, tracker, spec, retention, true, make(chan string, 64), requestChan)
+	if len(requestChan) != 1 {
+		t.Fatalf("expected the final round to upload a version")
+	}
+	if round := <-requestChan; round.Checkpoint.NumberOfFiles != 2 {
+		t.Errorf("final version has %d files, want 2", round.Checkpoint.NumberOfFiles)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Unix socket in the run location that accepts on-demand checkpoint requests
+	CheckpointTriggerSocketName string = "checkpoint.sock"
+	// Directory in the run location where marker files trigger on-demand checkpoints
+	CheckpointTriggerDirName string = "checkpoint-trigger"
+	// The result of a marker triggered checkpoint is written to <marker><suffix>
+	CheckpointStatusSuffix string = ".status"
+)
+
+type CheckpointStatus string
+
+const (
+	// The changed files were uploaded
+	CheckpointUploaded CheckpointStatus = "uploaded"
+	// No file changed since the last round, or the checkpoint path does not exist yet
+	CheckpointUnchanged CheckpointStatus = "unchanged"
+	// A versioned round was postponed to the next round since files are still being written.
+	// Requested rounds skip the settle delay, so they are never deferred.
+	CheckpointDeferred CheckpointStatus = "deferred"
+	// Scanning, staging or uploading the checkpoint failed, the next round retries it
+	CheckpointFailed CheckpointStatus = "failed"
+)
+
+type CheckpointResult struct {
+	Name          string           `json:"name"`
+	Status        CheckpointStatus `json:"status"`
+	Url           string           `json:"url,omitempty"`
+	SizeInBytes   int64            `json:"size_in_bytes"`
+	NumberOfFiles int              `json:"number_of_files"`
+	Message       string           `json:"message,omitempty"`
+}
+
+// CheckpointTriggerRequest is sent by the user process over the checkpoint socket. An empty name
+// triggers all checkpoints.
+type CheckpointTriggerRequest struct {
+	Name string `json:"name"`
+}
+
+type CheckpointTriggerResponse struct {
+	Results []CheckpointResult `json:"results"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// CheckpointTrigger requests immediate rounds from a running Checkpoint. A checkpoint is named by
+// its index in the task spec or by the base name of its path.
+type CheckpointTrigger struct {
+	Name     string
+	index    int
+	requests chan chan CheckpointResult
+	done     chan struct{}
+}
+
+func NewCheckpointTrigger(index int, checkpointInfo string) *CheckpointTrigger {
+	spec, _ := parseCheckpointInfo(checkpointInfo)
+	return &CheckpointTrigger{
+		Name:     filepath.Base(filepath.Clean(spec.path)),
+		index:    index,
+		requests: make(chan chan CheckpointResult),
+		done:     make(chan struct{}),
+	}
+}
+
+// Request runs a checkpoint round immediately and waits for it to finish
+func (t *CheckpointTrigger) Request() CheckpointResult {
+	result := make(chan CheckpointResult, 1)
+	select {
+	case t.requests <- result:
+		checkpointResult := <-result
+		checkpointResult.Name = t.Name
+		return checkpointResult
+	case <-t.done:
+		return CheckpointResult{Name: t.Name, Status: CheckpointFailed,
+			Message: "checkpointing has already finished"}
+	}
+}
+
+func (t *CheckpointTrigger) matches(name string) bool {
+	return name == "" || name == t.Name || name == strconv.Itoa(t.index)
+}
+
+// requestCheckpoints triggers every checkpoint matching name, one after the other
+func requestCheckpoints(triggers []*CheckpointTrigger, name string) ([]CheckpointResult, error) {
+	var results []CheckpointResult
+	for _, trigger := range triggers {
+		if trigger.matches(name) {
+			results = append(results, trigger.Request())
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no checkpoint named %s", name)
+	}
+	return results, nil
+}
+
+// handleCheckpointMarker triggers the checkpoint named by a marker file and replaces the marker
+// with a status file holding the result.
+func handleCheckpointMarker(triggerDir string, name string, triggers []*CheckpointTrigger,
+	opsChan chan string) {
+
+	if strings.HasSuffix(name, CheckpointStatusSuffix) || strings.HasPrefix(name, ".") {
+		return
+	}
+	os.Remove(filepath.Join(triggerDir, name))
+	opsChan <- fmt.Sprintf("Checkpoint requested through marker %s", name)
+
+	var response CheckpointTriggerResponse
+	checkpointName := name
+	if name == "all" {
+		checkpointName = ""
+	}
+	results, err := requestCheckpoints(triggers, checkpointName)
+	if err != nil {
+		response.Error = err.Error()
+		opsChan <- fmt.Sprintf("Invalid checkpoint marker %s: %s", name, err)
+	}
+	response.Results = results
+
+	responseJson, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return
+	}
+	// Written to a hidden file first so that readers never see a partial status
+	statusPath := filepath.Join(triggerDir, name+CheckpointStatusSuffix)
+	tempPath := filepath.Join(triggerDir, "."+name+CheckpointStatusSuffix)
+	if err := os.WriteFile(tempPath, responseJson, 0666); err != nil {
+		opsChan <- fmt.Sprintf("Failed to write checkpoint status %s: %s", statusPath, err)
+		return
+	}
+	os.Rename(tempPath, statusPath)
+}
+
+func serveCheckpointConn(conn net.Conn, triggers []*CheckpointTrigger, opsChan chan string) {
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var request CheckpointTriggerRequest
+		if err := decoder.Decode(&request); err != nil {
+			return
+		}
+		opsChan <- fmt.Sprintf("Checkpoint requested through socket for %q", request.Name)
+
+		var response CheckpointTriggerResponse
+		results, err := requestCheckpoints(triggers, request.Name)
+		if err != nil {
+			response.Error = err.Error()
+		}
+		response.Results = results
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+	}
+}
+
+// ServeCheckpointTriggers accepts on-demand checkpoint requests from the user process, through
+// the checkpoint socket and through marker files in the trigger directory of the run location.
+func ServeCheckpointTriggers(ctx context.Context, runLocation string,
+	triggers []*CheckpointTrigger, opsChan chan string) {
+
+	if len(triggers) == 0 {
+		return
+	}
+
+	triggerDir := filepath.Join(runLocation, CheckpointTriggerDirName)
+	if err := os.MkdirAll(triggerDir, 0777); err != nil {
+		opsChan <- fmt.Sprintf("Failed to create checkpoint trigger directory: %s", err)
+	} else {
+		// The user command may run as a different user
+		os.Chmod(triggerDir, 0777)
+		go watchCheckpointMarkers(ctx, triggerDir, triggers, opsChan)
+	}
+
+	socketPath := filepath.Join(runLocation, CheckpointTriggerSocketName)
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		opsChan <- fmt.Sprintf("Failed to listen for checkpoint requests: %s", err)
+		return
+	}
+	os.Chmod(socketPath, 0666)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go serveCheckpointConn(conn, triggers, opsChan)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// watchCheckpointMarkers triggers a checkpoint whenever a marker file is written to or moved into
+// triggerDir. Markers that already exist when the watch starts are handled as well. The watch
+// stops once ctx is canceled.
+func watchCheckpointMarkers(ctx context.Context, triggerDir string, triggers []*CheckpointTrigger,
+	opsChan chan string) {
+
+	// Non-blocking, so that the read is handled by the runtime poller and closing the watcher
+	// unblocks it
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		opsChan <- fmt.Sprintf("Failed to watch checkpoint markers: %s", err)
+		return
+	}
+	watcher := os.NewFile(uintptr(fd), "inotify")
+	defer watcher.Close()
+
+	_, err = syscall.InotifyAddWatch(fd, triggerDir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO)
+	if err != nil {
+		opsChan <- fmt.Sprintf("Failed to watch checkpoint markers in %s: %s", triggerDir, err)
+		return
+	}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			watcher.Close()
+		case <-stopped:
+		}
+	}()
+
+	if entries, err := os.ReadDir(triggerDir); err == nil {
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				go handleCheckpointMarker(triggerDir, entry.Name(), triggers, opsChan)
+			}
+		}
+	}
+
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := watcher.Read(buffer)
+		if ctx.Err() != nil {
+			return
+		} else if err != nil || n <= 0 {
+			opsChan <- fmt.Sprintf("Stopped watching checkpoint markers: %v", err)
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			offset = nameEnd
+			if event.Len == 0 || event.Mask&syscall.IN_ISDIR != 0 {
+				continue
+			}
+			name := string(bytes.TrimRight(buffer[nameStart:nameEnd], "\x00"))
+			go handleCheckpointMarker(triggerDir, name, triggers, opsChan)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+)
+
+// startIdleCheckpoint runs a Checkpoint whose timer never fires, for a checkpoint path that does
+// not exist, so that triggered rounds finish without uploading.
+func startIdleCheckpoint(t *testing.T, index int, path string) *CheckpointTrigger {
+	t.Helper()
+	checkpointInfo := path + ";s3://bucket/ckpt;3600;"
+	trigger := NewCheckpointTrigger(index, checkpointInfo)
+	stop := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	opsChan := make(chan string, 64)
+	go func() {
+		for range opsChan {
+		}
+	}()
+	go Checkpoint(context.Background(), opsChan, make(chan messages.Request, 4), checkpointInfo,
+		trigger, 0, &wg, stop)
+	t.Cleanup(func() {
+		close(stop)
+		wg.Wait()
+	})
+	return trigger
+}
+
+// ---------------------------------------------------------------------------
+// CheckpointTrigger — naming, matching and requests after stop
+// ---------------------------------------------------------------------------
+
+func TestRequestCheckpoints_MatchesByNameIndexOrAll(t *testing.T) {
+	triggers := []*CheckpointTrigger{
+		startIdleCheckpoint(t, 0, "/missing/model"),
+		startIdleCheckpoint(t, 1, "/missing/optimizer/"),
+	}
+
+	cases := map[string]int{"model": 1, "optimizer": 1, "1": 1, "": 2}
+	for name, want := range cases {
+		results, err := requestCheckpoints(triggers, name)
+		if err != nil || len(results) != want {
+			t.Errorf("requestCheckpoints(%q) = %v, %v, want %d results", name, results, err, want)
+		}
+		for _, result := range results {
+			if result.Status != CheckpointUnchanged {
+				t.Errorf("status = %s, want %s", result.Status, CheckpointUnchanged)
+			}
+		}
+	}
+
+	if _, err := requestCheckpoints(triggers, "unknown"); err == nil {
+		t.Errorf("expected error for unknown checkpoint name")
+	}
+}
+
+func TestCheckpointTrigger_RequestAfterCheckpointFinishedFails(t *testing.T) {
+	trigger := NewCheckpointTrigger(0, "/missing/model;s3://bucket/ckpt;not-a-number;")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	stop := make(chan bool)
+	close(stop)
+	Checkpoint(context.Background(), make(chan string, 8), make(chan messages.Request, 1),
+		"/missing/model;s3://bucket/ckpt;not-a-number;", trigger, 0, &wg, stop)
+
+	result := trigger.Request()
+	if result.Status != CheckpointFailed || result.Name != "model" {
+		t.Errorf("result = %#v, want failed result for model", result)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ServeCheckpointTriggers — unix socket and marker file requests
+// ---------------------------------------------------------------------------
+
+func TestServeCheckpointTriggers_SocketAndMarkerReturnStatus(t *testing.T) {
+	runLocation := t.TempDir()
+	triggers := []*CheckpointTrigger{startIdleCheckpoint(t, 0, "/missing/model")}
+	opsChan := make(chan string, 64)
+	go func() {
+		for range opsChan {
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ServeCheckpointTriggers(ctx, runLocation, triggers, opsChan)
+
+	// Socket request
+	socketPath := filepath.Join(runLocation, CheckpointTriggerSocketName)
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("unix", socketPath); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("dial checkpoint socket: %v", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(CheckpointTriggerRequest{Name: "model"}); err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+	var response CheckpointTriggerResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Name != "model" ||
+		response.Results[0].Status != CheckpointUnchanged {
+		t.Errorf("socket response = %#v", response)
+	}
+
+	// Marker file request
+	markerPath := filepath.Join(runLocation, CheckpointTriggerDirName, "model")
+	if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+	statusPath := markerPath + CheckpointStatusSuffix
+	var statusJson []byte
+	for i := 0; i < 100; i++ {
+		if statusJson, err = os.ReadFile(statusPath); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("status file was not written: %v", err)
+	}
+	var status CheckpointTriggerResponse
+	if err := json.Unmarshal(statusJson, &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if len(status.Results) != 1 || status.Results[0].Status != CheckpointUnchanged {
+		t.Errorf("marker status = %#v", status)
+	}
+	if _, err := os.Stat(markerPath); !os.IsNotExist(err) {
+		t.Errorf("expected marker to be removed")
+	}
+}
+
+func TestWatchCheckpointMarkers_StopsWhenCanceled(t *testing.T) {
+	opsChan := make(chan string, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		watchCheckpointMarkers(ctx, t.TempDir(), nil, opsChan)
+		close(stopped)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch did not stop after cancel")
+	}
+}
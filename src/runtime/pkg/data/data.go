@@ -21,6 +21,7 @@ package data
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -30,9 +31,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -119,55 +122,53 @@ var WebsocketConnection WebsocketConnectionInfo
 
 func createOutCommandStream(osmoChan chan string) func(*exec.Cmd,
 	*bufio.Scanner, *sync.WaitGroup, chan bool) {
+	return createOutCommandStreamWithTail(osmoChan, nil)
+}
+
+func createOutCommandStreamWithTail(osmoChan chan string, tail *commandOutputTail) func(
+	*exec.Cmd, *bufio.Scanner, *sync.WaitGroup, chan bool) {
 	streamOutCommand := func(cmd *exec.Cmd, scanner *bufio.Scanner,
 		waitStreamLogs *sync.WaitGroup, timeoutChan chan bool) {
 		defer waitStreamLogs.Done()
 
-		lastMessageTime := time.Now()
-		quit := make(chan bool)
-
-		go func() {
-			for {
-				select {
-				case <-quit:
-					return
-				default:
-					if time.Since(lastMessageTime) >= DataTimeout {
-						if err := cmd.Process.Kill(); err != nil {
-							osmo_errors.SetExitCode(osmo_errors.CMD_FAILED_CODE)
-							panic(fmt.Sprintf("Failed to kill process: %s", err))
-						}
-						timeoutChan <- true
-						return
-					}
-					// Wait a second between checks
-					time.Sleep(time.Second)
-				}
+		// Kill the command once it has not printed anything for DataTimeout
+		var timedOut atomic.Bool
+		watchdog := time.AfterFunc(DataTimeout, func() {
+			timedOut.Store(true)
+			if err := cmd.Process.Kill(); err != nil {
+				log.Printf("Failed to kill process: %s", err)
 			}
-		}()
+		})
 
 		for scanner.Scan() {
+			watchdog.Reset(DataTimeout)
 			log.Println(scanner.Text())
 			osmoChan <- scanner.Text()
-			lastMessageTime = time.Now()
+			tail.add(scanner.Text())
 		}
-		if err := scanner.Err(); err != nil {
-			osmo_errors.SetExitCode(osmo_errors.CMD_FAILED_CODE)
-			panic(err)
+		watchdog.Stop()
+		if err := scanner.Err(); err != nil && !timedOut.Load() {
+			log.Printf("Error: %s", err)
+			osmoChan <- fmt.Sprintf("Error: %s", err)
 		}
 
-		quit <- true
-		timeoutChan <- false
+		timeoutChan <- timedOut.Load()
 	}
 	return streamOutCommand
 }
 
 func createErrCommandStream(osmoChan chan string) func(*bufio.Scanner, *sync.WaitGroup) {
+	return createErrCommandStreamWithTail(osmoChan, nil)
+}
+
+func createErrCommandStreamWithTail(osmoChan chan string,
+	tail *commandOutputTail) func(*bufio.Scanner, *sync.WaitGroup) {
 	streamErrCommand := func(scanner *bufio.Scanner, waitStreamLogs *sync.WaitGroup) {
 		defer waitStreamLogs.Done()
 		for scanner.Scan() {
 			log.Println(scanner.Text())
 			osmoChan <- scanner.Text()
+			tail.add(scanner.Text())
 		}
 		if err := scanner.Err(); err != nil {
 			log.Printf("Error: %s", err)
@@ -177,184 +178,185 @@ func createErrCommandStream(osmoChan chan string) func(*bufio.Scanner, *sync.Wai
 	return streamErrCommand
 }
 
-func RunOSMOCommandStreamingWithRetry(command []string, retryCommand []string,
-	retryCount int, osmoChan chan string, exitCode osmo_errors.ExitCode) {
-	for i := 0; i < retryCount; i++ {
-		var commandInput []string
-		if i > 0 {
-			osmoChan <- "OSMO Command timed out. Retrying..."
-			commandInput = retryCommand
-		} else {
-			commandInput = command
+// RetryPolicies are the retry policies of the data operations
+var RetryPolicies = common.DefaultRetryPolicies()
+
+// waitForServiceConnection blocks until the connection to the service is stable
+func waitForServiceConnection(ctx context.Context, policy common.RetryPolicy,
+	osmoChan chan string) error {
+	logged := false
+	for WebsocketConnection.IsBroken {
+		if !logged {
+			osmoChan <- "Failed to communicate with OSMO service. " +
+				"Waiting for service connection before retrying..."
+			logged = true
 		}
+		if err := common.SleepContext(ctx, policy.ServicePollInterval); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
 
-		var msg string
-		var err error
-		firstError := false
+// commandExitCode returns the exit code of a failed command, or -1 if it did not exit
+func commandExitCode(err error) int {
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		// This works on both Unix and Windows. Although package syscall is
+		// generally platform dependent, WaitStatus is defined for both Unix and Windows
+		// and in Windows it contains the exit code.
+		if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
 
-		// This retry count variable is for 429 has no limit
-		backoffCount := 0
-		for {
-			// Wait until we have a stable connection to the service
-			if WebsocketConnection.IsBroken {
-				time.Sleep(10 * time.Second)
-				continue
-			}
-			cmd := exec.Command(commandInput[0], commandInput[1:]...)
-			msg, err = common.RunCommand(cmd,
-				createOutCommandStream(osmoChan), createErrCommandStream(osmoChan))
-			if err != nil {
-				if exiterr, ok := err.(*exec.ExitError); ok {
-					// The program has exited with an exit code != 0
-
-					// This works on both Unix and Windows. Although package syscall is
-					// generally platform dependent, WaitStatus is defined for both Unix and Windows
-					// and in Windows it contains the exit code.
-					if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-						continueLoop := false
-						sleepTime := time.Second
-						// Exit code 10 is cannot connect to service
-						if status.ExitStatus() == 10 {
-							if !firstError {
-								osmoChan <- "Failed to communicate with OSMO service. " +
-									"Waiting for service connection before retrying..."
-								firstError = true
-							}
-							continueLoop = true
-						} else if status.ExitStatus() == 75 {
-							if !firstError || math.Mod(float64(backoffCount), 5) == 0 {
-								osmoChan <- "Rate limited by service. Waiting before retrying..."
-								firstError = true
-							}
-							sleepTime = ExponentialBackoffWithJitter(backoffCount)
-							backoffCount++
-							continueLoop = true
-						}
-						if continueLoop {
-							time.Sleep(sleepTime)
-							continue
-						}
-					}
-				}
-			}
-			break
+// logRetry reports a retry decision
+func logRetry(command string, retrier *common.Retrier, decision common.RetryDecision,
+	osmoChan chan string) {
+	if decision.Retry {
+		osmoChan <- fmt.Sprintf("%s failed: %s. Retrying in %s (attempt %d of %d)...",
+			command, decision.Reason, decision.Delay.Round(time.Millisecond),
+			retrier.Attempts(), retrier.Policy.MaxAttempts)
+	} else {
+		osmoChan <- fmt.Sprintf("%s failed: %s. Not retrying.", command, decision.Reason)
+	}
+}
+
+// commandOutputTail keeps the latest lines of a command output for matching retriable output
+type commandOutputTail struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+const maxOutputTailLines = 50
+
+func (t *commandOutputTail) add(line string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > maxOutputTailLines {
+		t.lines = t.lines[len(t.lines)-maxOutputTailLines:]
+	}
+}
+
+func (t *commandOutputTail) String() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return strings.Join(t.lines, "\n")
+}
+
+// RunOSMOCommandStreamingWithRetry runs the command, streaming its output, and retries it
+// with retryCommand under the policy. Once the retries run out or ctx is canceled, it returns
+// an error with exitCode.
+func RunOSMOCommandStreamingWithRetry(ctx context.Context, command []string,
+	retryCommand []string, policy common.RetryPolicy, osmoChan chan string,
+	exitCode osmo_errors.ExitCode) error {
+	retrier := common.NewRetrier(policy)
+	commandInput := command
+	for {
+		if err := waitForServiceConnection(ctx, policy, osmoChan); err != nil {
+			return osmo_errors.NewExitError(exitCode, err)
+		}
+
+		tail := &commandOutputTail{}
+		cmd := exec.CommandContext(ctx, commandInput[0], commandInput[1:]...)
+		msg, err := common.RunCommand(cmd, createOutCommandStreamWithTail(osmoChan, tail),
+			createErrCommandStreamWithTail(osmoChan, tail))
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return osmo_errors.NewExitError(exitCode, ctx.Err())
 		}
 		_, isTypeTimeout := err.(*osmo_errors.TimeoutError)
-		if isTypeTimeout {
+		decision := retrier.Next(commandExitCode(err), isTypeTimeout, tail.String())
+		logRetry("OSMO command", retrier, decision, osmoChan)
+		if decision.Retry {
+			if err := common.SleepContext(ctx, decision.Delay); err != nil {
+				return osmo_errors.NewExitError(exitCode, err)
+			}
+			commandInput = retryCommand
 			continue
 		}
-		if err != nil {
-			osmo_errors.LogError(msg, "", osmoChan, err, osmo_errors.CMD_FAILED_CODE)
-		} else {
-			return
+		if !decision.Exhausted {
+			return osmo_errors.CommandError(msg, "", osmoChan, err, osmo_errors.CMD_FAILED_CODE)
 		}
+		osmoChan <- fmt.Sprintf("Failed after %d retries", retrier.Attempts()-1)
+		return osmo_errors.Errorf(exitCode, "Failed after %d retries: %s",
+			retrier.Attempts()-1, decision.Reason)
 	}
-	osmoChan <- fmt.Sprintf("Failed after %d retries", retryCount)
-	osmo_errors.SetExitCode(exitCode)
-	panic(fmt.Sprintf("Failed after %d retries", retryCount))
 }
 
-func RunOSMOCommandWithRetry(commandArgs []string, retryCount int,
-	osmoChan chan string, code osmo_errors.ExitCode) bytes.Buffer {
+// RunOSMOCommandWithRetry runs the command and returns its stdout, retrying it under the
+// policy. Once the retries run out or ctx is canceled, it returns an error with code.
+func RunOSMOCommandWithRetry(ctx context.Context, commandArgs []string,
+	policy common.RetryPolicy, osmoChan chan string,
+	code osmo_errors.ExitCode) (bytes.Buffer, error) {
 	var outb, errb bytes.Buffer
-	var err error
-	for i := 0; i < retryCount; i++ {
-		if i > 0 {
-			osmoChan <- "Retrying..."
+	retrier := common.NewRetrier(policy)
+	for {
+		if err := waitForServiceConnection(ctx, policy, osmoChan); err != nil {
+			return outb, osmo_errors.NewExitError(code, err)
 		}
-		firstError := false
-
-		// This retry count variable is for 429 has no limit
-		backoffCount := 0
-		for {
-			// Wait until we have a stable connection to the service
-			if WebsocketConnection.IsBroken {
-				if !firstError {
-					osmoChan <- "Failed to communicate with OSMO service. " +
-						"Waiting for service connection before retrying..."
-					firstError = true
-				}
-				time.Sleep(10 * time.Second)
-				continue
-			}
-			cmd := exec.Command(commandArgs[0], commandArgs[1:]...)
-			cmd.Stdout = &outb
-			cmd.Stderr = &errb
-			if err = cmd.Run(); err != nil {
-				if exiterr, ok := err.(*exec.ExitError); ok {
-					// The program has exited with an exit code != 0
-
-					// This works on both Unix and Windows. Although package syscall is
-					// generally platform dependent, WaitStatus is defined for both Unix and Windows
-					// and in Windows it contains the exit code.
-					if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-						continueLoop := false
-						sleepTime := time.Second
-						// Exit code 10 is cannot connect to service
-						if status.ExitStatus() == 10 {
-							if !firstError {
-								osmoChan <- "Failed to communicate with OSMO service. " +
-									"Waiting for service connection before retrying..."
-								firstError = true
-							}
-							continueLoop = true
-						} else if status.ExitStatus() == 75 {
-							if !firstError || math.Mod(float64(backoffCount), 5) == 0 {
-								osmoChan <- "Rate limited by service. Waiting before retrying..."
-								firstError = true
-							}
-							sleepTime = ExponentialBackoffWithJitter(backoffCount)
-							backoffCount++
-							continueLoop = true
-						}
-						if continueLoop {
-							outb.Reset()
-							errb.Reset()
-							time.Sleep(sleepTime)
-							continue
-						}
-					}
-					break
-				}
-			}
-			break
+
+		outb.Reset()
+		errb.Reset()
+		cmd := exec.CommandContext(ctx, commandArgs[0], commandArgs[1:]...)
+		cmd.Stdout = &outb
+		cmd.Stderr = &errb
+		err := cmd.Run()
+		if err == nil {
+			return outb, nil
 		}
-		if err != nil {
+		if ctx.Err() != nil {
+			return outb, osmo_errors.NewExitError(code, ctx.Err())
+		}
+
+		exitCode := commandExitCode(err)
+		decision := retrier.Next(exitCode, false, errb.String()+outb.String())
+		if !slices.Contains(policy.WaitExitCodes, exitCode) {
 			log.Println("out:", outb.String())
 			log.Println("err:", errb.String())
 			osmoChan <- outb.String()
 			osmoChan <- errb.String()
+		}
+		logRetry("OSMO command", retrier, decision, osmoChan)
+		if decision.Retry {
+			if err := common.SleepContext(ctx, decision.Delay); err != nil {
+				return outb, osmo_errors.NewExitError(code, err)
+			}
 			continue
 		}
-
-		return outb
+		osmoChan <- fmt.Sprintf("Failed after %d retries", retrier.Attempts()-1)
+		return outb, osmo_errors.CommandError(outb.String(), errb.String(), osmoChan, err, code)
 	}
-	osmoChan <- fmt.Sprintf("Failed after %d retries", retryCount)
-	osmo_errors.LogError(outb.String(), errb.String(), osmoChan, err, code)
-	return outb
 }
 
-func CreateFolder(inputPath string, folder string) string {
+func CreateFolder(inputPath string, folder string) (string, error) {
 	if !strings.HasSuffix(inputPath, "/") {
 		inputPath += "/"
 	}
 	mountPath := inputPath + folder
 	if err := os.MkdirAll(mountPath, os.ModePerm); err != nil {
-		osmo_errors.SetExitCode(osmo_errors.FILE_FAILED_CODE)
-		panic(err)
+		return "", osmo_errors.NewExitError(osmo_errors.FILE_FAILED_CODE, err)
 	}
 	log.Printf("Created directory: %s", mountPath)
-	return mountPath
+	return mountPath, nil
 }
 
 func DownloadURI(
+	ctx context.Context,
 	c net.Conn,
 	uri string,
 	folderLoc string,
 	regex string,
 	osmoChan chan string,
 	benchmarkFolderName string,
-) []BenchmarkMetrics {
+) ([]BenchmarkMetrics, error) {
 	if benchmarkFolderName == "" {
 		benchmarkFolderName = fmt.Sprintf("download_%d", time.Now().UnixMilli())
 	}
@@ -367,22 +369,41 @@ func DownloadURI(
 	if regex != "" {
 		downloadInput = append(downloadInput, "--regex", regex)
 	}
+	downloadInput = append(downloadInput, progressArgs(benchmarkFolderName)...)
 
 	downloadResumeInput := append(downloadInput, "--resume")
 
-	RunOSMOCommandStreamingWithRetry(
-		downloadInput, downloadResumeInput, 5, osmoChan, osmo_errors.DOWNLOAD_FAILED_CODE)
+	err := RunOSMOCommandStreamingWithRetry(ctx, downloadInput, downloadResumeInput,
+		RetryPolicies[common.DownloadOperation], osmoChan, osmo_errors.DOWNLOAD_FAILED_CODE)
+	if err != nil {
+		return nil, err
+	}
 
-	return CollectBenchmarkMetrics(benchmarkPath)
+	return CollectBenchmarkMetrics(benchmarkPath), nil
 }
 
 func UploadData(
+	ctx context.Context,
+	uri string,
+	path string,
+	regex string,
+	osmoChan chan string,
+	benchmarkFolderName string,
+) ([]BenchmarkMetrics, error) {
+	return uploadData(ctx, uri, path, regex, osmoChan, benchmarkFolderName,
+		common.UploadOperation)
+}
+
+// uploadData uploads under the retry policy of the operation
+func uploadData(
+	ctx context.Context,
 	uri string,
 	path string,
 	regex string,
 	osmoChan chan string,
 	benchmarkFolderName string,
-) []BenchmarkMetrics {
+	operation common.OperationClass,
+) ([]BenchmarkMetrics, error) {
 	if benchmarkFolderName == "" {
 		benchmarkFolderName = fmt.Sprintf("upload_%d", time.Now().UnixMilli())
 	}
@@ -395,14 +416,18 @@ func UploadData(
 	if regex != "" {
 		uploadInput = append(uploadInput, "--regex", regex)
 	}
+	uploadInput = append(uploadInput, progressArgs(benchmarkFolderName)...)
 
-	RunOSMOCommandStreamingWithRetry(uploadInput, uploadInput, 5, osmoChan,
-		osmo_errors.UPLOAD_FAILED_CODE)
+	err := RunOSMOCommandStreamingWithRetry(ctx, uploadInput, uploadInput,
+		RetryPolicies[operation], osmoChan, osmo_errors.UPLOAD_FAILED_CODE)
+	if err != nil {
+		return nil, err
+	}
 
-	return CollectBenchmarkMetrics(benchmarkPath)
+	return CollectBenchmarkMetrics(benchmarkPath), nil
 }
 
-func PrintDirContents(c net.Conn, path string, maxLevel int, osmoChan chan string) {
+func PrintDirContents(c net.Conn, path string, maxLevel int, osmoChan chan string) error {
 	// Set the lines output for tree
 	// Get the first 20 lines and the last line if the number of lines is over 20
 	treePath := common.ResolveCommandPath("TREE_PATH", "tree", "/usr/bin/tree")
@@ -411,7 +436,7 @@ func PrintDirContents(c net.Conn, path string, maxLevel int, osmoChan chan strin
 	cmd.Stdout = &outb
 	cmd.Stderr = &errb
 	if err := cmd.Run(); err != nil {
-		osmo_errors.LogError(outb.String(), errb.String(), osmoChan, err,
+		return osmo_errors.CommandError(outb.String(), errb.String(), osmoChan, err,
 			osmo_errors.MISC_FAILED_CODE)
 	}
 
@@ -421,7 +446,7 @@ func PrintDirContents(c net.Conn, path string, maxLevel int, osmoChan chan strin
 	lines := strings.Split(output, "\n")
 	if len(lines) <= 20 {
 		osmoChan <- output
-		return
+		return nil
 	}
 
 	var builder strings.Builder
@@ -432,6 +457,7 @@ func PrintDirContents(c net.Conn, path string, maxLevel int, osmoChan chan strin
 	// Append the last line
 	builder.WriteString(lines[len(lines)-1])
 	osmoChan <- builder.String()
+	return nil
 }
 
 func CollectBenchmarkMetrics(benchmarkPath string) []BenchmarkMetrics {
@@ -463,45 +489,3 @@ func CollectBenchmarkMetrics(benchmarkPath string) []BenchmarkMetrics {
 
 	return benchmarkMetrics
 }
-
-func Checkpoint(opsChan chan string, checkpointInfo string,
-	waitCheckpoint *sync.WaitGroup, stopCheckpoint *bool) {
-
-	defer waitCheckpoint.Done()
-	checkpointSplit := strings.SplitN(checkpointInfo, ";", 4)
-	path := checkpointSplit[0]
-	url := checkpointSplit[1]
-	frequency := checkpointSplit[2]
-	regex := checkpointSplit[3]
-
-	frequencyInt, err := strconv.Atoi(frequency)
-	if err != nil {
-		opsChan <- fmt.Sprintf("Invalid checkpoint frequency: %s", frequency)
-		return
-	}
-	duration := time.Duration(frequencyInt) * time.Second
-
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
-	// Sleep until the duration has passed or stop is requested
-	timer := time.NewTimer(duration)
-	for {
-		select {
-		case <-timer.C:
-			// Upload the data
-			opsChan <- fmt.Sprintf("Checkpointing data from %s to %s...", path, url)
-			UploadData(url, path, regex, opsChan, "")
-			timer = time.NewTimer(duration)
-		case <-ticker.C:
-			if *stopCheckpoint {
-				timer.Stop()
-				opsChan <- fmt.Sprintf("Checkpointing data from %s to %s...", path, url)
-				UploadData(url, path, regex, opsChan, "")
-				opsChan <- fmt.Sprintf("Checkpointing data from %s to %s finished", path,
-					url)
-				return
-			}
-		}
-	}
-}
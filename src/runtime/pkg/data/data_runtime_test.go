@@ -20,7 +20,9 @@ package data
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -29,6 +31,10 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/messages"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 )
 
 // ---------------------------------------------------------------------------
@@ -170,7 +176,10 @@ func TestExponentialBackoffWithJitter_NegativeReturnsZero(t *testing.T) {
 func TestCreateFolder_CreatesNestedDirectoryAndReturnsFullPath(t *testing.T) {
 	root := t.TempDir()
 
-	got := CreateFolder(root, "child")
+	got, err := CreateFolder(root, "child")
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
 
 	want := root + "/child"
 	if got != want {
@@ -192,7 +201,10 @@ func TestCreateFolder_NoOpWhenAlreadyExists(t *testing.T) {
 		t.Fatalf("setup MkdirAll failed: %v", err)
 	}
 
-	got := CreateFolder(root+"/", "subfolder")
+	got, err := CreateFolder(root+"/", "subfolder")
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
 
 	if got != root+"/subfolder" {
 		t.Errorf("CreateFolder returned %q, want %q", got, root+"/subfolder")
@@ -309,7 +321,9 @@ func TestPrintDirContents_EmitsTreeOutputBelowTwentyLines(t *testing.T) {
 	}
 
 	osmoChan := make(chan string, 4)
-	PrintDirContents(nil, root, 1, osmoChan)
+	if err := PrintDirContents(nil, root, 1, osmoChan); err != nil {
+		t.Fatalf("PrintDirContents: %v", err)
+	}
 	close(osmoChan)
 
 	var collected []string
@@ -338,7 +352,9 @@ func TestPrintDirContents_TruncatesTreeOutputAboveTwentyLines(t *testing.T) {
 	}
 
 	osmoChan := make(chan string, 4)
-	PrintDirContents(nil, root, 1, osmoChan)
+	if err := PrintDirContents(nil, root, 1, osmoChan); err != nil {
+		t.Fatalf("PrintDirContents: %v", err)
+	}
 	close(osmoChan)
 
 	var collected []string
@@ -358,29 +374,41 @@ func TestPrintDirContents_TruncatesTreeOutputAboveTwentyLines(t *testing.T) {
 // RunOSMOCommandWithRetry — success path, non-retriable failure path
 // ---------------------------------------------------------------------------
 
+// singleAttemptPolicy retries every failure once like access checks, without further attempts
+func singleAttemptPolicy() common.RetryPolicy {
+	policy := common.DefaultRetryPolicies()[common.AuthCheckOperation]
+	policy.MaxAttempts = 1
+	return policy
+}
+
 func TestRunOSMOCommandWithRetry_ReturnsStdoutOnSuccess(t *testing.T) {
 	WebsocketConnection = WebsocketConnectionInfo{}
 	osmoChan := make(chan string, 16)
 
-	outb := RunOSMOCommandWithRetry([]string{"sh", "-c", "printf hello"},
-		1, osmoChan, 0)
+	outb, err := RunOSMOCommandWithRetry(context.Background(), []string{"sh", "-c", "printf hello"},
+		singleAttemptPolicy(), osmoChan, 0)
+	if err != nil {
+		t.Fatalf("RunOSMOCommandWithRetry: %v", err)
+	}
 
 	if outb.String() != "hello" {
 		t.Errorf("stdout = %q, want %q", outb.String(), "hello")
 	}
 }
 
-func TestRunOSMOCommandWithRetry_PanicsAfterRetriesOnNonRetriableExit(t *testing.T) {
+func TestRunOSMOCommandWithRetry_ReturnsErrorAfterRetriesOnNonRetriableExit(t *testing.T) {
 	WebsocketConnection = WebsocketConnectionInfo{}
 	osmoChan := make(chan string, 64)
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatalf("expected panic after retry exhaustion")
-		}
-	}()
+	_, err := RunOSMOCommandWithRetry(context.Background(), []string{"sh", "-c", "exit 1"},
+		singleAttemptPolicy(), osmoChan, osmo_errors.DOWNLOAD_FAILED_CODE)
 
-	RunOSMOCommandWithRetry([]string{"sh", "-c", "exit 1"}, 1, osmoChan, 0)
+	if err == nil {
+		t.Fatalf("expected an error after retry exhaustion")
+	}
+	if code := osmo_errors.CodeOf(err); code != osmo_errors.DOWNLOAD_FAILED_CODE {
+		t.Errorf("exit code = %d, want %d", code, osmo_errors.DOWNLOAD_FAILED_CODE)
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -394,11 +422,14 @@ func TestRunOSMOCommandStreamingWithRetry_SucceedsOnFirstAttempt(t *testing.T) {
 	WebsocketConnection = WebsocketConnectionInfo{}
 	osmoChan := make(chan string, 64)
 
-	RunOSMOCommandStreamingWithRetry(
+	err := RunOSMOCommandStreamingWithRetry(context.Background(),
 		[]string{"sh", "-c", "echo streaming-ok"},
 		[]string{"sh", "-c", "echo streaming-ok"},
-		2, osmoChan, 0,
+		common.DefaultRetryPolicy(), osmoChan, 0,
 	)
+	if err != nil {
+		t.Fatalf("RunOSMOCommandStreamingWithRetry: %v", err)
+	}
 	close(osmoChan)
 
 	var collected []string
@@ -413,11 +444,11 @@ func TestRunOSMOCommandStreamingWithRetry_SucceedsOnFirstAttempt(t *testing.T) {
 
 func TestCheckpoint_InvalidFrequencyReturnsImmediately(t *testing.T) {
 	osmoChan := make(chan string, 8)
-	stop := false
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	Checkpoint(osmoChan, "/data;s3://bucket/url;not-a-number;*.bin", &wg, &stop)
+	Checkpoint(context.Background(), osmoChan, make(chan messages.Request, 1),
+		"/data;s3://bucket/url;not-a-number;*.bin", nil, 0, &wg, make(chan bool))
 
 	close(osmoChan)
 	var collected []string
@@ -564,7 +595,11 @@ func TestRunOSMOCommandWithRetry_RetriesOnExitCode10ThenSucceeds(t *testing.T) {
 		t.Fatalf("write script: %v", err)
 	}
 
-	outb := RunOSMOCommandWithRetry([]string{scriptPath}, 1, osmoChan, 0)
+	outb, err := RunOSMOCommandWithRetry(context.Background(), []string{scriptPath},
+		singleAttemptPolicy(), osmoChan, 0)
+	if err != nil {
+		t.Fatalf("RunOSMOCommandWithRetry: %v", err)
+	}
 
 	if outb.String() != "hello" {
 		t.Errorf("stdout = %q, want %q", outb.String(), "hello")
@@ -585,19 +620,102 @@ func TestRunOSMOCommandWithRetry_RetriesOnExitCode75ThenSucceeds(t *testing.T) {
 		t.Fatalf("write script: %v", err)
 	}
 
-	outb := RunOSMOCommandWithRetry([]string{scriptPath}, 1, osmoChan, 0)
+	outb, err := RunOSMOCommandWithRetry(context.Background(), []string{scriptPath},
+		singleAttemptPolicy(), osmoChan, 0)
+	if err != nil {
+		t.Fatalf("RunOSMOCommandWithRetry: %v", err)
+	}
 
 	if outb.String() != "rate_ok" {
 		t.Errorf("stdout = %q, want %q", outb.String(), "rate_ok")
 	}
 }
 
+func TestRunOSMOCommandStreamingWithRetry_RetriesOnMatchingOutput(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	osmoChan := make(chan string, 64)
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	script := fmt.Sprintf(
+		"if [ -e %s ]; then echo resumed; exit 0; fi\ntouch %s\n"+
+			"echo 'connection reset by peer' >&2\nexit 1\n", marker, marker)
+	policy := common.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.RetriableOutput = []string{"connection reset"}
+
+	err := RunOSMOCommandStreamingWithRetry(context.Background(), []string{"sh", "-c", script},
+		[]string{"sh", "-c", script}, policy, osmoChan, 0)
+	if err != nil {
+		t.Fatalf("RunOSMOCommandStreamingWithRetry: %v", err)
+	}
+	close(osmoChan)
+
+	var collected []string
+	for msg := range osmoChan {
+		collected = append(collected, msg)
+	}
+	joined := strings.Join(collected, "\n")
+	if !strings.Contains(joined, "output matched connection reset. Retrying") ||
+		!strings.Contains(joined, "resumed") {
+		t.Errorf("expected a logged retry and the resumed output, got: %v", collected)
+	}
+}
+
+func TestRunOSMOCommandStreamingWithRetry_ReturnsErrorOnNonRetriableExit(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	osmoChan := make(chan string, 64)
+
+	err := RunOSMOCommandStreamingWithRetry(context.Background(), []string{"sh", "-c", "exit 3"},
+		[]string{"sh", "-c", "exit 3"}, common.DefaultRetryPolicy(), osmoChan, 0)
+
+	if err == nil {
+		t.Fatalf("expected an error on a non-retriable failure")
+	}
+	if !strings.Contains(strings.Join(drainChannel(osmoChan), "\n"), "not retriable") {
+		t.Errorf("expected the retry decision to be logged")
+	}
+}
+
+func TestRunOSMOCommandStreamingWithRetry_StopsWhenContextIsCanceled(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	osmoChan := make(chan string, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := RunOSMOCommandStreamingWithRetry(ctx, []string{"sleep", "30"}, []string{"sleep", "30"},
+		common.DefaultRetryPolicy(), osmoChan, osmo_errors.DOWNLOAD_FAILED_CODE)
+
+	if err == nil {
+		t.Fatalf("expected an error when the context is canceled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want it to wrap context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("command kept running for %v after the context was canceled", elapsed)
+	}
+}
+
+func drainChannel(osmoChan chan string) []string {
+	var collected []string
+	for {
+		select {
+		case msg := <-osmoChan:
+			collected = append(collected, msg)
+		default:
+			return collected
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
-// CreateFolder — panics when MkdirAll cannot create the directory because the
-// parent is a file.
+// CreateFolder — returns an error when MkdirAll cannot create the directory
+// because the parent is a file.
 // ---------------------------------------------------------------------------
 
-func TestCreateFolder_PanicsWhenParentIsAFile(t *testing.T) {
+func TestCreateFolder_ReturnsErrorWhenParentIsAFile(t *testing.T) {
 	dir := t.TempDir()
 	// Write a regular file at the location we'll try to MkdirAll under.
 	parent := filepath.Join(dir, "i_am_a_file")
@@ -605,11 +723,12 @@ func TestCreateFolder_PanicsWhenParentIsAFile(t *testing.T) {
 		t.Fatalf("write parent file: %v", err)
 	}
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatalf("expected CreateFolder to panic when parent is a file")
-		}
-	}()
+	_, err := CreateFolder(parent, "child")
 
-	_ = CreateFolder(parent, "child")
+	if err == nil {
+		t.Fatalf("expected CreateFolder to fail when parent is a file")
+	}
+	if code := osmo_errors.CodeOf(err); code != osmo_errors.FILE_FAILED_CODE {
+		t.Errorf("exit code = %d, want %d", code, osmo_errors.FILE_FAILED_CODE)
+	}
 }
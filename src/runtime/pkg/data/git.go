@@ -0,0 +1,242 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+const (
+	GitOperation string = "Git"
+)
+
+// Define "git" input
+type GitInput struct {
+	// git:<folder>,<repo>,<ref>
+	Folder string
+	Url    string
+	// Branch, tag or commit. The default branch of the repository is used when empty.
+	Ref         string
+	Submodules  bool
+	SparsePaths []string
+}
+
+func (f GitInput) GetLogInfo() string {
+	if f.Ref == "" {
+		return f.Url
+	}
+	return f.Url + "@" + f.Ref
+}
+func (f GitInput) GetUrlIdentifier() string { return f.Url }
+func (f GitInput) GetFolder() string        { return f.Folder }
+func (f GitInput) Download(ctx context.Context, c net.Conn, inputPath string,
+	osmoChan chan string, metricChan chan metrics.Metric,
+	retryId string, groupName string, taskName string, inputIndex int) error {
+
+	repoPath, err := CreateFolder(inputPath, f.Folder)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+	commit, err := f.clone(ctx, repoPath, osmoChan)
+	if err != nil {
+		return err
+	}
+	endTime := time.Now()
+
+	sizeInBytes, numberOfFiles := checkoutSize(repoPath)
+	metricChan <- metrics.TaskIOMetrics{
+		RetryId:       retryId,
+		GroupName:     groupName,
+		TaskName:      taskName,
+		URL:           f.Url + "@" + commit,
+		Type:          "INPUT",
+		StartTime:     startTime.Format("2006-01-02 15:04:05.000"),
+		EndTime:       endTime.Format("2006-01-02 15:04:05.000"),
+		SizeInBytes:   sizeInBytes,
+		NumberOfFiles: numberOfFiles,
+		OperationType: GitOperation,
+		DownloadType:  Download,
+	}
+
+	log.Printf("Cloned %s at commit %s to %s", f.GetLogInfo(), commit, repoPath)
+	osmoChan <- fmt.Sprintf("Cloned %s at commit %s to {{input:%s}}",
+		f.GetLogInfo(), commit, f.Folder)
+	return PrintDirContents(c, repoPath, 1, osmoChan)
+}
+
+// clone shallow clones the ref into repoPath and returns the SHA of the checked out commit.
+// Fetching the ref directly instead of using `git clone --branch` also supports commits.
+func (f GitInput) clone(ctx context.Context, repoPath string,
+	osmoChan chan string) (string, error) {
+	gitPath := common.ResolveCommandPath("GIT_PATH", "git", "/usr/bin/git")
+	git := func(args ...string) []string {
+		return append([]string{gitPath, "-C", repoPath}, args...)
+	}
+	ref := f.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// Only the commands that talk to the remote are retried
+	local := common.RetryPolicy{MaxAttempts: 1}
+	remote := gitRetryPolicy()
+	run := func(policy common.RetryPolicy, args ...string) error {
+		return runGitCommandWithRetry(ctx, git(args...), policy, osmoChan)
+	}
+
+	if err := run(local, "init", "--quiet"); err != nil {
+		return "", err
+	}
+	if err := run(local, "config", "remote.origin.url", f.Url); err != nil {
+		return "", err
+	}
+	if len(f.SparsePaths) > 0 {
+		osmoChan <- "Limiting checkout to " + strings.Join(f.SparsePaths, ", ")
+		sparseArgs := append([]string{"sparse-checkout", "set", "--cone"}, f.SparsePaths...)
+		if err := run(local, sparseArgs...); err != nil {
+			return "", err
+		}
+	}
+	if err := run(remote, "fetch", "--depth", "1", "--no-tags", "origin", ref); err != nil {
+		return "", err
+	}
+	if err := run(local, "checkout", "--quiet", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	if f.Submodules {
+		err := run(remote, "submodule", "update", "--init", "--recursive", "--depth", "1")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var outb, errb bytes.Buffer
+	cmd := exec.CommandContext(ctx, gitPath, "-C", repoPath, "rev-parse", "HEAD")
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		return "", osmo_errors.CommandError(outb.String(), errb.String(), osmoChan, err,
+			osmo_errors.DOWNLOAD_FAILED_CODE)
+	}
+	return strings.TrimSpace(outb.String()), nil
+}
+
+// createGitOutCommandStream forwards the output of a git command. Unlike osmo data commands, git
+// reports its progress on stderr, so a quiet stdout does not mean the command is stuck.
+func createGitOutCommandStream(osmoChan chan string) func(*exec.Cmd,
+	*bufio.Scanner, *sync.WaitGroup, chan bool) {
+	streamOutCommand := func(cmd *exec.Cmd, scanner *bufio.Scanner,
+		waitStreamLogs *sync.WaitGroup, timeoutChan chan bool) {
+		defer waitStreamLogs.Done()
+		for scanner.Scan() {
+			log.Println(scanner.Text())
+			osmoChan <- scanner.Text()
+		}
+		timeoutChan <- false
+	}
+	return streamOutCommand
+}
+
+// gitRetryPolicy returns the download retry policy for git commands that talk to the remote.
+// Git exits with 128 on any failure and never with the wait exit codes of osmo commands, so
+// every failure is retried.
+func gitRetryPolicy() common.RetryPolicy {
+	policy := RetryPolicies[common.DownloadOperation]
+	policy.RetryAllFailures = true
+	policy.WaitExitCodes = nil
+	return policy
+}
+
+// runGitCommandWithRetry runs a git command and retries its failures under the retry policy
+func runGitCommandWithRetry(ctx context.Context, command []string, policy common.RetryPolicy,
+	osmoChan chan string) error {
+	retrier := common.NewRetrier(policy)
+	for {
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		// Fail instead of waiting for credentials that will never be typed
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		msg, err := common.RunCommand(cmd,
+			createGitOutCommandStream(osmoChan), createErrCommandStream(osmoChan))
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return osmo_errors.NewExitError(osmo_errors.DOWNLOAD_FAILED_CODE, ctx.Err())
+		}
+		decision := retrier.Next(commandExitCode(err), false, msg)
+		if policy.MaxAttempts > 1 {
+			logRetry("Git command", retrier, decision, osmoChan)
+		}
+		if decision.Retry {
+			if err := common.SleepContext(ctx, decision.Delay); err != nil {
+				return osmo_errors.NewExitError(osmo_errors.DOWNLOAD_FAILED_CODE, err)
+			}
+			continue
+		}
+		if retrier.Attempts() > 1 {
+			osmoChan <- fmt.Sprintf("Failed after %d retries", retrier.Attempts()-1)
+		}
+		return osmo_errors.CommandError(msg, "", osmoChan, err,
+			osmo_errors.DOWNLOAD_FAILED_CODE)
+	}
+}
+
+// checkoutSize returns the size and number of the checked out files, ignoring git metadata
+func checkoutSize(repoPath string) (int64, int) {
+	var sizeInBytes int64
+	numberOfFiles := 0
+	filepath.WalkDir(repoPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Name() == ".git" {
+			// A directory for the repository, a file for its submodules
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			sizeInBytes += info.Size()
+			numberOfFiles++
+		}
+		return nil
+	})
+	return sizeInBytes, numberOfFiles
+}
@@ -0,0 +1,221 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+)
+
+// ---------------------------------------------------------------------------
+// Parsing
+// ---------------------------------------------------------------------------
+
+func TestParseInput_LegacyGitSpec(t *testing.T) {
+	want := GitInput{Folder: "src", Url: "https://github.com/org/repo.git", Ref: "v1.2"}
+	got, problems := ParseInput("git:src,https://github.com/org/repo.git,v1.2")
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseInput mismatch:\n got:  %#v\n want: %#v", got, want)
+	}
+	if got := ParseInputOutput("git:src,https://github.com/org/repo.git,v1.2"); !reflect.DeepEqual(
+		got, want) {
+		t.Fatalf("ParseInputOutput mismatch:\n got:  %#v\n want: %#v", got, want)
+	}
+
+	// An empty ref checks out the default branch
+	got, problems = ParseInput("git:src,git@github.com:org/repo.git,")
+	if len(problems) > 0 || got.(GitInput).Ref != "" {
+		t.Fatalf("got %#v with problems %v, want an empty ref", got, problems)
+	}
+}
+
+func TestParseInput_JsonGitSpecWithOptions(t *testing.T) {
+	got, problems := ParseInput(`{"version":1,"type":"git","folder":"src",` +
+		`"url":"https://github.com/org/repo.git","ref":"main","submodules":true,` +
+		`"sparse_paths":["lib","docs/api"]}`)
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	want := GitInput{Folder: "src", Url: "https://github.com/org/repo.git", Ref: "main",
+		Submodules: true, SparsePaths: []string{"lib", "docs/api"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseInput mismatch:\n got:  %#v\n want: %#v", got, want)
+	}
+}
+
+func TestParseInput_GitOptionsAreValidated(t *testing.T) {
+	_, problems := ParseInput(`{"version":1,"type":"git","folder":"src","url":"repo",` +
+		`"regex":".*","sparse_paths":["/etc"]}`)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	_, problems = ParseInput(`{"version":1,"type":"url","folder":"0","url":"s3://b/d",` +
+		`"ref":"main","submodules":true}`)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	_, problems = ParseOutput(`{"version":1,"type":"url","url":"s3://b/d","sparse_paths":["a"]}`)
+	if len(problems) != 1 || !strings.Contains(problems[0], "sparse_paths") {
+		t.Fatalf("expected a sparse_paths problem, got %v", problems)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Download
+// ---------------------------------------------------------------------------
+
+// stageGitRepo creates a repository with a tagged first commit and a second commit on top of it
+// and returns its file:// url and the SHA of the tagged commit.
+func stageGitRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet", "--initial-branch", "main")
+	writeTree(t, repo, map[string]string{"src/main.go": "v1", "docs/index.md": "docs"})
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "first")
+	git("tag", "v1")
+	tagged := git("rev-parse", "HEAD")
+	writeTree(t, repo, map[string]string{"src/main.go": "v2"})
+	git("commit", "--quiet", "-am", "second")
+	return "file://" + repo, tagged
+}
+
+func TestGitInput_Download_ChecksOutRefAndReportsCommit(t *testing.T) {
+	url, tagged := stageGitRepo(t)
+	t.Setenv("PATH", stageNoOpOsmo(t)+":"+os.Getenv("PATH"))
+
+	inputPath := t.TempDir() + "/"
+	osmoChan := make(chan string, 64)
+	metricChan := make(chan metrics.Metric, 8)
+	input := GitInput{Folder: "repo", Url: url, Ref: "v1"}
+	if err := input.Download(context.Background(), nil, inputPath, osmoChan, metricChan, "0",
+		"grp", "tsk", 0); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(inputPath, "repo", "src", "main.go"))
+	if err != nil || string(content) != "v1" {
+		t.Fatalf("src/main.go = %q (%v), want the tagged content", content, err)
+	}
+
+	close(metricChan)
+	metric := (<-metricChan).(metrics.TaskIOMetrics)
+	if metric.URL != url+"@"+tagged || metric.OperationType != GitOperation {
+		t.Errorf("metric = %+v, want url %s@%s", metric, url, tagged)
+	}
+	if metric.NumberOfFiles != 2 || metric.SizeInBytes != int64(len("v1")+len("docs")) {
+		t.Errorf("metric counts %d files of %d bytes, want 2 files without git metadata",
+			metric.NumberOfFiles, metric.SizeInBytes)
+	}
+
+	close(osmoChan)
+	var logged bool
+	for message := range osmoChan {
+		logged = logged || strings.Contains(message, "at commit "+tagged)
+	}
+	if !logged {
+		t.Error("expected the resolved commit in the task logs")
+	}
+}
+
+func TestGitInput_Download_DefaultBranchWithSparsePaths(t *testing.T) {
+	url, _ := stageGitRepo(t)
+	t.Setenv("PATH", stageNoOpOsmo(t)+":"+os.Getenv("PATH"))
+
+	inputPath := t.TempDir() + "/"
+	input := GitInput{Folder: "repo", Url: url, SparsePaths: []string{"src"}}
+	if err := input.Download(context.Background(), nil, inputPath, make(chan string, 64),
+		make(chan metrics.Metric, 8), "0", "grp", "tsk", 0); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(inputPath, "repo", "src", "main.go"))
+	if err != nil || string(content) != "v2" {
+		t.Fatalf("src/main.go = %q (%v), want the default branch content", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(inputPath, "repo", "docs")); !os.IsNotExist(err) {
+		t.Errorf("docs should be excluded by the sparse checkout, stat err = %v", err)
+	}
+}
+
+func TestGitInput_Download_RetriesFetchUnderDownloadPolicy(t *testing.T) {
+	url, _ := stageGitRepo(t)
+	t.Setenv("PATH", stageNoOpOsmo(t)+":"+os.Getenv("PATH"))
+	policies := common.DefaultRetryPolicies()
+	download := policies[common.DownloadOperation]
+	download.MaxAttempts = 2
+	download.InitialBackoff = time.Millisecond
+	policies[common.DownloadOperation] = download
+	defaultPolicies := RetryPolicies
+	RetryPolicies = policies
+	t.Cleanup(func() { RetryPolicies = defaultPolicies })
+
+	osmoChan := make(chan string, 256)
+	input := GitInput{Folder: "repo", Url: url, Ref: "missing"}
+	err := input.Download(context.Background(), nil, t.TempDir()+"/", osmoChan,
+		make(chan metrics.Metric, 8), "0", "grp", "tsk", 0)
+	if err == nil {
+		t.Fatal("expected the fetch of a missing ref to fail")
+	}
+
+	close(osmoChan)
+	var retries, exhausted int
+	for message := range osmoChan {
+		if strings.HasPrefix(message, "Git command failed: exit code 128. Retrying") {
+			retries++
+		}
+		if strings.Contains(message, "after 2 attempts. Not retrying.") {
+			exhausted++
+		}
+	}
+	if retries != 1 || exhausted != 1 {
+		t.Errorf("logged %d retries and %d exhausted decisions, want 1 of each", retries,
+			exhausted)
+	}
+}
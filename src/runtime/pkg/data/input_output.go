@@ -19,6 +19,7 @@ SPDX-License-Identifier: Apache-2.0
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -39,15 +40,15 @@ type InputOutput interface {
 
 type InputType interface {
 	GetFolder() string
-	Download(c net.Conn, inputPath string, osmoChan chan string,
+	Download(ctx context.Context, c net.Conn, inputPath string, osmoChan chan string,
 		metricChan chan metrics.Metric, retryId string, groupName string, taskName string,
-		inputIndex int)
+		inputIndex int) error
 }
 
 type OutputType interface {
-	UploadFolder(c net.Conn, outputPath string, osmoChan chan string,
+	UploadFolder(ctx context.Context, c net.Conn, outputPath string, osmoChan chan string,
 		metricChan chan metrics.Metric, retryId string, groupName string, taskName string,
-		outputUrlID string, outputIndex int)
+		outputUrlID string, outputIndex int) error
 }
 
 // Define "task" input/output
@@ -62,15 +63,29 @@ type TaskInput struct {
 func (f TaskInput) GetLogInfo() string       { return f.Name }
 func (f TaskInput) GetUrlIdentifier() string { return f.Url }
 func (f TaskInput) GetFolder() string        { return f.Folder }
-func (f TaskInput) Download(c net.Conn, inputPath string,
+func (f TaskInput) Download(ctx context.Context, c net.Conn, inputPath string,
 	osmoChan chan string, metricChan chan metrics.Metric,
-	retryId string, groupName string, taskName string, inputIndex int) {
+	retryId string, groupName string, taskName string, inputIndex int) error {
 
-	CreateFolder(inputPath, f.Folder)
+	if _, err := CreateFolder(inputPath, f.Folder); err != nil {
+		return err
+	}
 	inputType := "Downloaded"
 
 	benchmarkFolder := fmt.Sprintf("INPUT_%d", inputIndex)
-	benchmarks := DownloadURI(c, f.Url, inputPath+f.Folder, f.Regex, osmoChan, benchmarkFolder)
+	stopProgress := TransferProgressReporter{metricChan, retryId, groupName, taskName, f.Url,
+		"INPUT"}.Watch(benchmarkFolder)
+	benchmarks, err := DownloadURI(ctx, c, f.Url, inputPath+f.Folder, f.Regex, osmoChan,
+		benchmarkFolder)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	err = verifyDownload(ctx, c, f.Url, inputPath+f.Folder, f.Regex, osmoChan,
+		benchmarkFolder+"_manifest")
+	if err != nil {
+		return err
+	}
 
 	for _, benchmark := range benchmarks {
 		if benchmark.TotalBytesTransferred == 0 {
@@ -94,7 +109,7 @@ func (f TaskInput) Download(c net.Conn, inputPath string,
 
 	log.Printf("%s %s to %s", inputType, f.Name, inputPath+f.Folder)
 	osmoChan <- inputType + " " + f.Name + " to {{input:" + f.Folder + "}}"
-	PrintDirContents(c, inputPath+f.Folder, 1, osmoChan)
+	return PrintDirContents(c, inputPath+f.Folder, 1, osmoChan)
 }
 
 type TaskOutput struct {
@@ -105,12 +120,23 @@ type TaskOutput struct {
 
 func (f TaskOutput) GetLogInfo() string       { return f.Name }
 func (f TaskOutput) GetUrlIdentifier() string { return f.Url }
-func (f *TaskOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan string,
-	metricChan chan metrics.Metric, retryId string, groupName string, taskName string,
-	outputUrlID string, outputIndex int) {
+func (f *TaskOutput) UploadFolder(ctx context.Context, c net.Conn, outputPath string,
+	osmoChan chan string, metricChan chan metrics.Metric, retryId string, groupName string,
+	taskName string, outputUrlID string, outputIndex int) error {
 
 	benchmarkFolder := fmt.Sprintf("OUTPUT_%d", outputIndex)
-	benchmarks := UploadData(f.Url, outputPath+"*", "", osmoChan, benchmarkFolder)
+	stopProgress := TransferProgressReporter{metricChan, retryId, groupName, taskName, outputUrlID,
+		"OUTPUT"}.Watch(benchmarkFolder)
+	benchmarks, err := UploadData(ctx, f.Url, outputPath+"*", "", osmoChan, benchmarkFolder)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	// Uploaded after the data so a present manifest implies the upload completed
+	err = uploadManifest(ctx, f.Url, outputPath, osmoChan, benchmarkFolder+"_manifest")
+	if err != nil {
+		return err
+	}
 
 	for _, benchmark := range benchmarks {
 		if benchmark.TotalBytesTransferred == 0 {
@@ -134,6 +160,7 @@ func (f *TaskOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan s
 
 	log.Printf("Uploaded %s from %s", f.Name, outputPath+"*")
 	osmoChan <- "Uploaded " + f.Name
+	return nil
 }
 
 // Define "url" input/output
@@ -147,14 +174,23 @@ type UrlInput struct {
 func (f UrlInput) GetLogInfo() string       { return f.Url }
 func (f UrlInput) GetUrlIdentifier() string { return f.Url }
 func (f UrlInput) GetFolder() string        { return f.Folder }
-func (f UrlInput) Download(c net.Conn, inputPath string,
+func (f UrlInput) Download(ctx context.Context, c net.Conn, inputPath string,
 	osmoChan chan string, metricChan chan metrics.Metric,
-	retryId string, groupName string, taskName string, inputIndex int) {
+	retryId string, groupName string, taskName string, inputIndex int) error {
 
-	CreateFolder(inputPath, f.Folder)
+	if _, err := CreateFolder(inputPath, f.Folder); err != nil {
+		return err
+	}
 	inputType := "Downloaded"
 	benchmarkFolder := fmt.Sprintf("%s_%s_INPUT_%d", groupName, taskName, inputIndex)
-	benchmarks := DownloadURI(c, f.Url, inputPath+f.Folder, f.Regex, osmoChan, benchmarkFolder)
+	stopProgress := TransferProgressReporter{metricChan, retryId, groupName, taskName, f.Url,
+		"INPUT"}.Watch(benchmarkFolder)
+	benchmarks, err := DownloadURI(ctx, c, f.Url, inputPath+f.Folder, f.Regex, osmoChan,
+		benchmarkFolder)
+	stopProgress()
+	if err != nil {
+		return err
+	}
 	for _, benchmark := range benchmarks {
 		if benchmark.TotalBytesTransferred == 0 {
 			continue
@@ -178,7 +214,7 @@ func (f UrlInput) Download(c net.Conn, inputPath string,
 
 	log.Printf("%s %s to %s", inputType, f.Url, inputPath+f.Folder)
 	osmoChan <- inputType + " " + f.Url + " to {{input:" + f.Folder + "}}"
-	PrintDirContents(c, inputPath+f.Folder, 1, osmoChan)
+	return PrintDirContents(c, inputPath+f.Folder, 1, osmoChan)
 }
 
 type UrlOutput struct {
@@ -189,11 +225,17 @@ type UrlOutput struct {
 
 func (f UrlOutput) GetLogInfo() string       { return f.Url }
 func (f UrlOutput) GetUrlIdentifier() string { return f.Url }
-func (f *UrlOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan string,
-	metricChan chan metrics.Metric, retryId string, groupName string, taskName string,
-	outputUrlID string, outputIndex int) {
+func (f *UrlOutput) UploadFolder(ctx context.Context, c net.Conn, outputPath string,
+	osmoChan chan string, metricChan chan metrics.Metric, retryId string, groupName string,
+	taskName string, outputUrlID string, outputIndex int) error {
 	benchmarkFolder := fmt.Sprintf("OUTPUT_%d", outputIndex)
-	benchmarks := UploadData(f.Url, outputPath+"*", f.Regex, osmoChan, benchmarkFolder)
+	stopProgress := TransferProgressReporter{metricChan, retryId, groupName, taskName, outputUrlID,
+		"OUTPUT"}.Watch(benchmarkFolder)
+	benchmarks, err := UploadData(ctx, f.Url, outputPath+"*", f.Regex, osmoChan, benchmarkFolder)
+	stopProgress()
+	if err != nil {
+		return err
+	}
 
 	for _, benchmark := range benchmarks {
 		if benchmark.TotalBytesTransferred == 0 {
@@ -217,6 +259,7 @@ func (f *UrlOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan st
 
 	log.Printf("Uploaded %s from %s", f.Url, outputPath+"*")
 	osmoChan <- "Uploaded " + f.Url
+	return nil
 }
 
 type KpiOutput struct {
@@ -227,11 +270,14 @@ type KpiOutput struct {
 
 func (f KpiOutput) GetLogInfo() string       { return fmt.Sprintf("KPI: %s", f.Path) }
 func (f KpiOutput) GetUrlIdentifier() string { return fmt.Sprintf("%s/%s", f.Url, f.Path) }
-func (f *KpiOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan string,
-	metricChan chan metrics.Metric, retryId string, groupName string, taskName string,
-	outputUrlID string, outputIndex int) {
+func (f *KpiOutput) UploadFolder(ctx context.Context, c net.Conn, outputPath string,
+	osmoChan chan string, metricChan chan metrics.Metric, retryId string, groupName string,
+	taskName string, outputUrlID string, outputIndex int) error {
 	benchmarkFolder := fmt.Sprintf("OUTPUT_%d", outputIndex)
-	benchmarks := UploadData(f.Url, outputPath+f.Path, "", osmoChan, benchmarkFolder)
+	benchmarks, err := UploadData(ctx, f.Url, outputPath+f.Path, "", osmoChan, benchmarkFolder)
+	if err != nil {
+		return err
+	}
 
 	for _, benchmark := range benchmarks {
 		if benchmark.TotalBytesTransferred == 0 {
@@ -255,42 +301,82 @@ func (f *KpiOutput) UploadFolder(c net.Conn, outputPath string, osmoChan chan st
 
 	log.Printf("Uploaded KPI from %s", f.Path)
 	osmoChan <- "Uploaded KPI: " + f.Path
+
+	// The file is already uploaded, so unparsable values are reported without failing the task
+	values, problems, err := ParseKpiFile(outputPath + f.Path)
+	if err != nil {
+		osmoChan <- fmt.Sprintf("Failed to parse KPI %s: %s", f.Path, err)
+		return nil
+	}
+	for _, problem := range problems {
+		osmoChan <- fmt.Sprintf("Skipping value in KPI %s: %s", f.Path, problem)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	metricChan <- metrics.KpiMetrics{
+		RetryId:   retryId,
+		GroupName: groupName,
+		TaskName:  taskName,
+		URL:       outputUrlID,
+		Path:      f.Path,
+		Time:      time.Now().Format("2006-01-02 15:04:05.000"),
+		Values:    values,
+	}
+	osmoChan <- fmt.Sprintf("Reported %d values from KPI %s", len(values), f.Path)
+	return nil
 }
 
+// ParseInputOutput parses a legacy spec without knowing whether it is an input or an output,
+// telling them apart by the number of fields. Prefer ParseInput and ParseOutput, which also
+// accept JSON specs and validate them.
 func ParseInputOutput(value string) InputOutput {
-	details := strings.SplitN(value, ":", 2)
-	if details[0] == "task" {
+	specType, fields, _ := strings.Cut(value, ":")
+	var spec InputOutputSpec
+	var err error
+	switch specType {
+	case "task", "url":
 		// task:<folder>,<url>,<regex> or task:<url>
-		lineDetails := strings.SplitN(details[1], ",", 3)
-		if len(lineDetails) == 3 {
-			return TaskInput{lineDetails[0],
-				lineDetails[1][strings.LastIndex(lineDetails[1], "/")+1:],
-				lineDetails[1], lineDetails[2]}
-		}
-		return &TaskOutput{lineDetails[0][strings.LastIndex(lineDetails[0], "/")+1:],
-			lineDetails[0]}
-	} else if details[0] == "url" {
 		// url:<folder>,<url>,<regex> or url:<url>,<regex>
-		lineDetails := strings.SplitN(details[1], ",", 3)
-		if len(lineDetails) == 2 {
-			return &UrlOutput{lineDetails[0], lineDetails[1]}
+		if len(strings.SplitN(fields, ",", 3)) == 3 {
+			spec, err = parseLegacyInputSpec(value)
+			if err == nil {
+				return spec.input()
+			}
+		} else {
+			spec, err = parseLegacyOutputSpec(value)
+		}
+	case "git":
+		// Only has input
+		// git:<folder>,<repo>,<ref>
+		spec, err = parseLegacyInputSpec(value)
+		if err == nil {
+			return spec.input()
 		}
-		return UrlInput{lineDetails[0], lineDetails[1], lineDetails[2]}
-	} else if details[0] == "kpi" {
+	case "kpi":
 		// Only has output
 		// kpi:<url>,<path>
-		lineDetails := strings.SplitN(details[1], ",", 2)
-		return &KpiOutput{lineDetails[0], lineDetails[1]}
+		spec, err = parseLegacyOutputSpec(value)
+	default:
+		osmo_errors.SetExitCode(osmo_errors.INVALID_INPUT_CODE)
+		panic(fmt.Sprintf("Unknown Input %s", specType))
+	}
+	if err != nil {
+		osmo_errors.SetExitCode(osmo_errors.INVALID_INPUT_CODE)
+		panic(fmt.Sprintf("Invalid %s: %s", value, err))
 	}
-	osmo_errors.SetExitCode(osmo_errors.INVALID_INPUT_CODE)
-	panic(fmt.Sprintf("Unknown Input %s", details[0]))
+	return spec.output()
 }
 
 // ValidateDataAuth validates access permissions for a single input/output operation
 // Retries on execution failures (service down, rate limit) but fails fast on auth failures
-func ValidateDataAuth(value string, userConfig string, osmoChan chan string) error {
-	inputOutput := ParseInputOutput(value)
+func ValidateDataAuth(ctx context.Context, value string, userConfig string,
+	osmoChan chan string) error {
+	return validateDataAuth(ctx, ParseInputOutput(value), userConfig, osmoChan)
+}
 
+func validateDataAuth(ctx context.Context, inputOutput InputOutput, userConfig string,
+	osmoChan chan string) error {
 	var commandArgs []string
 	logInfo := inputOutput.GetLogInfo()
 	urlIdentifier := inputOutput.GetUrlIdentifier()
@@ -306,13 +392,17 @@ func ValidateDataAuth(value string, userConfig string, osmoChan chan string) err
 		osmoChan <- fmt.Sprintf("Validating WRITE access for URI output: %s", logInfo)
 
 	default:
-		// All other types (TaskInput, TaskOutput, KpiOutput) are ignored
+		// All other types (TaskInput, GitInput, TaskOutput, KpiOutput) are ignored
 		return nil
 	}
 
 	// Execute with retry logic for transient failures (exit 1)
 	// Auth failures (exit 0 with status=fail) will be caught immediately
-	outb := RunOSMOCommandWithRetry(commandArgs, 3, osmoChan, osmo_errors.DATA_AUTH_CHECK_FAILED_CODE)
+	outb, err := RunOSMOCommandWithRetry(ctx, commandArgs,
+		RetryPolicies[common.AuthCheckOperation], osmoChan, osmo_errors.DATA_AUTH_CHECK_FAILED_CODE)
+	if err != nil {
+		return err
+	}
 
 	// Parse JSON response
 	var result struct {
@@ -323,7 +413,7 @@ func ValidateDataAuth(value string, userConfig string, osmoChan chan string) err
 	if err := json.Unmarshal(outb.Bytes(), &result); err != nil {
 		errMsg := fmt.Sprintf("Failed to parse validation response for %s: %s", logInfo, err.Error())
 		osmoChan <- errMsg
-		return fmt.Errorf("%s", errMsg)
+		return osmo_errors.Errorf(osmo_errors.DATA_UNAUTHORIZED_CODE, "%s", errMsg)
 	}
 
 	switch strings.ToLower(result.Status) {
@@ -334,33 +424,43 @@ func ValidateDataAuth(value string, userConfig string, osmoChan chan string) err
 	case "fail":
 		errMsg := fmt.Sprintf("Data auth validation failed for %s: %s", logInfo, result.Error)
 		osmoChan <- errMsg
-		return fmt.Errorf("%s", errMsg)
+		return osmo_errors.Errorf(osmo_errors.DATA_UNAUTHORIZED_CODE, "%s", errMsg)
 
 	default:
 		errMsg := fmt.Sprintf("unknown data auth validation status: %s", result.Status)
 		osmoChan <- errMsg
-		return fmt.Errorf("%s", errMsg)
+		return osmo_errors.Errorf(osmo_errors.DATA_UNAUTHORIZED_CODE, "%s", errMsg)
 	}
 }
 
 // ValidateInputsOutputsAccess validates read access for inputs and write access for outputs.
 // Only URL inputs and outputs require runtime data auth validation.
-// All other types (TaskInput, TaskOutput, KpiOutput) are ignored
+// All other types (TaskInput, GitInput, TaskOutput, KpiOutput) are ignored
 func ValidateInputsOutputsAccess(
+	ctx context.Context,
 	inputs common.ArrayFlags,
 	outputs common.ArrayFlags,
 	userConfig string,
 	osmoChan chan string,
 ) error {
-	osmoChan <- "Validating data access permissions..."
+	allItems := make([]InputOutput, 0, len(inputs)+len(outputs))
+	for _, value := range inputs {
+		allItems = append(allItems, ParseInputOutput(value))
+	}
+	for _, value := range outputs {
+		allItems = append(allItems, ParseInputOutput(value))
+	}
+	return ValidateDataAccess(ctx, allItems, userConfig, osmoChan)
+}
 
-	allItems := make([]string, 0, len(inputs)+len(outputs))
-	allItems = append(allItems, inputs...)
-	allItems = append(allItems, outputs...)
+// ValidateDataAccess validates the access of already parsed inputs and outputs
+func ValidateDataAccess(ctx context.Context, items []InputOutput, userConfig string,
+	osmoChan chan string) error {
+	osmoChan <- "Validating data access permissions..."
 
-	// Validate all items - ValidateDataAuth will parse and determine if validation is needed
-	for _, value := range allItems {
-		if err := ValidateDataAuth(value, userConfig, osmoChan); err != nil {
+	// Validate all items - validateDataAuth will determine if validation is needed
+	for _, inputOutput := range items {
+		if err := validateDataAuth(ctx, inputOutput, userConfig, osmoChan); err != nil {
 			return err
 		}
 	}
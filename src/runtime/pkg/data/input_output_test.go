@@ -19,6 +19,7 @@ SPDX-License-Identifier: Apache-2.0
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -250,7 +251,7 @@ func stageFakeOsmo(t *testing.T, body string) string {
 func TestValidateDataAuth_TaskInput_ReturnsNilWithoutShellout(t *testing.T) {
 	osmoChan := make(chan string, 16)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"task:myfolder,http://host/path/data.tar,*.txt", "/cfg.yaml", osmoChan)
 
 	if err != nil {
@@ -261,7 +262,7 @@ func TestValidateDataAuth_TaskInput_ReturnsNilWithoutShellout(t *testing.T) {
 func TestValidateDataAuth_TaskOutput_ReturnsNilWithoutShellout(t *testing.T) {
 	osmoChan := make(chan string, 16)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"task:s3://bucket/output/file", "/cfg.yaml", osmoChan)
 
 	if err != nil {
@@ -272,7 +273,7 @@ func TestValidateDataAuth_TaskOutput_ReturnsNilWithoutShellout(t *testing.T) {
 func TestValidateDataAuth_KpiOutput_ReturnsNilWithoutShellout(t *testing.T) {
 	osmoChan := make(chan string, 16)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"kpi:http://metrics.example,results/metrics.json", "/cfg.yaml", osmoChan)
 
 	if err != nil {
@@ -287,7 +288,7 @@ func TestValidateDataAuth_UrlInput_PassReturnsNilAndAnnouncesRead(t *testing.T)
 	dir := stageFakeOsmo(t, "#!/bin/sh\nprintf '{\"status\":\"pass\"}'\n")
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:inputs,http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err != nil {
@@ -319,7 +320,7 @@ func TestValidateDataAuth_UrlInput_FailReturnsErrorWithDetail(t *testing.T) {
 		"#!/bin/sh\nprintf '{\"status\":\"fail\",\"error\":\"forbidden\"}'\n")
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:inputs,http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err == nil {
@@ -341,7 +342,7 @@ func TestValidateDataAuth_UrlInput_UnknownStatusReturnsError(t *testing.T) {
 		"#!/bin/sh\nprintf '{\"status\":\"weird\"}'\n")
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:inputs,http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err == nil {
@@ -359,7 +360,7 @@ func TestValidateDataAuth_UrlInput_InvalidJSONReturnsParseError(t *testing.T) {
 	dir := stageFakeOsmo(t, "#!/bin/sh\nprintf 'not-json'\n")
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:inputs,http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err == nil {
@@ -388,7 +389,7 @@ printf '{"status":"fail","error":"WRITE access flag missing"}'
 `)
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err != nil {
@@ -414,7 +415,7 @@ func TestValidateDataAuth_UrlOutput_FailReturnsErrorWithDetail(t *testing.T) {
 		"#!/bin/sh\nprintf '{\"status\":\"fail\",\"error\":\"denied\"}'\n")
 	t.Setenv("PATH", dir)
 
-	err := ValidateDataAuth(
+	err := ValidateDataAuth(context.Background(),
 		"url:http://example.com/data,*.json", "/cfg.yaml", osmoChan)
 
 	if err == nil {
@@ -434,7 +435,7 @@ func TestValidateDataAuth_UrlOutput_FailReturnsErrorWithDetail(t *testing.T) {
 func TestValidateInputsOutputsAccess_EmptyListsAnnouncesAndReturnsNil(t *testing.T) {
 	osmoChan := make(chan string, 16)
 
-	err := ValidateInputsOutputsAccess(
+	err := ValidateInputsOutputsAccess(context.Background(),
 		common.ArrayFlags{}, common.ArrayFlags{}, "/cfg.yaml", osmoChan)
 	close(osmoChan)
 
@@ -464,7 +465,7 @@ func TestValidateInputsOutputsAccess_TaskAndKpiItems_ReturnsNilSkippingShellout(
 	inputs := common.ArrayFlags{"task:f,http://h/p/d.tar,*.txt"}
 	outputs := common.ArrayFlags{"task:s3://bucket/file", "kpi:http://m,results/m.json"}
 
-	err := ValidateInputsOutputsAccess(inputs, outputs, "/cfg.yaml", osmoChan)
+	err := ValidateInputsOutputsAccess(context.Background(), inputs, outputs, "/cfg.yaml", osmoChan)
 
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
@@ -485,7 +486,8 @@ func TestValidateInputsOutputsAccess_UrlInputFailShortCircuits(t *testing.T) {
 		"url:in2,http://example.com/data2,*.json",
 	}
 
-	err := ValidateInputsOutputsAccess(inputs, common.ArrayFlags{}, "/cfg.yaml", osmoChan)
+	err := ValidateInputsOutputsAccess(context.Background(), inputs, common.ArrayFlags{},
+		"/cfg.yaml", osmoChan)
 
 	if err == nil {
 		t.Fatal("expected error to propagate from the first failing item")
@@ -594,8 +596,10 @@ func TestTaskInput_Download_RunsThroughCreateFolderAndMetricPipeline(t *testing.
 		Url:    "s3://bucket/data.tar",
 		Regex:  "",
 	}
-	ti.Download(nil, inputPath, osmoChan, metricChan,
-		"r1", "g1", "t1", 3)
+	if err := ti.Download(context.Background(), nil, inputPath, osmoChan, metricChan,
+		"r1", "g1", "t1", 3); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
 
 	if _, err := os.Stat(inputPath + "fld"); err != nil {
 		t.Errorf("expected CreateFolder to make %q: %v", inputPath+"fld", err)
@@ -622,8 +626,10 @@ func TestTaskOutput_UploadFolder_RunsThroughMetricPipeline(t *testing.T) {
 	metricChan := make(chan metrics.Metric, 8)
 
 	to := &TaskOutput{Name: "out.bin", Url: "s3://bucket/out.bin"}
-	to.UploadFolder(nil, outputPath, osmoChan, metricChan,
-		"r2", "g2", "t2", "url-id", 7)
+	if err := to.UploadFolder(context.Background(), nil, outputPath, osmoChan, metricChan,
+		"r2", "g2", "t2", "url-id", 7); err != nil {
+		t.Fatalf("UploadFolder: %v", err)
+	}
 
 	urls := drainMetricChan(metricChan)
 	if len(urls) != 1 {
@@ -647,8 +653,10 @@ func TestUrlInput_Download_UsesGroupTaskIndexedBenchmarkFolder(t *testing.T) {
 	metricChan := make(chan metrics.Metric, 8)
 
 	ui := UrlInput{Folder: "uin", Url: "s3://bucket/data", Regex: "*.bin"}
-	ui.Download(nil, inputPath, osmoChan, metricChan,
-		"r3", "grp", "tsk", 2)
+	if err := ui.Download(context.Background(), nil, inputPath, osmoChan, metricChan,
+		"r3", "grp", "tsk", 2); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
 
 	if _, err := os.Stat(inputPath + "uin"); err != nil {
 		t.Errorf("expected CreateFolder to make %q: %v", inputPath+"uin", err)
@@ -674,8 +682,10 @@ func TestUrlOutput_UploadFolder_RunsThroughMetricPipeline(t *testing.T) {
 	metricChan := make(chan metrics.Metric, 8)
 
 	uo := &UrlOutput{Url: "s3://bucket/out", Regex: "*.bin"}
-	uo.UploadFolder(nil, outputPath, osmoChan, metricChan,
-		"r4", "g4", "t4", "url-id-4", 4)
+	if err := uo.UploadFolder(context.Background(), nil, outputPath, osmoChan, metricChan,
+		"r4", "g4", "t4", "url-id-4", 4); err != nil {
+		t.Fatalf("UploadFolder: %v", err)
+	}
 
 	urls := drainMetricChan(metricChan)
 	if len(urls) != 1 {
@@ -698,8 +708,10 @@ func TestKpiOutput_UploadFolder_RunsThroughMetricPipeline(t *testing.T) {
 	metricChan := make(chan metrics.Metric, 8)
 
 	kpi := &KpiOutput{Url: "s3://bucket/kpi", Path: "results/m.json"}
-	kpi.UploadFolder(nil, outputPath, osmoChan, metricChan,
-		"r5", "g5", "t5", "url-id-9", 9)
+	if err := kpi.UploadFolder(context.Background(), nil, outputPath, osmoChan, metricChan,
+		"r5", "g5", "t5", "url-id-9", 9); err != nil {
+		t.Fatalf("UploadFolder: %v", err)
+	}
 
 	urls := drainMetricChan(metricChan)
 	if len(urls) != 1 {
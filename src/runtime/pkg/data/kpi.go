@@ -0,0 +1,219 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KPI files larger than this are uploaded but not parsed
+	MaxKpiFileSize int64 = 1024 * 1024
+	// Only the first values of a KPI file are sent to the service
+	MaxKpiValues int = 1000
+)
+
+var kpiNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.\-/]{1,256}$`)
+
+// kpiValues collects named numeric values and the reasons entries were skipped
+type kpiValues struct {
+	values   map[string]float64
+	problems []string
+}
+
+func (k *kpiValues) add(name string, value float64) {
+	switch {
+	case !kpiNameRegex.MatchString(name):
+		k.problems = append(k.problems, fmt.Sprintf("invalid KPI name %q", name))
+	case math.IsNaN(value) || math.IsInf(value, 0):
+		k.problems = append(k.problems, fmt.Sprintf("KPI %s is not a finite number", name))
+	case len(k.values) >= MaxKpiValues:
+		k.problems = append(k.problems, fmt.Sprintf("KPI %s exceeds the limit of %d values",
+			name, MaxKpiValues))
+	default:
+		k.values[name] = value
+	}
+}
+
+func (k *kpiValues) addString(name string, value string) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		k.problems = append(k.problems, fmt.Sprintf("KPI %s is not a number: %q", name, value))
+		return
+	}
+	k.add(name, number)
+}
+
+// addJson flattens nested objects into dotted names. Numbers and booleans are kept, other
+// values are reported.
+func (k *kpiValues) addJson(name string, value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if name == "" {
+				k.addJson(key, typed[key])
+			} else {
+				k.addJson(name+"."+key, typed[key])
+			}
+		}
+	case json.Number:
+		k.addString(name, typed.String())
+	case bool:
+		if typed {
+			k.add(name, 1)
+		} else {
+			k.add(name, 0)
+		}
+	default:
+		k.problems = append(k.problems, fmt.Sprintf("KPI %s is not a number", name))
+	}
+}
+
+func parseJsonKpis(content []byte, kpis *kpiValues) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var parsed any
+	if err := decoder.Decode(&parsed); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, isObject := parsed.(map[string]any); !isObject {
+		return fmt.Errorf("expected a JSON object of KPI names to values")
+	}
+	kpis.addJson("", parsed)
+	return nil
+}
+
+// parseCsvKpis accepts either `name,value` rows or a header row of names followed by rows of
+// values, in which case the last row holds the final values.
+func parseCsvKpis(content []byte, kpis *kpiValues) error {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	isKeyValue := len(rows[0]) == 2
+	for _, row := range rows[1:] {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(row[0]), 64); err == nil {
+			isKeyValue = false
+		}
+		if _, err := strconv.ParseFloat(strings.TrimSpace(row[len(row)-1]), 64); err != nil {
+			isKeyValue = false
+		}
+	}
+	if isKeyValue {
+		for i, row := range rows {
+			// The first row may be a header such as `name,value`
+			if _, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64); err != nil && i == 0 {
+				continue
+			}
+			kpis.addString(strings.TrimSpace(row[0]), row[1])
+		}
+		return nil
+	}
+
+	if len(rows) < 2 {
+		return fmt.Errorf("expected a header row followed by a row of values")
+	}
+	header, last := rows[0], rows[len(rows)-1]
+	for i, name := range header {
+		kpis.addString(strings.TrimSpace(name), last[i])
+	}
+	return nil
+}
+
+// parseKeyValueKpis accepts lines of `name=value`, `name: value` or `name value`
+func parseKeyValueKpis(content []byte, kpis *kpiValues) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			name, value, found = strings.Cut(line, ":")
+		}
+		if !found {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				kpis.problems = append(kpis.problems, fmt.Sprintf("invalid KPI line %q", line))
+				continue
+			}
+			name, value = fields[0], fields[1]
+		}
+		kpis.addString(strings.TrimSpace(name), value)
+	}
+	return scanner.Err()
+}
+
+// ParseKpiFile returns the numeric values of a KPI file. JSON and CSV files are recognized by
+// their extension and everything else is read as key/value lines. Invalid entries are skipped and
+// described by the returned problems, while an error means the file could not be read at all.
+func ParseKpiFile(path string) (map[string]float64, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s is not a file", path)
+	}
+	if info.Size() > MaxKpiFileSize {
+		return nil, nil, fmt.Errorf("%s is larger than %d bytes", path, MaxKpiFileSize)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kpis := kpiValues{values: map[string]float64{}}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = parseJsonKpis(content, &kpis)
+	case ".csv":
+		err = parseCsvKpis(content, &kpis)
+	default:
+		err = parseKeyValueKpis(content, &kpis)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return kpis.values, kpis.problems, nil
+}
@@ -0,0 +1,131 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+)
+
+// ---------------------------------------------------------------------------
+// ParseKpiFile
+// ---------------------------------------------------------------------------
+
+func parseKpiContent(t *testing.T, name string, content string) (map[string]float64, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{name: content})
+	values, problems, err := ParseKpiFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("ParseKpiFile(%s): %v", name, err)
+	}
+	return values, problems
+}
+
+func TestParseKpiFile_JsonFlattensNestedObjects(t *testing.T) {
+	values, problems := parseKpiContent(t, "kpi.json",
+		`{"loss": 0.25, "eval": {"accuracy": 0.9, "passed": true}, "model": "resnet"}`)
+	want := map[string]float64{"loss": 0.25, "eval.accuracy": 0.9, "eval.passed": 1}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "model") {
+		t.Errorf("expected a problem for the string value, got %v", problems)
+	}
+}
+
+func TestParseKpiFile_CsvKeyValueRowsWithHeader(t *testing.T) {
+	values, problems := parseKpiContent(t, "kpi.csv", "name,value\nloss,0.5\nsteps,1000\n")
+	want := map[string]float64{"loss": 0.5, "steps": 1000}
+	if !reflect.DeepEqual(values, want) || len(problems) > 0 {
+		t.Errorf("values = %v, problems = %v, want %v", values, problems, want)
+	}
+}
+
+func TestParseKpiFile_CsvHeaderUsesLastRow(t *testing.T) {
+	values, problems := parseKpiContent(t, "kpi.csv", "loss,accuracy\n0.9,0.1\n0.2,0.8\n")
+	want := map[string]float64{"loss": 0.2, "accuracy": 0.8}
+	if !reflect.DeepEqual(values, want) || len(problems) > 0 {
+		t.Errorf("values = %v, problems = %v, want %v", values, problems, want)
+	}
+}
+
+func TestParseKpiFile_KeyValueLines(t *testing.T) {
+	values, problems := parseKpiContent(t, "kpi.txt",
+		"# final values\nloss=0.1\naccuracy: 0.95\nthroughput 1200\nbad name=1\nlatency=NaN\n")
+	want := map[string]float64{"loss": 0.1, "accuracy": 0.95, "throughput": 1200}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+	if len(problems) != 2 {
+		t.Errorf("expected problems for the invalid name and NaN, got %v", problems)
+	}
+}
+
+func TestParseKpiFile_RejectsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"list.json": "[1, 2]"})
+	if _, _, err := ParseKpiFile(filepath.Join(dir, "list.json")); err == nil {
+		t.Error("expected an error for a JSON array")
+	}
+	if _, _, err := ParseKpiFile(dir); err == nil {
+		t.Error("expected an error for a directory")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// KpiOutput.UploadFolder
+// ---------------------------------------------------------------------------
+
+func TestKpiOutput_UploadFolder_EmitsKpiMetrics(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	t.Setenv("PATH", stageNoOpOsmo(t))
+	redirectBenchmarkPath(t)
+
+	outputPath := t.TempDir() + "/"
+	writeTree(t, outputPath, map[string]string{"results/m.json": `{"loss": 0.5}`})
+	metricChan := make(chan metrics.Metric, 8)
+
+	kpi := &KpiOutput{Url: "s3://bucket/kpi", Path: "results/m.json"}
+	if err := kpi.UploadFolder(context.Background(), nil, outputPath, make(chan string, 64),
+		metricChan, "1", "grp", "tsk", "url-id", 0); err != nil {
+		t.Fatalf("UploadFolder: %v", err)
+	}
+
+	close(metricChan)
+	var kpiMetrics []metrics.KpiMetrics
+	for metric := range metricChan {
+		if kpiMetric, ok := metric.(metrics.KpiMetrics); ok {
+			kpiMetrics = append(kpiMetrics, kpiMetric)
+		}
+	}
+	if len(kpiMetrics) != 1 {
+		t.Fatalf("expected 1 KPI metric, got %v", kpiMetrics)
+	}
+	got := kpiMetrics[0]
+	if got.RetryId != "1" || got.GroupName != "grp" || got.TaskName != "tsk" ||
+		got.Path != "results/m.json" || got.Values["loss"] != 0.5 {
+		t.Errorf("unexpected KPI metric %+v", got)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+const (
+	// ManifestFileName is uploaded next to the task output data. It is a hidden file so
+	// that it does not show up in the directory listing of downstream inputs.
+	ManifestFileName  string = ".osmo_manifest.json"
+	ManifestVersion   int    = 1
+	ManifestAlgorithm string = "sha256"
+)
+
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// hashFile returns the hex encoded SHA-256 digest and the size of the file at path
+func hashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// ComputeManifest walks root and records the size and SHA-256 digest of every regular file.
+// Symlinks to regular files are hashed through the link; other special files are skipped.
+// Paths are stored relative to root using forward slashes.
+func ComputeManifest(root string) (Manifest, error) {
+	manifest := Manifest{Version: ManifestVersion, Algorithm: ManifestAlgorithm}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		} else if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == ManifestFileName {
+			return nil
+		}
+
+		digest, size, err := hashFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{relPath, size, digest})
+		return nil
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return manifest, nil
+}
+
+func WriteManifest(manifest Manifest, path string) error {
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, manifestJson, 0644)
+}
+
+func ReadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	manifestJson, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(manifestJson, &manifest); err != nil {
+		return manifest, err
+	}
+	if manifest.Algorithm != ManifestAlgorithm {
+		return manifest, fmt.Errorf("unsupported manifest algorithm: %s", manifest.Algorithm)
+	}
+	return manifest, nil
+}
+
+// VerifyManifest checks the files under root against the manifest and returns a description
+// of every mismatch. Files that are listed in the manifest but not present under root are only
+// reported when requireAll is set, since a regex filtered download only fetches a subset.
+func VerifyManifest(root string, manifest Manifest, requireAll bool) []string {
+	var mismatches []string
+	for _, entry := range manifest.Files {
+		path := filepath.Join(root, filepath.FromSlash(entry.Path))
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) && !requireAll {
+				continue
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%s: missing", entry.Path))
+			continue
+		}
+
+		digest, size, err := hashFile(path)
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", entry.Path, err))
+			continue
+		}
+		if size != entry.Size {
+			mismatches = append(mismatches,
+				fmt.Sprintf("%s: size %d, expected %d", entry.Path, size, entry.Size))
+		} else if digest != entry.Sha256 {
+			mismatches = append(mismatches,
+				fmt.Sprintf("%s: sha256 %s, expected %s", entry.Path, digest, entry.Sha256))
+		}
+	}
+	return mismatches
+}
+
+// uploadManifest computes the manifest of outputPath and uploads it next to the data at url
+func uploadManifest(ctx context.Context, url string, outputPath string, osmoChan chan string,
+	benchmarkFolderName string) error {
+	manifest, err := ComputeManifest(outputPath)
+	if err != nil {
+		return osmo_errors.CommandError("", "", osmoChan, err, osmo_errors.FILE_FAILED_CODE)
+	}
+
+	manifestDir, err := os.MkdirTemp("", "osmo_manifest")
+	if err != nil {
+		return osmo_errors.CommandError("", "", osmoChan, err, osmo_errors.FILE_FAILED_CODE)
+	}
+	defer os.RemoveAll(manifestDir)
+
+	manifestPath := filepath.Join(manifestDir, ManifestFileName)
+	if err := WriteManifest(manifest, manifestPath); err != nil {
+		return osmo_errors.CommandError("", "", osmoChan, err, osmo_errors.FILE_FAILED_CODE)
+	}
+
+	if _, err := UploadData(ctx, url, manifestPath, "", osmoChan, benchmarkFolderName); err != nil {
+		return err
+	}
+	osmoChan <- fmt.Sprintf("Uploaded manifest with %d files", len(manifest.Files))
+	return nil
+}
+
+// fetchManifest returns the manifest uploaded next to the data at url, and whether there is one.
+// A full download already placed it in folder, where it is removed so that the task only sees
+// its input data. A regex filtered download does not include it, so it is downloaded on its own
+// to a temporary directory outside of the input folder.
+func fetchManifest(ctx context.Context, c net.Conn, url string, folder string, regex string,
+	osmoChan chan string, benchmarkFolderName string) (Manifest, bool, error) {
+	manifestPath := filepath.Join(folder, ManifestFileName)
+	if regex != "" {
+		manifestDir, err := os.MkdirTemp("", "osmo_manifest")
+		if err != nil {
+			return Manifest{}, false, osmo_errors.CommandError("", "", osmoChan, err,
+				osmo_errors.FILE_FAILED_CODE)
+		}
+		defer os.RemoveAll(manifestDir)
+		manifestRegex := "(^|/)" + regexp.QuoteMeta(ManifestFileName) + "$"
+		_, err = DownloadURI(ctx, c, url, manifestDir, manifestRegex, osmoChan,
+			benchmarkFolderName)
+		if err != nil {
+			osmoChan <- fmt.Sprintf("Failed to download manifest of %s: %s", url, err)
+			return Manifest{}, false, nil
+		}
+		manifestPath = filepath.Join(manifestDir, ManifestFileName)
+	}
+	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		return Manifest{}, false, nil
+	}
+	defer os.Remove(manifestPath)
+
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return Manifest{}, false, osmo_errors.CommandError("", "", osmoChan,
+			fmt.Errorf("failed to read manifest of %s: %w", url, err),
+			osmo_errors.DATA_INTEGRITY_FAILED_CODE)
+	}
+	return manifest, true, nil
+}
+
+// verifyDownload checks a downloaded folder against the manifest uploaded by the upstream task.
+// Downloads without a manifest (e.g. outputs of older runtimes) are not verified, which is
+// reported as a warning.
+func verifyDownload(ctx context.Context, c net.Conn, url string, folder string, regex string,
+	osmoChan chan string, benchmarkFolderName string) error {
+	manifest, found, err := fetchManifest(ctx, c, url, folder, regex, osmoChan,
+		benchmarkFolderName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		warning := fmt.Sprintf("WARNING: No manifest found for %s, the integrity of the "+
+			"download is not verified", url)
+		log.Println(warning)
+		osmoChan <- warning
+		return nil
+	}
+
+	mismatches := VerifyManifest(folder, manifest, regex == "")
+	if len(mismatches) > 0 {
+		for _, mismatch := range mismatches {
+			osmoChan <- "Integrity check failed for " + mismatch
+		}
+		return osmo_errors.Errorf(osmo_errors.DATA_INTEGRITY_FAILED_CODE,
+			"Integrity verification failed for %d files in %s", len(mismatches), folder)
+	}
+	osmoChan <- fmt.Sprintf("Verified integrity of %d files", len(manifest.Files))
+	return nil
+}
@@ -0,0 +1,334 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+// writeTree creates every file in files (relative path -> content) under root.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll %q: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %q: %v", path, err)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ComputeManifest — relative sorted paths, digests, skips the manifest itself
+// ---------------------------------------------------------------------------
+
+func TestComputeManifest_RecordsSortedRelativePathsAndDigests(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"b.txt":          "bravo",
+		"nested/a.txt":   "alpha",
+		ManifestFileName: "{}",
+	})
+
+	manifest, err := ComputeManifest(root)
+	if err != nil {
+		t.Fatalf("ComputeManifest: %v", err)
+	}
+
+	if manifest.Version != ManifestVersion || manifest.Algorithm != ManifestAlgorithm {
+		t.Errorf("header = (%d, %q), want (%d, %q)", manifest.Version, manifest.Algorithm,
+			ManifestVersion, ManifestAlgorithm)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected 2 entries (manifest skipped), got %#v", manifest.Files)
+	}
+	if manifest.Files[0].Path != "b.txt" || manifest.Files[1].Path != "nested/a.txt" {
+		t.Errorf("paths = [%q, %q], want [b.txt, nested/a.txt]",
+			manifest.Files[0].Path, manifest.Files[1].Path)
+	}
+	// sha256("alpha")
+	wantDigest := "8ed3f6ad685b959ead7022518e1af76cd816f8e8ec7ccdda1ed4018e8f2223f8"
+	if manifest.Files[1].Sha256 != wantDigest || manifest.Files[1].Size != 5 {
+		t.Errorf("entry = %#v, want size 5 digest %s", manifest.Files[1], wantDigest)
+	}
+}
+
+func TestManifest_WriteReadRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"x.bin": "payload"})
+	manifest, err := ComputeManifest(root)
+	if err != nil {
+		t.Fatalf("ComputeManifest: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), ManifestFileName)
+	if err := WriteManifest(manifest, path); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	got, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if len(got.Files) != 1 || got.Files[0] != manifest.Files[0] {
+		t.Errorf("round trip = %#v, want %#v", got.Files, manifest.Files)
+	}
+}
+
+func TestReadManifest_RejectsUnknownAlgorithm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestFileName)
+	if err := os.WriteFile(path, []byte(`{"version":1,"algorithm":"md5"}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	if _, err := ReadManifest(path); err == nil {
+		t.Errorf("expected error for unsupported algorithm")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// VerifyManifest — corruption, size mismatch and missing file handling
+// ---------------------------------------------------------------------------
+
+func TestVerifyManifest_MatchingTreeHasNoMismatches(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a": "1", "d/b": "2"})
+	manifest, _ := ComputeManifest(root)
+
+	if mismatches := VerifyManifest(root, manifest, true); len(mismatches) != 0 {
+		t.Errorf("expected no mismatches, got %v", mismatches)
+	}
+}
+
+func TestVerifyManifest_ReportsCorruptedAndResizedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"same": "abc", "resized": "abc"})
+	manifest, _ := ComputeManifest(root)
+	writeTree(t, root, map[string]string{"same": "abd", "resized": "abcd"})
+
+	mismatches := VerifyManifest(root, manifest, true)
+	if len(mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", mismatches)
+	}
+	joined := strings.Join(mismatches, "\n")
+	if !strings.Contains(joined, "resized: size 4, expected 3") {
+		t.Errorf("missing size mismatch in %v", mismatches)
+	}
+	if !strings.Contains(joined, "same: sha256") {
+		t.Errorf("missing digest mismatch in %v", mismatches)
+	}
+}
+
+func TestVerifyManifest_MissingFilesOnlyReportedWhenRequired(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"kept": "1", "dropped": "2"})
+	manifest, _ := ComputeManifest(root)
+	os.Remove(filepath.Join(root, "dropped"))
+
+	if mismatches := VerifyManifest(root, manifest, false); len(mismatches) != 0 {
+		t.Errorf("expected missing file to be ignored, got %v", mismatches)
+	}
+	mismatches := VerifyManifest(root, manifest, true)
+	if len(mismatches) != 1 || mismatches[0] != "dropped: missing" {
+		t.Errorf("mismatches = %v, want [dropped: missing]", mismatches)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// TaskOutput / TaskInput — manifest upload and download verification
+// ---------------------------------------------------------------------------
+
+func TestTaskOutput_UploadFolder_UploadsManifestAfterData(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	// The fake osmo records the source path of every upload and copies
+	// uploaded manifests so the test can inspect them. The real PATH is kept
+	// after the fake osmo so the script can use cp.
+	logDir := t.TempDir()
+	body := fmt.Sprintf("#!/bin/sh\necho \"$4\" >> %s/uploads\n"+
+		"case \"$4\" in *%s) cp \"$4\" %s/;; esac\nexit 0\n",
+		logDir, ManifestFileName, logDir)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	outputPath := t.TempDir() + "/"
+	writeTree(t, outputPath, map[string]string{"model.pt": "weights"})
+	osmoChan := make(chan string, 64)
+	metricChan := make(chan metrics.Metric, 8)
+
+	to := &TaskOutput{Name: "out", Url: "s3://bucket/out"}
+	if err := to.UploadFolder(context.Background(), nil, outputPath, osmoChan, metricChan,
+		"r", "g", "t", "url-id", 0); err != nil {
+		t.Fatalf("UploadFolder: %v", err)
+	}
+
+	uploads, err := os.ReadFile(filepath.Join(logDir, "uploads"))
+	if err != nil {
+		t.Fatalf("read upload log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(uploads)), "\n")
+	if len(lines) != 2 || lines[0] != outputPath+"*" ||
+		!strings.HasSuffix(lines[1], ManifestFileName) {
+		t.Fatalf("uploads = %v, want data then manifest", lines)
+	}
+	manifest, err := ReadManifest(filepath.Join(logDir, ManifestFileName))
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Path != "model.pt" {
+		t.Errorf("manifest files = %#v, want [model.pt]", manifest.Files)
+	}
+}
+
+// stageDownloadWithManifest writes a fake osmo whose download populates the
+// target folder with a data file and a manifest describing manifestContent.
+func stageDownloadWithManifest(t *testing.T, dataContent string, manifestContent string) {
+	t.Helper()
+	source := t.TempDir()
+	writeTree(t, source, map[string]string{"data.bin": manifestContent})
+	manifest, err := ComputeManifest(source)
+	if err != nil {
+		t.Fatalf("ComputeManifest: %v", err)
+	}
+	if err := WriteManifest(manifest, filepath.Join(source, ManifestFileName)); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	body := fmt.Sprintf("#!/bin/sh\nprintf '%s' > \"$4/data.bin\"\ncp %s/%s \"$4/\"\nexit 0\n",
+		dataContent, source, ManifestFileName)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+}
+
+func TestTaskInput_Download_VerifiesMatchingManifest(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+	stageDownloadWithManifest(t, "good", "good")
+
+	osmoChan := make(chan string, 64)
+	ti := TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up"}
+	if err := ti.Download(context.Background(), nil, t.TempDir()+"/", osmoChan,
+		make(chan metrics.Metric, 8), "r", "g", "t", 0); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	close(osmoChan)
+	var verified bool
+	for msg := range osmoChan {
+		if msg == "Verified integrity of 1 files" {
+			verified = true
+		}
+	}
+	if !verified {
+		t.Errorf("expected integrity verification message")
+	}
+}
+
+func TestTaskInput_Download_FailsOnManifestMismatch(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+	stageDownloadWithManifest(t, "corrupt", "good")
+
+	ti := TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up"}
+	err := ti.Download(context.Background(), nil, t.TempDir()+"/", make(chan string, 64),
+		make(chan metrics.Metric, 8), "r", "g", "t", 0)
+
+	code := osmo_errors.CodeOf(err)
+	if err == nil || code != osmo_errors.DATA_INTEGRITY_FAILED_CODE {
+		t.Fatalf("error = %v (exit code %d), want an integrity failure", err, code)
+	}
+}
+
+func TestTaskInput_Download_RemovesManifestFromInput(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+	stageDownloadWithManifest(t, "good", "good")
+
+	inputPath := t.TempDir() + "/"
+	ti := TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up"}
+	if err := ti.Download(context.Background(), nil, inputPath, make(chan string, 64),
+		make(chan metrics.Metric, 8), "r", "g", "t", 0); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(inputPath, "in", ManifestFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected the manifest to be removed from the input folder")
+	}
+}
+
+func TestTaskInput_Download_RegexFetchesManifestSeparately(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+
+	// The fake osmo only downloads the manifest when the regex asks for it
+	source := t.TempDir()
+	writeTree(t, source, map[string]string{"data.bin": "good"})
+	manifest, err := ComputeManifest(source)
+	if err != nil {
+		t.Fatalf("ComputeManifest: %v", err)
+	}
+	if err := WriteManifest(manifest, filepath.Join(source, ManifestFileName)); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	body := fmt.Sprintf("#!/bin/sh\ncase \"$*\" in *osmo_manifest*) cp %s/%s \"$4/\";;\n"+
+		"*) printf 'corrupt' > \"$4/data.bin\";; esac\nexit 0\n", source, ManifestFileName)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	inputPath := t.TempDir() + "/"
+	ti := TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up", Regex: `.*\.bin`}
+	err = ti.Download(context.Background(), nil, inputPath, make(chan string, 64),
+		make(chan metrics.Metric, 8), "r", "g", "t", 0)
+	if code := osmo_errors.CodeOf(err); code != osmo_errors.DATA_INTEGRITY_FAILED_CODE {
+		t.Fatalf("error = %v (exit code %d), want an integrity failure", err, code)
+	}
+	if _, err := os.Stat(filepath.Join(inputPath, "in", ManifestFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected the manifest to be downloaded outside of the input folder")
+	}
+}
+
+func TestTaskInput_Download_WarnsWithoutManifest(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	redirectBenchmarkPath(t)
+	t.Setenv("PATH", stageFakeOsmo(t, "#!/bin/sh\nprintf 'data' > \"$4/data.bin\"\n")+":"+
+		os.Getenv("PATH"))
+
+	osmoChan := make(chan string, 64)
+	ti := TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up"}
+	if err := ti.Download(context.Background(), nil, t.TempDir()+"/", osmoChan,
+		make(chan metrics.Metric, 8), "r", "g", "t", 0); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	close(osmoChan)
+	var warned bool
+	for msg := range osmoChan {
+		if strings.HasPrefix(msg, "WARNING: No manifest found for s3://bucket/up") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("expected a warning that the download is not verified")
+	}
+}
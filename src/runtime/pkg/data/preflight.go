@@ -0,0 +1,137 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+// DataSize is printed by the OSMO data CLI with the size command
+type DataSize struct {
+	// Keep the follow fields in sync with osmo/cli/data.py
+	NumberOfFiles int   `json:"count"`
+	SizeInBytes   int64 `json:"size"`
+}
+
+// QueryInputSize returns the size of the data downloaded by the input, and false if the size of
+// the input type cannot be queried before it is downloaded
+func QueryInputSize(ctx context.Context, input InputType,
+	osmoChan chan string) (int64, bool, error) {
+	var url, regex string
+	switch typedInput := input.(type) {
+	case TaskInput:
+		url, regex = typedInput.Url, typedInput.Regex
+	case UrlInput:
+		url, regex = typedInput.Url, typedInput.Regex
+	default:
+		// The size of git inputs is only known once they are cloned
+		return 0, false, nil
+	}
+
+	commandArgs := []string{"osmo", "data", "size", url}
+	if regex != "" {
+		commandArgs = append(commandArgs, "--regex", regex)
+	}
+	// Sizing lists the objects like the access checks
+	outb, err := RunOSMOCommandWithRetry(ctx, commandArgs,
+		RetryPolicies[common.AuthCheckOperation], osmoChan, osmo_errors.PREFLIGHT_FAILED_CODE)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var size DataSize
+	if err := json.Unmarshal(outb.Bytes(), &size); err != nil {
+		return 0, false, osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+			"Failed to parse the size of %s: %s", url, err)
+	}
+	return size.SizeInBytes, true, nil
+}
+
+// DiskRequirement is the free space needed by a path
+type DiskRequirement struct {
+	Path  string
+	Bytes int64
+}
+
+type filesystemSpace struct {
+	paths    []string
+	required int64
+	free     int64
+}
+
+// statFilesystem returns the filesystem and free space of the path, or of its closest existing
+// parent if it is not created yet
+func statFilesystem(path string) (syscall.Fsid, int64, error) {
+	var stat syscall.Statfs_t
+	for {
+		err := syscall.Statfs(path, &stat)
+		if err == nil {
+			return stat.Fsid, int64(stat.Bavail) * int64(stat.Bsize), nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return syscall.Fsid{}, 0, err
+		}
+		path = parent
+	}
+}
+
+// CheckDiskSpace fails if a filesystem has less free space than the requirements of its paths
+// combined, since the input and output folders often share a filesystem
+func CheckDiskSpace(requirements []DiskRequirement, osmoChan chan string) error {
+	var order []syscall.Fsid
+	filesystems := map[syscall.Fsid]*filesystemSpace{}
+	for _, requirement := range requirements {
+		fsid, free, err := statFilesystem(requirement.Path)
+		if err != nil {
+			return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+				"Failed to get the free space of %s: %s", requirement.Path, err)
+		}
+		filesystem, ok := filesystems[fsid]
+		if !ok {
+			filesystem = &filesystemSpace{free: free}
+			filesystems[fsid] = filesystem
+			order = append(order, fsid)
+		}
+		filesystem.paths = append(filesystem.paths, requirement.Path)
+		filesystem.required += requirement.Bytes
+	}
+
+	for _, fsid := range order {
+		filesystem := filesystems[fsid]
+		paths := strings.Join(filesystem.paths, ", ")
+		if filesystem.required > filesystem.free {
+			return osmo_errors.Errorf(osmo_errors.PREFLIGHT_FAILED_CODE,
+				"Not enough disk space for %s: %s required, %s available", paths,
+				formatGiB(filesystem.required), formatGiB(filesystem.free))
+		}
+		osmoChan <- fmt.Sprintf("Disk space for %s: %s required, %s available", paths,
+			formatGiB(filesystem.required), formatGiB(filesystem.free))
+	}
+	return nil
+}
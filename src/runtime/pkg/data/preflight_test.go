@@ -0,0 +1,123 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
+)
+
+// ---------------------------------------------------------------------------
+// QueryInputSize — sizes task and url inputs with the OSMO data CLI
+// ---------------------------------------------------------------------------
+
+func TestQueryInputSize_ParsesSizeOfUrlInput(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	argsFile := filepath.Join(t.TempDir(), "args")
+	body := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\necho '{\"count\": 3, \"size\": 4096}'\n",
+		argsFile)
+	t.Setenv("PATH", stageFakeOsmo(t, body)+":"+os.Getenv("PATH"))
+
+	size, known, err := QueryInputSize(context.Background(),
+		UrlInput{Folder: "in", Url: "s3://bucket/data", Regex: ".*\\.bin"}, make(chan string, 16))
+
+	if err != nil || !known || size != 4096 {
+		t.Fatalf("QueryInputSize = %d, %v, %v; want 4096, true, nil", size, known, err)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("read args: %v", err)
+	}
+	if want := "data size s3://bucket/data --regex .*\\.bin\n"; string(args) != want {
+		t.Errorf("args = %q, want %q", string(args), want)
+	}
+}
+
+func TestQueryInputSize_UnknownForGitInput(t *testing.T) {
+	size, known, err := QueryInputSize(context.Background(),
+		GitInput{Folder: "repo", Url: "https://example.com/repo.git"}, make(chan string, 16))
+
+	if err != nil || known || size != 0 {
+		t.Errorf("QueryInputSize = %d, %v, %v; want an unknown size", size, known, err)
+	}
+}
+
+func TestQueryInputSize_FailsOnInvalidOutput(t *testing.T) {
+	WebsocketConnection = WebsocketConnectionInfo{}
+	t.Setenv("PATH", stageFakeOsmo(t, "#!/bin/sh\necho not-json\n")+":"+os.Getenv("PATH"))
+
+	_, _, err := QueryInputSize(context.Background(),
+		TaskInput{Folder: "in", Name: "up", Url: "s3://bucket/up"}, make(chan string, 16))
+
+	if code := osmo_errors.CodeOf(err); err == nil || code != osmo_errors.PREFLIGHT_FAILED_CODE {
+		t.Errorf("error = %v (exit code %d), want a preflight failure", err, code)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// CheckDiskSpace — compares the requirements with the free space per filesystem
+// ---------------------------------------------------------------------------
+
+func TestCheckDiskSpace_PassesWithinFreeSpace(t *testing.T) {
+	osmoChan := make(chan string, 16)
+	root := t.TempDir()
+
+	err := CheckDiskSpace([]DiskRequirement{
+		{Path: filepath.Join(root, "inputs"), Bytes: 1},
+		{Path: filepath.Join(root, "missing", "outputs"), Bytes: 1},
+	}, osmoChan)
+
+	if err != nil {
+		t.Fatalf("CheckDiskSpace: %v", err)
+	}
+	// Both paths share the filesystem of the temporary directory
+	messages := drainChannel(osmoChan)
+	if len(messages) != 1 || !strings.Contains(messages[0], "inputs") ||
+		!strings.Contains(messages[0], "outputs") {
+		t.Errorf("messages = %v, want one report for the shared filesystem", messages)
+	}
+}
+
+func TestCheckDiskSpace_SumsRequirementsOfSharedFilesystem(t *testing.T) {
+	root := t.TempDir()
+	_, free, err := statFilesystem(root)
+	if err != nil {
+		t.Fatalf("statFilesystem: %v", err)
+	}
+
+	// Each requirement fits on its own, but not combined
+	half := free/2 + 1
+	err = CheckDiskSpace([]DiskRequirement{
+		{Path: filepath.Join(root, "inputs"), Bytes: half},
+		{Path: filepath.Join(root, "outputs"), Bytes: half},
+	}, make(chan string, 16))
+
+	if code := osmo_errors.CodeOf(err); err == nil || code != osmo_errors.PREFLIGHT_FAILED_CODE {
+		t.Fatalf("error = %v (exit code %d), want a preflight failure", err, code)
+	}
+	if !strings.Contains(err.Error(), "Not enough disk space") {
+		t.Errorf("error = %v, want a disk space message", err)
+	}
+}
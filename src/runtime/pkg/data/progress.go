@@ -0,0 +1,177 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+)
+
+// Time between progress metrics of a transfer. Zero disables them.
+var ProgressInterval time.Duration
+
+const ProgressSuffix string = "_progress.json"
+
+// Weight of the latest sample in the average throughput
+const progressSmoothing float64 = 0.3
+
+// TransferProgress is periodically written by the OSMO data CLI with --progress-out
+type TransferProgress struct {
+	// Keep the follow fields in sync with osmo/lib/data/storage/core/progress.py
+	BytesDone  int64 `json:"bytes_done"`
+	BytesTotal int64 `json:"bytes_total"`
+	FilesDone  int   `json:"files_done"`
+	FilesTotal int   `json:"files_total"`
+}
+
+// ProgressFilePath returns the progress file of the transfer with the benchmark folder
+func ProgressFilePath(benchmarkFolderName string) string {
+	return BenchmarkPath + benchmarkFolderName + ProgressSuffix
+}
+
+// progressArgs returns the arguments of the OSMO data CLI to write the progress file
+func progressArgs(benchmarkFolderName string) []string {
+	if ProgressInterval <= 0 {
+		return nil
+	}
+	return []string{"--progress-out", ProgressFilePath(benchmarkFolderName)}
+}
+
+func ReadTransferProgress(path string) (TransferProgress, error) {
+	var progress TransferProgress
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return progress, err
+	}
+	err = json.Unmarshal(content, &progress)
+	return progress, err
+}
+
+// progressEstimator averages the throughput of a transfer to estimate its remaining time
+type progressEstimator struct {
+	lastTime   time.Time
+	lastBytes  int64
+	throughput float64
+	hasSample  bool
+}
+
+func newProgressEstimator(start time.Time) *progressEstimator {
+	return &progressEstimator{lastTime: start}
+}
+
+// update returns the throughput in bytes per second and the remaining seconds, which are
+// negative while unknown
+func (e *progressEstimator) update(progress TransferProgress, now time.Time) (float64, float64) {
+	elapsed := now.Sub(e.lastTime).Seconds()
+	if elapsed > 0 {
+		// Fewer bytes than before means the transfer was restarted by a retry
+		transferred := max(progress.BytesDone-e.lastBytes, 0)
+		rate := float64(transferred) / elapsed
+		if e.hasSample {
+			e.throughput = progressSmoothing*rate + (1-progressSmoothing)*e.throughput
+		} else {
+			e.throughput = rate
+			e.hasSample = true
+		}
+		e.lastTime = now
+		e.lastBytes = progress.BytesDone
+	}
+
+	eta := -1.0
+	remaining := progress.BytesTotal - progress.BytesDone
+	if progress.BytesTotal > 0 && remaining <= 0 {
+		eta = 0
+	} else if progress.BytesTotal > 0 && e.throughput > 0 {
+		eta = float64(remaining) / e.throughput
+	}
+	return e.throughput, eta
+}
+
+// TransferProgressReporter sends the progress of an input or output as metrics
+type TransferProgressReporter struct {
+	MetricChan chan metrics.Metric
+	RetryId    string
+	GroupName  string
+	TaskName   string
+	URL        string
+	Type       string
+}
+
+// Watch sends the progress of the transfer with the benchmark folder at every ProgressInterval
+// until the returned function is called. It does nothing when ProgressInterval is zero.
+func (r TransferProgressReporter) Watch(benchmarkFolderName string) func() {
+	if ProgressInterval <= 0 || r.MetricChan == nil {
+		return func() {}
+	}
+	path := ProgressFilePath(benchmarkFolderName)
+	// Do not report the progress of an earlier transfer
+	os.Remove(path)
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		r.watch(path, ProgressInterval, stop)
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (r TransferProgressReporter) watch(path string, interval time.Duration, stop chan bool) {
+	estimator := newProgressEstimator(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			progress, err := ReadTransferProgress(path)
+			if err != nil {
+				// The transfer has not written its progress yet
+				continue
+			}
+			throughput, eta := estimator.update(progress, now)
+			metric := metrics.TaskIOProgressMetrics{
+				RetryId:    r.RetryId,
+				GroupName:  r.GroupName,
+				TaskName:   r.TaskName,
+				URL:        r.URL,
+				Type:       r.Type,
+				Time:       now.Format("2006-01-02 15:04:05.000"),
+				BytesDone:  progress.BytesDone,
+				BytesTotal: progress.BytesTotal,
+				FilesDone:  progress.FilesDone,
+				FilesTotal: progress.FilesTotal,
+				Throughput: throughput,
+				EtaSeconds: eta,
+			}
+			select {
+			case r.MetricChan <- metric:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/metrics"
+)
+
+func TestProgressEstimator_AveragesThroughputAndEstimatesRemainingTime(t *testing.T) {
+	start := time.Unix(0, 0)
+	estimator := newProgressEstimator(start)
+
+	throughput, eta := estimator.update(TransferProgress{BytesDone: 100, BytesTotal: 1000},
+		start.Add(time.Second))
+	if throughput != 100 || eta != 9 {
+		t.Fatalf("first sample = (%v, %v), want (100, 9)", throughput, eta)
+	}
+
+	throughput, _ = estimator.update(TransferProgress{BytesDone: 400, BytesTotal: 1000},
+		start.Add(2*time.Second))
+	if want := 0.3*300 + 0.7*100; throughput != want {
+		t.Errorf("throughput = %v, want %v", throughput, want)
+	}
+
+	_, eta = estimator.update(TransferProgress{BytesDone: 1000, BytesTotal: 1000},
+		start.Add(3*time.Second))
+	if eta != 0 {
+		t.Errorf("eta of a finished transfer = %v, want 0", eta)
+	}
+}
+
+func TestProgressEstimator_UnknownTimeWithoutTotalOrThroughput(t *testing.T) {
+	start := time.Unix(0, 0)
+	estimator := newProgressEstimator(start)
+
+	if _, eta := estimator.update(TransferProgress{BytesTotal: 1000},
+		start.Add(time.Second)); eta >= 0 {
+		t.Errorf("eta without throughput = %v, want negative", eta)
+	}
+	if _, eta := estimator.update(TransferProgress{BytesDone: 100},
+		start.Add(2*time.Second)); eta >= 0 {
+		t.Errorf("eta without total = %v, want negative", eta)
+	}
+}
+
+func TestProgressEstimator_RestartedTransferDoesNotGoNegative(t *testing.T) {
+	start := time.Unix(0, 0)
+	estimator := newProgressEstimator(start)
+	estimator.update(TransferProgress{BytesDone: 500, BytesTotal: 1000}, start.Add(time.Second))
+
+	throughput, _ := estimator.update(TransferProgress{BytesDone: 10, BytesTotal: 1000},
+		start.Add(2*time.Second))
+	if throughput < 0 {
+		t.Errorf("throughput = %v, want non-negative", throughput)
+	}
+}
+
+func TestProgressArgs_OnlyWhenEnabled(t *testing.T) {
+	redirectBenchmarkPath(t)
+	original := ProgressInterval
+	t.Cleanup(func() { ProgressInterval = original })
+
+	ProgressInterval = 0
+	if args := progressArgs("INPUT_0"); args != nil {
+		t.Errorf("progressArgs when disabled = %v, want nil", args)
+	}
+
+	ProgressInterval = time.Second
+	want := []string{"--progress-out", BenchmarkPath + "INPUT_0" + ProgressSuffix}
+	if args := progressArgs("INPUT_0"); !slices.Equal(args, want) {
+		t.Errorf("progressArgs = %v, want %v", args, want)
+	}
+}
+
+func TestTransferProgressReporter_SendsProgressOfFile(t *testing.T) {
+	redirectBenchmarkPath(t)
+	original := ProgressInterval
+	t.Cleanup(func() { ProgressInterval = original })
+	ProgressInterval = 10 * time.Millisecond
+
+	metricChan := make(chan metrics.Metric, 64)
+	reporter := TransferProgressReporter{
+		MetricChan: metricChan,
+		RetryId:    "0",
+		GroupName:  "group",
+		TaskName:   "task",
+		URL:        "s3://bucket/data",
+		Type:       "INPUT",
+	}
+	stop := reporter.Watch("INPUT_0")
+
+	content := `{"bytes_done": 50, "bytes_total": 200, "files_done": 1, "files_total": 4}`
+	if err := os.WriteFile(ProgressFilePath("INPUT_0"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write progress file: %v", err)
+	}
+
+	var progress metrics.TaskIOProgressMetrics
+	select {
+	case metric := <-metricChan:
+		progress = metric.(metrics.TaskIOProgressMetrics)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no progress metric was sent")
+	}
+	stop()
+
+	if progress.URL != "s3://bucket/data" || progress.Type != "INPUT" {
+		t.Errorf("metric = %+v, want URL and type of the reporter", progress)
+	}
+	if progress.BytesDone != 50 || progress.BytesTotal != 200 ||
+		progress.FilesDone != 1 || progress.FilesTotal != 4 {
+		t.Errorf("metric = %+v, want the progress of the file", progress)
+	}
+}
+
+func TestTransferProgressReporter_DisabledDoesNothing(t *testing.T) {
+	original := ProgressInterval
+	t.Cleanup(func() { ProgressInterval = original })
+	ProgressInterval = 0
+
+	metricChan := make(chan metrics.Metric, 1)
+	stop := TransferProgressReporter{MetricChan: metricChan}.Watch("INPUT_0")
+	stop()
+	if len(metricChan) != 0 {
+		t.Errorf("sent %d metrics while disabled", len(metricChan))
+	}
+}
@@ -0,0 +1,102 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+)
+
+// OutputQuota limits the combined size of the output folder and other monitored paths
+type OutputQuota struct {
+	Paths      []string
+	LimitBytes int64
+	// Percentages of the limit at which a warning is logged once
+	WarnPercents []int
+	Interval     time.Duration
+}
+
+type quotaState struct {
+	quota  OutputQuota
+	warned map[int]bool
+}
+
+func newQuotaState(quota OutputQuota) *quotaState {
+	percents := append([]int{}, quota.WarnPercents...)
+	sort.Ints(percents)
+	quota.WarnPercents = percents
+	return &quotaState{quota: quota, warned: map[int]bool{}}
+}
+
+func formatGiB(bytes int64) string {
+	return fmt.Sprintf("%.2f GiB", float64(bytes)/(1<<30))
+}
+
+// check returns the warnings for the thresholds newly crossed by size and whether the limit is
+// exceeded. Each threshold is only reported once.
+func (s *quotaState) check(size int64) ([]string, bool) {
+	var warnings []string
+	for _, percent := range s.quota.WarnPercents {
+		if s.warned[percent] || size*100 < s.quota.LimitBytes*int64(percent) {
+			continue
+		}
+		s.warned[percent] = true
+		warnings = append(warnings, fmt.Sprintf(
+			"Output size %s reached %d%% of the quota of %s",
+			formatGiB(size), percent, formatGiB(s.quota.LimitBytes)))
+	}
+	return warnings, size > s.quota.LimitBytes
+}
+
+func (s *quotaState) size() int64 {
+	var total int64
+	for _, path := range s.quota.Paths {
+		size, _ := common.CalculateFolderSize(path)
+		total += size
+	}
+	return total
+}
+
+// WatchOutputQuota checks the size of the quota paths at every interval and logs a warning at
+// each threshold. It returns true as soon as the limit is exceeded, or false once stop is closed.
+func WatchOutputQuota(quota OutputQuota, osmoChan chan string, stop chan bool) bool {
+	state := newQuotaState(quota)
+	ticker := time.NewTicker(quota.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+			size := state.size()
+			warnings, exceeded := state.check(size)
+			for _, warning := range warnings {
+				osmoChan <- warning
+			}
+			if exceeded {
+				osmoChan <- fmt.Sprintf("Output size %s exceeded the quota of %s",
+					formatGiB(size), formatGiB(quota.LimitBytes))
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Output quota
+// ---------------------------------------------------------------------------
+
+func TestQuotaState_WarnsOncePerThreshold(t *testing.T) {
+	state := newQuotaState(OutputQuota{LimitBytes: 1000, WarnPercents: []int{90, 50}})
+
+	if warnings, exceeded := state.check(400); len(warnings) != 0 || exceeded {
+		t.Fatalf("check(400) = %v, %v; want no warnings", warnings, exceeded)
+	}
+	warnings, exceeded := state.check(950)
+	if len(warnings) != 2 || exceeded {
+		t.Fatalf("check(950) = %v, %v; want both thresholds", warnings, exceeded)
+	}
+	if !strings.Contains(warnings[0], "50%") || !strings.Contains(warnings[1], "90%") {
+		t.Errorf("warnings should be in threshold order: %v", warnings)
+	}
+	if warnings, _ := state.check(960); len(warnings) != 0 {
+		t.Errorf("thresholds should only be reported once, got %v", warnings)
+	}
+	if _, exceeded := state.check(1001); !exceeded {
+		t.Error("check(1001) should exceed the limit of 1000")
+	}
+}
+
+func TestWatchOutputQuota_ReturnsWhenExceeded(t *testing.T) {
+	output := t.TempDir()
+	checkpoint := t.TempDir()
+	writeTree(t, output, map[string]string{"a.bin": strings.Repeat("x", 60)})
+	writeTree(t, checkpoint, map[string]string{"ckpt/b.bin": strings.Repeat("x", 60)})
+
+	osmoChan := make(chan string, 16)
+	quota := OutputQuota{Paths: []string{output, filepath.Join(checkpoint, "ckpt")},
+		LimitBytes: 100, WarnPercents: []int{50}, Interval: 10 * time.Millisecond}
+	if !WatchOutputQuota(quota, osmoChan, make(chan bool)) {
+		t.Fatal("expected the combined size of 120 bytes to exceed the quota")
+	}
+	close(osmoChan)
+	var logs []string
+	for message := range osmoChan {
+		logs = append(logs, message)
+	}
+	if len(logs) != 2 || !strings.Contains(logs[1], "exceeded the quota") {
+		t.Errorf("unexpected logs %v", logs)
+	}
+}
+
+func TestWatchOutputQuota_StopsWithoutExceeding(t *testing.T) {
+	stop := make(chan bool)
+	close(stop)
+	quota := OutputQuota{Paths: []string{t.TempDir()}, LimitBytes: 100, Interval: time.Hour}
+	if WatchOutputQuota(quota, make(chan string), stop) {
+		t.Error("expected false after stop")
+	}
+}
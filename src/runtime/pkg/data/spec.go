@@ -0,0 +1,277 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DataSpecVersion is the version of the JSON spec format for inputs, outputs and checkpoints.
+// A JSON spec is passed in place of the legacy `<type>:<fields>` string, e.g.
+// {"version":1,"type":"url","folder":"0","url":"s3://bucket/data","regex":".*\\.json"}
+const DataSpecVersion int = 1
+
+type InputOutputSpec struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+	Folder  string `json:"folder,omitempty"`
+	Url     string `json:"url"`
+	Regex   string `json:"regex,omitempty"`
+	Path    string `json:"path,omitempty"`
+	// Only for git inputs
+	Ref         string   `json:"ref,omitempty"`
+	Submodules  bool     `json:"submodules,omitempty"`
+	SparsePaths []string `json:"sparse_paths,omitempty"`
+}
+
+type CheckpointSpecEntry struct {
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	Url     string `json:"url"`
+	// Seconds between the end of one round and the start of the next
+	Frequency  int    `json:"frequency"`
+	Regex      string `json:"regex,omitempty"`
+	Versioning string `json:"versioning,omitempty"`
+	Keep       int    `json:"keep,omitempty"`
+}
+
+func isJsonSpec(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "{")
+}
+
+// decodeSpec strictly decodes a JSON spec and checks its version
+func decodeSpec(value string, spec any, version *int) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(spec); err != nil {
+		return fmt.Errorf("invalid JSON spec: %w", err)
+	}
+	if *version != DataSpecVersion {
+		return fmt.Errorf("unsupported spec version %d, expected %d", *version, DataSpecVersion)
+	}
+	return nil
+}
+
+// parseLegacyInputSpec parses `task:<folder>,<url>,<regex>`, `url:<folder>,<url>,<regex>` and
+// `git:<folder>,<repo>,<ref>`. Folders and urls cannot contain commas, the regex may.
+func parseLegacyInputSpec(value string) (InputOutputSpec, error) {
+	specType, fields, found := strings.Cut(value, ":")
+	if !found {
+		return InputOutputSpec{}, fmt.Errorf("missing type prefix")
+	}
+	lineDetails := strings.SplitN(fields, ",", 3)
+	if len(lineDetails) != 3 {
+		return InputOutputSpec{}, fmt.Errorf("expected %s:<folder>,<url>,<regex>", specType)
+	}
+	if specType == "git" {
+		return InputOutputSpec{Version: DataSpecVersion, Type: specType, Folder: lineDetails[0],
+			Url: lineDetails[1], Ref: lineDetails[2]}, nil
+	}
+	return InputOutputSpec{Version: DataSpecVersion, Type: specType, Folder: lineDetails[0],
+		Url: lineDetails[1], Regex: lineDetails[2]}, nil
+}
+
+// parseLegacyOutputSpec parses `task:<url>`, `url:<url>,<regex>` and `kpi:<url>,<path>`.
+// Task urls may contain commas, the other urls cannot.
+func parseLegacyOutputSpec(value string) (InputOutputSpec, error) {
+	specType, fields, found := strings.Cut(value, ":")
+	if !found {
+		return InputOutputSpec{}, fmt.Errorf("missing type prefix")
+	}
+	spec := InputOutputSpec{Version: DataSpecVersion, Type: specType}
+	switch specType {
+	case "task":
+		spec.Url = fields
+	case "url":
+		spec.Url, spec.Regex, _ = strings.Cut(fields, ",")
+	case "kpi":
+		var found bool
+		spec.Url, spec.Path, found = strings.Cut(fields, ",")
+		if !found {
+			return spec, fmt.Errorf("expected kpi:<url>,<path>")
+		}
+	}
+	return spec, nil
+}
+
+// validateRelativePath reports a path that would escape its parent directory
+func validateRelativePath(field string, path string) []string {
+	if path == "" {
+		return []string{fmt.Sprintf("%s is required", field)}
+	}
+	cleaned := filepath.Clean(path)
+	if filepath.IsAbs(path) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return []string{fmt.Sprintf("%s %q must be a relative path inside the task", field, path)}
+	}
+	return nil
+}
+
+func validateUrl(url string) []string {
+	if url == "" {
+		return []string{"url is required"}
+	}
+	return nil
+}
+
+// validateInput returns every problem of an input spec. Regexes are evaluated by the data CLI and
+// are therefore not checked here.
+func (s InputOutputSpec) validateInput() []string {
+	var problems []string
+	switch s.Type {
+	case "task", "url":
+		problems = append(problems, s.validateNotGit()...)
+	case "git":
+		if s.Regex != "" {
+			problems = append(problems, "regex is not supported for git inputs")
+		}
+		for _, path := range s.SparsePaths {
+			problems = append(problems, validateRelativePath("sparse path", path)...)
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown input type %q", s.Type))
+	}
+	problems = append(problems, validateRelativePath("folder", s.Folder)...)
+	problems = append(problems, validateUrl(s.Url)...)
+	if s.Path != "" {
+		problems = append(problems, "path is not supported for inputs")
+	}
+	return problems
+}
+
+func (s InputOutputSpec) validateOutput() []string {
+	var problems []string
+	switch s.Type {
+	case "task", "url":
+		if s.Path != "" {
+			problems = append(problems, fmt.Sprintf("path is not supported for %s outputs", s.Type))
+		}
+	case "kpi":
+		problems = append(problems, validateRelativePath("path", s.Path)...)
+	default:
+		problems = append(problems, fmt.Sprintf("unknown output type %q", s.Type))
+	}
+	if s.Folder != "" {
+		problems = append(problems, "folder is not supported for outputs")
+	}
+	if s.Type == "task" && s.Regex != "" {
+		problems = append(problems, "regex is not supported for task outputs")
+	}
+	problems = append(problems, validateUrl(s.Url)...)
+	problems = append(problems, s.validateNotGit()...)
+	return problems
+}
+
+// validateNotGit reports git options set on a spec that is not a git input
+func (s InputOutputSpec) validateNotGit() []string {
+	var problems []string
+	if s.Ref != "" {
+		problems = append(problems, fmt.Sprintf("ref is not supported for %s", s.Type))
+	}
+	if s.Submodules {
+		problems = append(problems, fmt.Sprintf("submodules is not supported for %s", s.Type))
+	}
+	if len(s.SparsePaths) > 0 {
+		problems = append(problems, fmt.Sprintf("sparse_paths is not supported for %s", s.Type))
+	}
+	return problems
+}
+
+func (s InputOutputSpec) input() InputOutput {
+	switch s.Type {
+	case "task":
+		return TaskInput{s.Folder, s.Url[strings.LastIndex(s.Url, "/")+1:], s.Url, s.Regex}
+	case "git":
+		return GitInput{s.Folder, s.Url, s.Ref, s.Submodules, s.SparsePaths}
+	}
+	return UrlInput{s.Folder, s.Url, s.Regex}
+}
+
+func (s InputOutputSpec) output() InputOutput {
+	switch s.Type {
+	case "task":
+		return &TaskOutput{s.Url[strings.LastIndex(s.Url, "/")+1:], s.Url}
+	case "kpi":
+		return &KpiOutput{s.Url, s.Path}
+	}
+	return &UrlOutput{s.Url, s.Regex}
+}
+
+// ParseInput parses a legacy or JSON input spec and returns every problem found
+func ParseInput(value string) (InputOutput, []string) {
+	var spec InputOutputSpec
+	var err error
+	if isJsonSpec(value) {
+		err = decodeSpec(value, &spec, &spec.Version)
+	} else {
+		spec, err = parseLegacyInputSpec(value)
+	}
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	if problems := spec.validateInput(); len(problems) > 0 {
+		return nil, problems
+	}
+	return spec.input(), nil
+}
+
+// ParseOutput parses a legacy or JSON output spec and returns every problem found
+func ParseOutput(value string) (InputOutput, []string) {
+	var spec InputOutputSpec
+	var err error
+	if isJsonSpec(value) {
+		err = decodeSpec(value, &spec, &spec.Version)
+	} else {
+		spec, err = parseLegacyOutputSpec(value)
+	}
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	if problems := spec.validateOutput(); len(problems) > 0 {
+		return nil, problems
+	}
+	return spec.output(), nil
+}
+
+// ParseInputsOutputs parses all inputs and outputs of a task. Instead of stopping at the first
+// invalid spec, it returns a description of every problem so they can be reported at once.
+func ParseInputsOutputs(inputs []string, outputs []string) (
+	[]InputOutput, []InputOutput, []string) {
+
+	var parsedInputs, parsedOutputs []InputOutput
+	var problems []string
+	for i, value := range inputs {
+		input, inputProblems := ParseInput(value)
+		for _, problem := range inputProblems {
+			problems = append(problems, fmt.Sprintf("input %d (%s): %s", i, value, problem))
+		}
+		parsedInputs = append(parsedInputs, input)
+	}
+	for i, value := range outputs {
+		output, outputProblems := ParseOutput(value)
+		for _, problem := range outputProblems {
+			problems = append(problems, fmt.Sprintf("output %d (%s): %s", i, value, problem))
+		}
+		parsedOutputs = append(parsedOutputs, output)
+	}
+	return parsedInputs, parsedOutputs, problems
+}
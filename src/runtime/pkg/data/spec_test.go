@@ -0,0 +1,170 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// ParseInput / ParseOutput — legacy syntax with commas
+// ---------------------------------------------------------------------------
+
+func TestParseInput_LegacyRegexMayContainCommas(t *testing.T) {
+	got, problems := ParseInput("url:0,s3://bucket/data,.*\\.(json|yaml){1,2}")
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+	want := UrlInput{Folder: "0", Url: "s3://bucket/data", Regex: ".*\\.(json|yaml){1,2}"}
+	if got != want {
+		t.Errorf("ParseInput = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseOutput_LegacyUrlOutputRegexWithCommasStaysOutput(t *testing.T) {
+	// ParseInputOutput cannot tell this apart from an input since it has three comma fields
+	got, problems := ParseOutput("url:s3://bucket/out,a{1,2}")
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+	urlOutput, ok := got.(*UrlOutput)
+	if !ok || urlOutput.Url != "s3://bucket/out" || urlOutput.Regex != "a{1,2}" {
+		t.Errorf("ParseOutput = %#v, want url output with regex a{1,2}", got)
+	}
+}
+
+func TestParseOutput_LegacyTaskUrlMayContainCommas(t *testing.T) {
+	got, problems := ParseOutput("task:s3://bucket/wf,1/task")
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+	taskOutput, ok := got.(*TaskOutput)
+	if !ok || taskOutput.Url != "s3://bucket/wf,1/task" || taskOutput.Name != "task" {
+		t.Errorf("ParseOutput = %#v", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ParseInput / ParseOutput — JSON specs
+// ---------------------------------------------------------------------------
+
+func TestParseInput_JsonSpecAllowsCommasEverywhere(t *testing.T) {
+	got, problems := ParseInput(
+		`{"version":1,"type":"task","folder":"0","url":"s3://b/a,b/up","regex":"x{1,2}"}`)
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+	want := TaskInput{Folder: "0", Name: "up", Url: "s3://b/a,b/up", Regex: "x{1,2}"}
+	if got != want {
+		t.Errorf("ParseInput = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseOutput_JsonKpiSpec(t *testing.T) {
+	got, problems := ParseOutput(`{"version":1,"type":"kpi","url":"s3://b/t","path":"r/m.json"}`)
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+	if kpi, ok := got.(*KpiOutput); !ok || kpi.Url != "s3://b/t" || kpi.Path != "r/m.json" {
+		t.Errorf("ParseOutput = %#v", got)
+	}
+}
+
+func TestParseInput_JsonSpecRejectsUnknownVersionAndFields(t *testing.T) {
+	cases := []string{
+		`{"version":2,"type":"url","folder":"0","url":"s3://b/d"}`,
+		`{"version":1,"type":"url","folder":"0","url":"s3://b/d","extra":true}`,
+		`{"version":1,"type":"url"`,
+	}
+	for _, value := range cases {
+		if _, problems := ParseInput(value); len(problems) == 0 {
+			t.Errorf("expected problems for %s", value)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ParseInputsOutputs — all problems reported together
+// ---------------------------------------------------------------------------
+
+func TestParseInputsOutputs_ReportsEveryProblem(t *testing.T) {
+	inputs := []string{
+		"url:0,s3://bucket/data,",
+		"url:../escape,s3://bucket/data,",
+		`{"version":1,"type":"svn","folder":"1","url":""}`,
+	}
+	outputs := []string{
+		"kpi:s3://bucket/kpi",
+		"bogus:s3://bucket/out",
+		"url:s3://bucket/out,",
+	}
+
+	parsedInputs, parsedOutputs, problems := ParseInputsOutputs(inputs, outputs)
+	if len(parsedInputs) != 3 || len(parsedOutputs) != 3 {
+		t.Fatalf("expected one entry per spec, got %d inputs and %d outputs",
+			len(parsedInputs), len(parsedOutputs))
+	}
+
+	joined := strings.Join(problems, "\n")
+	for _, want := range []string{
+		"input 1 (url:../escape,s3://bucket/data,): folder \"../escape\" must be a relative path",
+		"input 2", "unknown input type \"svn\"", "url is required",
+		"output 0 (kpi:s3://bucket/kpi): expected kpi:<url>,<path>",
+		"output 1 (bogus:s3://bucket/out): unknown output type \"bogus\"",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("problems are missing %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "input 0") || strings.Contains(joined, "output 2") {
+		t.Errorf("valid specs reported as problems:\n%s", joined)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ValidateCheckpoint — legacy and JSON specs
+// ---------------------------------------------------------------------------
+
+func TestValidateCheckpoint_ReportsAllProblems(t *testing.T) {
+	if problems := ValidateCheckpoint("/ckpt;s3://bucket/ckpt;30;"); len(problems) != 0 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+	if problems := ValidateCheckpoint("/ckpt;s3://bucket/ckpt"); len(problems) != 1 {
+		t.Errorf("expected a single problem for a short spec, got %v", problems)
+	}
+
+	problems := ValidateCheckpoint(
+		`{"version":1,"path":"","url":"","frequency":-1,"versioning":"daily","keep":-2}`)
+	if len(problems) != 5 {
+		t.Errorf("expected 5 problems, got %v", problems)
+	}
+}
+
+func TestParseCheckpointInfo_JsonSpec(t *testing.T) {
+	spec, err := parseCheckpointInfo(`{"version":1,"path":"/ckpt","url":"s3://b/c;d",` +
+		`"frequency":60,"regex":"a;b","versioning":"timestamp","keep":2}`)
+	if err != nil {
+		t.Fatalf("parseCheckpointInfo: %v", err)
+	}
+	if spec.url != "s3://b/c;d" || spec.regex != "a;b" || spec.frequency.Seconds() != 60 ||
+		spec.versioning != CheckpointVersioningTimestamp || spec.keep != 2 {
+		t.Errorf("spec = %#v", spec)
+	}
+}
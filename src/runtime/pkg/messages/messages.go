@@ -22,10 +22,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
 	"go.corp.nvidia.com/osmo/runtime/pkg/osmo_errors"
 )
 
@@ -43,8 +42,14 @@ const (
 	UserExecStart    RequestType = "UserExecStart"
 	UserStop         RequestType = "UserStop"         // Ctrl requests User to stop its process
 	UserStopFinished RequestType = "UserStopFinished" // User confirms to Ctrl its process is killed
+	UserKill         RequestType = "UserKill"         // Ctrl requests User to end its process for good
 	UserStart        RequestType = "UserStart"
 	UserRsyncStatus  RequestType = "UserRsyncStatus"
+	UserCheckpoint   RequestType = "UserCheckpoint" // User reports a finished checkpoint round to Ctrl
+	UserResources    RequestType = "UserResources"  // User reports a resource usage sample to Ctrl
+	Hello            RequestType = "Hello"          // Handshake of the protocol version
+	UserRestart      RequestType = "UserRestart"    // User reports a local restart of its process
+	UserReadiness    RequestType = "UserReadiness"  // User reports whether its process is ready
 )
 
 const (
@@ -55,6 +60,8 @@ const (
 	Upload   IOType = "UPLOAD"
 	LogDone  IOType = "LOG_DONE"
 	Barrier  IOType = "BARRIER"
+	// Readiness of the user command reported by its readiness probe
+	Readiness IOType = "READINESS"
 )
 
 /////////////////////////////////////////////////////
@@ -71,12 +78,38 @@ type Request struct {
 	Command       string
 	TaskPort      int
 	RsyncRunning  bool
+	Checkpoint    *CheckpointRound      `json:",omitempty"`
+	ResourceUsage *common.ResourceUsage `json:",omitempty"`
+	// Retry policy of the checkpoint uploads done by osmo-user
+	CheckpointRetry *common.RetryPolicy `json:",omitempty"`
+	Hello           *HelloMessage       `json:",omitempty"`
+	Restart         *CommandRestart     `json:",omitempty"`
+	Ready           bool                `json:",omitempty"`
+	// Process of osmo-user which wrote the message, empty for the user command
+	Tag string `json:",omitempty"`
 }
 
-func ExecStartRequest(outputFolder string) Request {
+// CommandRestart is a restart of the user command by the restart policy of osmo-user
+type CommandRestart struct {
+	Time     time.Time
+	Restart  int
+	ExitCode int
+	Backoff  time.Duration
+}
+
+type CheckpointRound struct {
+	Url           string
+	StartTime     time.Time
+	EndTime       time.Time
+	SizeInBytes   int64
+	NumberOfFiles int
+}
+
+func ExecStartRequest(outputFolder string, checkpointRetry common.RetryPolicy) Request {
 	return Request{
-		Type:         ExecStart,
-		OutputFolder: outputFolder,
+		Type:            ExecStart,
+		OutputFolder:    outputFolder,
+		CheckpointRetry: &checkpointRetry,
 	}
 }
 
@@ -134,9 +167,41 @@ func UserRsyncStatusRequest(rsyncRunning bool) Request {
 	}
 }
 
-func EncodeMessage(unixConn net.Conn, message string, requestMessage Request) {
+func UserCheckpointRequest(round CheckpointRound) Request {
+	return Request{
+		Type:       UserCheckpoint,
+		Checkpoint: &round,
+	}
+}
+
+func UserResourcesRequest(usage common.ResourceUsage) Request {
+	return Request{
+		Type:          UserResources,
+		ResourceUsage: &usage,
+	}
+}
+
+// UserRestartRequest reports the restart to ctrl, which logs the message
+func UserRestartRequest(message string, restart CommandRestart) Request {
+	return Request{
+		Type:       UserRestart,
+		MessageOps: message,
+		Restart:    &restart,
+	}
+}
+
+// UserReadinessRequest reports the readiness to ctrl, which logs the message
+func UserReadinessRequest(message string, ready bool) Request {
+	return Request{
+		Type:       UserReadiness,
+		MessageOps: message,
+		Ready:      ready,
+	}
+}
+
+func EncodeMessage(userConn *Conn, message string, requestMessage Request) {
 	log.Println(message)
-	err := json.NewEncoder(unixConn).Encode(requestMessage)
+	err := userConn.Send(requestMessage)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to send request: %v", err))
 	}
@@ -151,6 +216,9 @@ type LogRequest struct {
 	Time   time.Time
 	Text   string
 	IOType IOType
+	// Set when the line is a structured log
+	Level  string            `json:",omitempty"`
+	Fields map[string]string `json:",omitempty"`
 }
 
 type LogDoneRequest struct {
@@ -163,9 +231,20 @@ type BarrierRequest struct {
 	IOType IOType
 }
 
+type ReadinessRequest struct {
+	Ready  bool
+	IOType IOType
+}
+
 func CreateLog(source string, text string, ioType IOType) string {
+	return CreateStructuredLog(source, text, ioType, "", nil)
+}
+
+// CreateStructuredLog creates a log with the level and fields parsed from a structured line
+func CreateStructuredLog(source string, text string, ioType IOType, level string,
+	fields map[string]string) string {
 	currTime := time.Now().UTC()
-	logRequest := LogRequest{source, currTime, text, ioType}
+	logRequest := LogRequest{source, currTime, text, ioType, level, fields}
 	logJson, err := json.Marshal(logRequest)
 	if err != nil {
 		osmo_errors.SetExitCode(osmo_errors.WEBSOCKET_MESSAGE_FAILED_CODE)
@@ -194,7 +273,22 @@ func CreateBarrier(name string, count int) string {
 	return string(requestJson)
 }
 
-func Put(conn *websocket.Conn, message string) error {
+func CreateReadiness(ready bool) string {
+	readinessRequest := ReadinessRequest{ready, Readiness}
+	requestJson, err := json.Marshal(readinessRequest)
+	if err != nil {
+		osmo_errors.SetExitCode(osmo_errors.WEBSOCKET_MESSAGE_FAILED_CODE)
+		panic(err)
+	}
+	return string(requestJson)
+}
+
+// JSONWriter is a connection to the service, either a websocket or the local service
+type JSONWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+func Put(conn JSONWriter, message string) error {
 	err := conn.WriteJSON(message)
 	if err != nil {
 		return err
@@ -219,3 +313,9 @@ func UserStartRequest() Request {
 		Type: UserStart,
 	}
 }
+
+func UserKillRequest() Request {
+	return Request{
+		Type: UserKill,
+	}
+}
@@ -0,0 +1,580 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package messages
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Versions of the protocol between osmo-ctrl and osmo-user. Version 1 sends newline delimited
+// requests without a handshake. Version 2 sends framed envelopes after the handshake.
+const (
+	LegacyProtocolVersion = 1
+	ProtocolVersion       = 2
+)
+
+// Optional features of the protocol, enabled when both peers announce them in the handshake
+const (
+	// The user reconnects after the connection breaks and the lost envelopes are resent
+	CapabilityResume = "resume"
+)
+
+var supportedCapabilities = []string{CapabilityResume}
+
+// Time for the peer to answer the hello
+const HandshakeTimeout = 5 * time.Second
+
+// Number of sent envelopes kept to be resent when the connection is resumed
+const maxResentEnvelopes = 4096
+
+// Frames larger than this are rejected instead of allocated
+const maxFrameSize = 16 * 1024 * 1024
+
+type ConnRole string
+
+const (
+	ControlRole ConnRole = "control" // Connection of the requests between ctrl and user
+	ExecRole    ConnRole = "exec"    // Connection of an exec session
+)
+
+// HelloMessage is sent as a newline delimited request before the connection switches to
+// envelopes, so that a version 1 peer ignores it as an unexpected request type
+type HelloMessage struct {
+	Version      int
+	Capabilities []string
+	Session      string
+	Role         ConnRole
+	// Id of the last envelope received, so that the peer resends the later ones on resume
+	LastReceived uint64
+}
+
+// Envelope frames a request with its id. Replies refer to the id of their request. Requests
+// of an unknown type are ignored by the peer, so new types can be added without a new version.
+type Envelope struct {
+	Id      uint64
+	ReplyTo uint64 `json:",omitempty"`
+	Type    RequestType
+	Payload json.RawMessage `json:",omitempty"`
+}
+
+// Message is a received request with the id to reply to
+type Message struct {
+	Request
+	Id uint64
+}
+
+// Conn is the connection between osmo-ctrl and osmo-user for the negotiated protocol version.
+// Send is safe for concurrent use, Receive is called by a single reader.
+type Conn struct {
+	version      int
+	capabilities map[string]bool
+	session      string
+	// Time Receive waits for the user to reconnect, zero on the user side which redials
+	resumeTimeout time.Duration
+
+	writeMutex sync.Mutex // Held while writing to the connection
+
+	mutex        sync.Mutex
+	conn         net.Conn
+	reader       *bufio.Reader
+	decoder      *json.Decoder // Version 1 only
+	pending      *Request      // First version 1 request, read during the handshake
+	nextId       uint64
+	lastReceived uint64
+	sent         []Envelope
+	calls        map[uint64]chan Request      // Version 2 calls by request id
+	legacyCalls  map[RequestType]chan Request // Version 1 calls by reply type
+	resumed      chan struct{}                // Closed when the connection is resumed
+	interrupted  chan struct{}
+	interruptOne sync.Once
+}
+
+func newConn(version int, capabilities []string, session string, conn net.Conn,
+	reader *bufio.Reader) *Conn {
+	c := &Conn{
+		version:      version,
+		capabilities: make(map[string]bool),
+		session:      session,
+		conn:         conn,
+		reader:       reader,
+		calls:        make(map[uint64]chan Request),
+		legacyCalls:  make(map[RequestType]chan Request),
+		resumed:      make(chan struct{}),
+		interrupted:  make(chan struct{}),
+	}
+	for _, capability := range capabilities {
+		c.capabilities[capability] = true
+	}
+	if version == LegacyProtocolVersion {
+		c.decoder = json.NewDecoder(reader)
+	}
+	return c
+}
+
+// Accept performs the handshake of osmo-ctrl with a new connection of osmo-user. A user which
+// does not answer the hello within the timeout speaks version 1. Once the connection breaks,
+// Receive waits up to resumeTimeout for the user to reconnect.
+func Accept(conn net.Conn, timeout time.Duration, resumeTimeout time.Duration) (*Conn, error) {
+	session, err := newSession()
+	if err != nil {
+		return nil, err
+	}
+	hello := HelloMessage{
+		Version:      ProtocolVersion,
+		Capabilities: supportedCapabilities,
+		Session:      session,
+		Role:         ControlRole,
+	}
+	if err := writeHello(conn, hello); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	reply, err := readHello(conn, reader, timeout)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return newConn(LegacyProtocolVersion, nil, session, conn, reader), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if reply.Session != session {
+		return nil, fmt.Errorf("Unexpected session %s in the hello of the user", reply.Session)
+	}
+	version := min(ProtocolVersion, reply.Version)
+	c := newConn(version, commonCapabilities(reply.Capabilities), session, conn, reader)
+	c.resumeTimeout = resumeTimeout
+	return c, nil
+}
+
+// Connect performs the handshake of osmo-user with osmo-ctrl. An osmo-ctrl which starts with
+// another request speaks version 1, and the request is returned by the first Receive.
+func Connect(conn net.Conn) (*Conn, error) {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	// A version 1 ctrl sends its failure without a newline before it exits
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, err
+	}
+	var first Request
+	if err := json.Unmarshal(line, &first); err != nil {
+		return nil, err
+	}
+	if first.Type != Hello || first.Hello == nil {
+		c := newConn(LegacyProtocolVersion, nil, "", conn, reader)
+		c.pending = &first
+		return c, nil
+	}
+
+	version := min(ProtocolVersion, first.Hello.Version)
+	capabilities := commonCapabilities(first.Hello.Capabilities)
+	reply := HelloMessage{
+		Version:      version,
+		Capabilities: capabilities,
+		Session:      first.Hello.Session,
+		Role:         ControlRole,
+	}
+	if err := writeHello(conn, reply); err != nil {
+		return nil, err
+	}
+	return newConn(version, capabilities, first.Hello.Session, conn, reader), nil
+}
+
+// DialExec opens the connection of an exec session, which is introduced with a hello in
+// version 2 so that osmo-ctrl tells it apart from a reconnection
+func (c *Conn) DialExec(path string) (net.Conn, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil || c.version == LegacyProtocolVersion {
+		return conn, err
+	}
+	hello := HelloMessage{Version: c.version, Session: c.session, Role: ExecRole}
+	if err := writeHello(conn, hello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReadHello reads the hello of a new connection of osmo-user. The returned connection reads
+// the data buffered after the hello.
+func ReadHello(conn net.Conn, timeout time.Duration) (HelloMessage, net.Conn, error) {
+	reader := bufio.NewReader(conn)
+	hello, err := readHello(conn, reader, timeout)
+	return hello, &bufferedConn{Conn: conn, reader: reader}, err
+}
+
+func (c *Conn) Version() int {
+	return c.version
+}
+
+func (c *Conn) Session() string {
+	return c.session
+}
+
+func (c *Conn) HasCapability(capability string) bool {
+	return c.capabilities[capability]
+}
+
+// Send sends the request. Once the connection breaks, the envelopes of a resumable connection
+// are resent when it is resumed instead of failing.
+func (c *Conn) Send(request Request) error {
+	_, err := c.send(0, request, nil)
+	return err
+}
+
+// Reply sends the reply to the request with the id
+func (c *Conn) Reply(requestId uint64, reply Request) error {
+	_, err := c.send(requestId, reply, nil)
+	return err
+}
+
+// Call sends the request and waits for its reply. Version 1 has no request ids, so the reply is
+// the next request of the reply type.
+func (c *Conn) Call(ctx context.Context, request Request, replyType RequestType) (Request,
+	error) {
+	reply := make(chan Request, 1)
+	if c.version == LegacyProtocolVersion {
+		c.mutex.Lock()
+		c.legacyCalls[replyType] = reply
+		c.mutex.Unlock()
+		defer func() {
+			c.mutex.Lock()
+			delete(c.legacyCalls, replyType)
+			c.mutex.Unlock()
+		}()
+	}
+	id, err := c.send(0, request, reply)
+	if id != 0 {
+		// The reply is no longer awaited once the call fails or is canceled
+		defer func() {
+			c.mutex.Lock()
+			delete(c.calls, id)
+			c.mutex.Unlock()
+		}()
+	}
+	if err != nil {
+		return Request{}, err
+	}
+	select {
+	case response := <-reply:
+		return response, nil
+	case <-ctx.Done():
+		return Request{}, ctx.Err()
+	}
+}
+
+// send writes the request and returns the id of its envelope, which is 0 for version 1
+func (c *Conn) send(replyTo uint64, request Request, reply chan Request) (uint64, error) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if c.version == LegacyProtocolVersion {
+		c.mutex.Lock()
+		conn := c.conn
+		c.mutex.Unlock()
+		return 0, json.NewEncoder(conn).Encode(request)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+	c.mutex.Lock()
+	c.nextId++
+	envelope := Envelope{Id: c.nextId, ReplyTo: replyTo, Type: request.Type, Payload: payload}
+	if reply != nil {
+		c.calls[envelope.Id] = reply
+	}
+	if c.capabilities[CapabilityResume] {
+		c.sent = append(c.sent, envelope)
+		if len(c.sent) > maxResentEnvelopes {
+			c.sent = c.sent[len(c.sent)-maxResentEnvelopes:]
+		}
+	}
+	conn := c.conn
+	c.mutex.Unlock()
+
+	if err := writeFrame(conn, envelope); err != nil && !c.capabilities[CapabilityResume] {
+		return envelope.Id, err
+	}
+	return envelope.Id, nil
+}
+
+// Receive returns the next request which is not a reply to a call. On the ctrl side, a broken
+// resumable connection waits for the user to reconnect.
+func (c *Conn) Receive() (Message, error) {
+	for {
+		c.mutex.Lock()
+		if c.pending != nil {
+			request := *c.pending
+			c.pending = nil
+			c.mutex.Unlock()
+			return Message{Request: request}, nil
+		}
+		reader, decoder, resumed := c.reader, c.decoder, c.resumed
+		c.mutex.Unlock()
+
+		if c.version == LegacyProtocolVersion {
+			var request Request
+			if err := decoder.Decode(&request); err != nil {
+				return Message{}, err
+			}
+			c.mutex.Lock()
+			call, isCall := c.legacyCalls[request.Type]
+			delete(c.legacyCalls, request.Type)
+			c.mutex.Unlock()
+			if isCall {
+				call <- request
+				continue
+			}
+			return Message{Request: request}, nil
+		}
+
+		envelope, err := readFrame(reader)
+		if err != nil {
+			if c.resumeTimeout == 0 || !c.capabilities[CapabilityResume] {
+				return Message{}, err
+			}
+			if resumeErr := c.waitResume(resumed); resumeErr != nil {
+				return Message{}, fmt.Errorf("%w: %w", err, resumeErr)
+			}
+			continue
+		}
+
+		c.mutex.Lock()
+		if envelope.Id <= c.lastReceived {
+			// Resent after a reconnection although it was received
+			c.mutex.Unlock()
+			continue
+		}
+		c.lastReceived = envelope.Id
+		call, isCall := c.calls[envelope.ReplyTo]
+		delete(c.calls, envelope.ReplyTo)
+		c.mutex.Unlock()
+
+		var request Request
+		if len(envelope.Payload) > 0 {
+			if err := json.Unmarshal(envelope.Payload, &request); err != nil {
+				return Message{}, err
+			}
+		}
+		request.Type = envelope.Type
+		if envelope.ReplyTo != 0 && isCall {
+			call <- request
+			continue
+		}
+		return Message{Request: request, Id: envelope.Id}, nil
+	}
+}
+
+func (c *Conn) waitResume(resumed chan struct{}) error {
+	timer := time.NewTimer(c.resumeTimeout)
+	defer timer.Stop()
+	select {
+	case <-resumed:
+		return nil
+	case <-c.interrupted:
+		return errors.New("The connection was interrupted")
+	case <-timer.C:
+		return fmt.Errorf("The user did not reconnect within %v", c.resumeTimeout)
+	}
+}
+
+// Interrupt unblocks Receive without closing the connection, which can still be sent to
+func (c *Conn) Interrupt() {
+	c.interruptOne.Do(func() { close(c.interrupted) })
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conn.SetReadDeadline(time.Now())
+}
+
+// Resume replaces the connection with the reconnection of the user, which sent the hello
+func (c *Conn) Resume(conn net.Conn, hello HelloMessage) error {
+	if !c.capabilities[CapabilityResume] {
+		return errors.New("The connection cannot be resumed")
+	}
+	if hello.Session != c.session {
+		return fmt.Errorf("Unknown session %s", hello.Session)
+	}
+	c.mutex.Lock()
+	lastReceived := c.lastReceived
+	c.mutex.Unlock()
+	ack := HelloMessage{Version: c.version, Session: c.session, Role: ControlRole,
+		LastReceived: lastReceived}
+	if err := writeHello(conn, ack); err != nil {
+		return err
+	}
+	return c.replace(conn, bufio.NewReader(conn), hello.LastReceived)
+}
+
+// Redial reconnects to osmo-ctrl with the dial function and resends the envelopes which were
+// not received
+func (c *Conn) Redial(dial func() (net.Conn, error)) error {
+	if !c.capabilities[CapabilityResume] {
+		return errors.New("The connection cannot be resumed")
+	}
+	conn, err := dial()
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	lastReceived := c.lastReceived
+	c.mutex.Unlock()
+	hello := HelloMessage{Version: c.version, Session: c.session, Role: ControlRole,
+		LastReceived: lastReceived}
+	if err := writeHello(conn, hello); err != nil {
+		conn.Close()
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	ack, err := readHello(conn, reader, HandshakeTimeout)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return c.replace(conn, reader, ack.LastReceived)
+}
+
+// replace swaps in the new connection and resends the envelopes after the last one the peer
+// received
+func (c *Conn) replace(conn net.Conn, reader *bufio.Reader, peerReceived uint64) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.mutex.Lock()
+	previous := c.conn
+	c.conn = conn
+	c.reader = reader
+	var resend []Envelope
+	for _, envelope := range c.sent {
+		if envelope.Id > peerReceived {
+			resend = append(resend, envelope)
+		}
+	}
+	resumed := c.resumed
+	c.resumed = make(chan struct{})
+	c.mutex.Unlock()
+
+	previous.Close()
+	close(resumed)
+	for _, envelope := range resend {
+		if err := writeFrame(conn, envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Conn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn.Close()
+}
+
+func newSession() (string, error) {
+	session := make([]byte, 8)
+	if _, err := rand.Read(session); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(session), nil
+}
+
+func commonCapabilities(capabilities []string) []string {
+	var common []string
+	for _, capability := range capabilities {
+		for _, supported := range supportedCapabilities {
+			if capability == supported {
+				common = append(common, capability)
+			}
+		}
+	}
+	return common
+}
+
+func writeHello(conn net.Conn, hello HelloMessage) error {
+	return json.NewEncoder(conn).Encode(Request{Type: Hello, Hello: &hello})
+}
+
+func readHello(conn net.Conn, reader *bufio.Reader, timeout time.Duration) (HelloMessage,
+	error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := reader.ReadBytes('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return HelloMessage{}, err
+	}
+	var request Request
+	if err := json.Unmarshal(line, &request); err != nil {
+		return HelloMessage{}, err
+	}
+	if request.Type != Hello || request.Hello == nil {
+		return HelloMessage{}, fmt.Errorf("Expected a hello instead of %s", request.Type)
+	}
+	return *request.Hello, nil
+}
+
+// writeFrame writes the envelope after its length as a 4 byte big endian integer
+func writeFrame(conn net.Conn, envelope Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+	_, err = conn.Write(frame)
+	return err
+}
+
+func readFrame(reader *bufio.Reader) (Envelope, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return Envelope{}, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return Envelope{}, fmt.Errorf("Frame of %d bytes exceeds the maximum size", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return Envelope{}, err
+	}
+	var envelope Envelope
+	err := json.Unmarshal(body, &envelope)
+	return envelope, err
+}
+
+// bufferedConn reads the data buffered by the reader before the rest of the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
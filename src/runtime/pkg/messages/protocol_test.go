@@ -0,0 +1,353 @@
+/*
+SPDX-FileCopyrightText: Copyright (c) 2026 NVIDIA CORPORATION & AFFILIATES. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package messages
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.corp.nvidia.com/osmo/runtime/pkg/common"
+)
+
+// listenUnix returns a listener on a socket in a temporary directory
+func listenUnix(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "ctrl.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+func dialUnix(t *testing.T, listener net.Listener) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// connectPair performs the handshake of a ctrl and a user connection
+func connectPair(t *testing.T, listener net.Listener) (*Conn, *Conn) {
+	t.Helper()
+	userDone := make(chan *Conn)
+	go func() {
+		userConn, err := Connect(dialUnix(t, listener))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		userDone <- userConn
+	}()
+	serverConn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctrlConn, err := Accept(serverConn, time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	userConn := <-userDone
+	if userConn == nil {
+		t.FailNow()
+	}
+	return ctrlConn, userConn
+}
+
+func TestHandshake_NegotiatesVersionAndCapabilities(t *testing.T) {
+	ctrlConn, userConn := connectPair(t, listenUnix(t))
+
+	for _, conn := range []*Conn{ctrlConn, userConn} {
+		if conn.Version() != ProtocolVersion {
+			t.Errorf("expected version %d, got %d", ProtocolVersion, conn.Version())
+		}
+		if !conn.HasCapability(CapabilityResume) {
+			t.Errorf("expected the resume capability")
+		}
+	}
+	if ctrlConn.Session() == "" || ctrlConn.Session() != userConn.Session() {
+		t.Errorf("expected the same session, got %q and %q", ctrlConn.Session(),
+			userConn.Session())
+	}
+
+	if err := ctrlConn.Send(ExecStartRequest("/output", common.DefaultRetryPolicy())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := userConn.Receive()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if message.Type != ExecStart || message.OutputFolder != "/output" || message.Id == 0 {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
+
+func TestAccept_FallsBackToLegacyVersionWithoutHelloReply(t *testing.T) {
+	listener := listenUnix(t)
+	legacyUser := dialUnix(t, listener)
+	serverConn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctrlConn, err := Accept(serverConn, 50*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctrlConn.Version() != LegacyProtocolVersion {
+		t.Fatalf("expected version %d, got %d", LegacyProtocolVersion, ctrlConn.Version())
+	}
+
+	// A version 1 user ignores the hello and decodes the requests which follow it
+	if err := ctrlConn.Send(ExecFinishedRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoder := json.NewDecoder(legacyUser)
+	var hello, request Request
+	if err := decoder.Decode(&hello); err != nil || hello.Type != Hello {
+		t.Fatalf("expected a hello, got %+v (%v)", hello, err)
+	}
+	if err := decoder.Decode(&request); err != nil || request.Type != ExecFinished {
+		t.Errorf("expected %s, got %+v (%v)", ExecFinished, request, err)
+	}
+
+	// Requests of a version 1 user are decoded one after the other
+	encoder := json.NewEncoder(legacyUser)
+	encoder.Encode(MessageOutRequest("first"))
+	encoder.Encode(MessageOutRequest("second"))
+	for _, expected := range []string{"first", "second"} {
+		message, err := ctrlConn.Receive()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if message.MessageOut != expected {
+			t.Errorf("expected %q, got %q", expected, message.MessageOut)
+		}
+	}
+}
+
+func TestConnect_FallsBackToLegacyVersionWithoutHello(t *testing.T) {
+	listener := listenUnix(t)
+	userSide := dialUnix(t, listener)
+	legacyCtrl, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encoder := json.NewEncoder(legacyCtrl)
+	encoder.Encode(ExecStartRequest("/output", common.DefaultRetryPolicy()))
+	encoder.Encode(UserKillRequest())
+
+	userConn, err := Connect(userSide)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userConn.Version() != LegacyProtocolVersion {
+		t.Fatalf("expected version %d, got %d", LegacyProtocolVersion, userConn.Version())
+	}
+	for _, expected := range []RequestType{ExecStart, UserKill} {
+		message, err := userConn.Receive()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if message.Type != expected {
+			t.Errorf("expected %s, got %s", expected, message.Type)
+		}
+	}
+}
+
+func TestConnect_ReadsLegacyFailureWithoutNewline(t *testing.T) {
+	listener := listenUnix(t)
+	userSide := dialUnix(t, listener)
+	legacyCtrl, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctrlFailed, _ := json.Marshal(CtrlFailedRequest())
+	legacyCtrl.Write(ctrlFailed)
+	legacyCtrl.Close()
+
+	userConn, err := Connect(userSide)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := userConn.Receive()
+	if err != nil || message.Type != CtrlFailed {
+		t.Errorf("expected %s, got %+v (%v)", CtrlFailed, message, err)
+	}
+}
+
+func TestCall_ReturnsTheReplyToTheRequest(t *testing.T) {
+	ctrlConn, userConn := connectPair(t, listenUnix(t))
+
+	go func() {
+		for {
+			message, err := userConn.Receive()
+			if err != nil {
+				return
+			}
+			if message.Type == UserStop {
+				// Unrelated requests may be sent before the reply
+				userConn.Send(MessageOutRequest("stopping"))
+				userConn.Reply(message.Id, UserStopFinishedRequest())
+			}
+		}
+	}()
+	received := make(chan Message, 1)
+	go func() {
+		for {
+			message, err := ctrlConn.Receive()
+			if err != nil {
+				return
+			}
+			received <- message
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := ctrlConn.Call(ctx, UserStopRequest(), UserStopFinished)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply.Type != UserStopFinished {
+		t.Errorf("expected %s, got %s", UserStopFinished, reply.Type)
+	}
+	select {
+	case message := <-received:
+		if message.Type != MessageOut {
+			t.Errorf("expected %s, got %s", MessageOut, message.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the unrelated request to be received")
+	}
+}
+
+func TestCall_ForgetsTheCallOnCancel(t *testing.T) {
+	ctrlConn, _ := connectPair(t, listenUnix(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ctrlConn.Call(ctx, UserStopRequest(), UserStopFinished); err == nil {
+		t.Fatal("expected the call without a reply to fail")
+	}
+	ctrlConn.mutex.Lock()
+	defer ctrlConn.mutex.Unlock()
+	if len(ctrlConn.calls) != 0 {
+		t.Errorf("expected no pending calls, got %d", len(ctrlConn.calls))
+	}
+}
+
+func TestReceive_ReturnsUnknownRequestTypes(t *testing.T) {
+	ctrlConn, userConn := connectPair(t, listenUnix(t))
+
+	if err := userConn.Send(Request{Type: "FutureRequest"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := ctrlConn.Receive()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if message.Type != "FutureRequest" {
+		t.Errorf("expected FutureRequest, got %s", message.Type)
+	}
+}
+
+func TestRedial_ResendsLostEnvelopesOnce(t *testing.T) {
+	listener := listenUnix(t)
+	ctrlConn, userConn := connectPair(t, listener)
+
+	if err := userConn.Send(MessageOutRequest("before")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if message, err := ctrlConn.Receive(); err != nil || message.MessageOut != "before" {
+		t.Fatalf("expected %q, got %+v (%v)", "before", message, err)
+	}
+
+	// Break the connection, then send while the user is disconnected
+	userConn.Close()
+	receiveErr := make(chan error, 1)
+	received := make(chan Message, 2)
+	go func() {
+		for {
+			message, err := ctrlConn.Receive()
+			if err != nil {
+				receiveErr <- err
+				return
+			}
+			received <- message
+		}
+	}()
+	if err := userConn.Send(MessageOutRequest("during")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		hello, serverConn, err := ReadHello(serverConn, time.Second)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if err := ctrlConn.Resume(serverConn, hello); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+	err := userConn.Redial(func() (net.Conn, error) {
+		return net.Dial("unix", listener.Addr().String())
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := userConn.Send(MessageOutRequest("after")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"during", "after"} {
+		select {
+		case message := <-received:
+			if message.MessageOut != expected {
+				t.Errorf("expected %q, got %q", expected, message.MessageOut)
+			}
+		case err := <-receiveErr:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %q to be received", expected)
+		}
+	}
+}
+
+func TestReceive_FailsWhenTheUserDoesNotReconnect(t *testing.T) {
+	listener := listenUnix(t)
+	ctrlConn, userConn := connectPair(t, listener)
+	ctrlConn.resumeTimeout = 50 * time.Millisecond
+
+	userConn.Close()
+	if _, err := ctrlConn.Receive(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -52,12 +52,74 @@ type TaskIOMetrics struct {
 	DownloadType  string `json:"download_type"`
 }
 
+// KpiMetrics holds the numeric values parsed from a KPI output file
+type KpiMetrics struct {
+	RetryId   string             `json:"retry_id"`
+	GroupName string             `json:"group_name"`
+	TaskName  string             `json:"task_name"`
+	URL       string             `json:"url"`
+	Path      string             `json:"path"`
+	Time      string             `json:"time"`
+	Values    map[string]float64 `json:"values"`
+}
+
+// ResourceMetrics is a sample of the resources used by the user command
+type ResourceMetrics struct {
+	RetryId               string  `json:"retry_id"`
+	GroupName             string  `json:"group_name"`
+	TaskName              string  `json:"task_name"`
+	Time                  string  `json:"time"`
+	Processes             int     `json:"processes"`
+	CpuSeconds            float64 `json:"cpu_seconds"`
+	RssBytes              int64   `json:"rss_bytes"`
+	OpenFiles             int     `json:"open_files"`
+	CgroupCpuSeconds      float64 `json:"cgroup_cpu_seconds"`
+	CgroupMemoryBytes     int64   `json:"cgroup_memory_bytes"`
+	CgroupMemoryPeakBytes int64   `json:"cgroup_memory_peak_bytes"`
+	OomKills              int64   `json:"oom_kills"`
+	OutputSizeBytes       int64   `json:"output_size_bytes"`
+	NetworkRxBytes        int64   `json:"network_rx_bytes"`
+	NetworkTxBytes        int64   `json:"network_tx_bytes"`
+}
+
+// TaskIOProgressMetrics is the progress of an input or output while it is transferred
+type TaskIOProgressMetrics struct {
+	RetryId    string  `json:"retry_id"`
+	GroupName  string  `json:"group_name"`
+	TaskName   string  `json:"task_name"`
+	URL        string  `json:"url"`
+	Type       string  `json:"type"`
+	Time       string  `json:"time"`
+	BytesDone  int64   `json:"bytes_done"`
+	BytesTotal int64   `json:"bytes_total"`
+	FilesDone  int     `json:"files_done"`
+	FilesTotal int     `json:"files_total"`
+	Throughput float64 `json:"throughput_bytes_per_second"`
+	// Negative when the remaining time is unknown
+	EtaSeconds float64 `json:"eta_seconds"`
+}
+
+// RestartMetrics is a restart of the user command by the restart policy of osmo-user
+type RestartMetrics struct {
+	RetryId        string  `json:"retry_id"`
+	GroupName      string  `json:"group_name"`
+	TaskName       string  `json:"task_name"`
+	Time           string  `json:"time"`
+	Restart        int     `json:"restart"`
+	ExitCode       int     `json:"exit_code"`
+	BackoffSeconds float64 `json:"backoff_seconds"`
+}
+
 type Metric interface {
 	getMetricType() string
 }
 
-func (f GroupMetrics) getMetricType() string  { return "group_metrics" }
-func (f TaskIOMetrics) getMetricType() string { return "task_io_metrics" }
+func (f GroupMetrics) getMetricType() string          { return "group_metrics" }
+func (f TaskIOMetrics) getMetricType() string         { return "task_io_metrics" }
+func (f KpiMetrics) getMetricType() string            { return "task_kpi_metrics" }
+func (f ResourceMetrics) getMetricType() string       { return "task_resource_metrics" }
+func (f TaskIOProgressMetrics) getMetricType() string { return "task_io_progress_metrics" }
+func (f RestartMetrics) getMetricType() string        { return "task_restart_metrics" }
 
 type MetricsRequest struct {
 	Source     string
@@ -67,13 +129,14 @@ type MetricsRequest struct {
 	MetricType string
 }
 
-func CreateMetrics(source string, metric Metric, ioType IOType) string {
+// CreateMetrics encodes the metric for the service. Metrics are encoded while logs are sent, so
+// a failure is returned rather than ending ctrl.
+func CreateMetrics(source string, metric Metric, ioType IOType) (string, error) {
 	currTime := time.Now().UTC()
 	metricsRequest := MetricsRequest{source, currTime, metric, ioType, metric.getMetricType()}
 	metricsJson, err := json.Marshal(metricsRequest)
 	if err != nil {
-		osmo_errors.SetExitCode(osmo_errors.METRICS_FAILED_CODE)
-		panic(err)
+		return "", osmo_errors.NewExitError(osmo_errors.METRICS_FAILED_CODE, err)
 	}
-	return string(metricsJson)
+	return string(metricsJson), nil
 }
@@ -20,13 +20,18 @@ package osmo_errors
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"runtime/debug"
+	"sync"
+	"unicode/utf8"
 )
 
 type ExitCode int
 
-// Exit code for type of ctrl failure
+// Exit code for type of ctrl failure, guarded by terminationMutex
 var exitCode ExitCode
 
 const (
@@ -36,6 +41,9 @@ const (
 	UPLOAD_FAILED_CODE          ExitCode = 12 // Failures regarding upload calls
 	DATA_AUTH_CHECK_FAILED_CODE ExitCode = 13 // Failures regarding data auth
 	DATA_UNAUTHORIZED_CODE      ExitCode = 14 // Failures regarding data unauthorized
+	DATA_INTEGRITY_FAILED_CODE  ExitCode = 15 // Failures regarding data integrity verification
+	OUTPUT_QUOTA_EXCEEDED_CODE  ExitCode = 16 // Failures regarding the output size quota
+	PREFLIGHT_FAILED_CODE       ExitCode = 17 // Failures regarding preflight checks
 
 	// Connection Failures
 	TOKEN_INVALID_CODE            ExitCode = 20 // Failures regarding token
@@ -54,6 +62,136 @@ const (
 	MISC_FAILED_CODE ExitCode = 40 // Failures in general
 )
 
+var exitCodeNames = map[ExitCode]string{
+	DOWNLOAD_FAILED_CODE:          "DOWNLOAD_FAILED",
+	MOUNT_FAILED_CODE:             "MOUNT_FAILED",
+	UPLOAD_FAILED_CODE:            "UPLOAD_FAILED",
+	DATA_AUTH_CHECK_FAILED_CODE:   "DATA_AUTH_CHECK_FAILED",
+	DATA_UNAUTHORIZED_CODE:        "DATA_UNAUTHORIZED",
+	DATA_INTEGRITY_FAILED_CODE:    "DATA_INTEGRITY_FAILED",
+	OUTPUT_QUOTA_EXCEEDED_CODE:    "OUTPUT_QUOTA_EXCEEDED",
+	PREFLIGHT_FAILED_CODE:         "PREFLIGHT_FAILED",
+	TOKEN_INVALID_CODE:            "TOKEN_INVALID",
+	WEBSOCKET_TIMEOUT_CODE:        "WEBSOCKET_TIMEOUT",
+	WEBSOCKET_MESSAGE_FAILED_CODE: "WEBSOCKET_MESSAGE_FAILED",
+	UNIX_MESSAGE_FAILED_CODE:      "UNIX_MESSAGE_FAILED",
+	BARRIER_FAILED_CODE:           "BARRIER_FAILED",
+	METRICS_FAILED_CODE:           "METRICS_FAILED",
+	INVALID_INPUT_CODE:            "INVALID_INPUT",
+	CMD_FAILED_CODE:               "CMD_FAILED",
+	FILE_FAILED_CODE:              "FILE_FAILED",
+	MISC_FAILED_CODE:              "MISC_FAILED",
+}
+
+// Failures which may succeed when the task is retried, such as network or storage errors
+var retriableCodes = map[ExitCode]bool{
+	DOWNLOAD_FAILED_CODE:          true,
+	MOUNT_FAILED_CODE:             true,
+	UPLOAD_FAILED_CODE:            true,
+	WEBSOCKET_TIMEOUT_CODE:        true,
+	WEBSOCKET_MESSAGE_FAILED_CODE: true,
+	UNIX_MESSAGE_FAILED_CODE:      true,
+	BARRIER_FAILED_CODE:           true,
+}
+
+func (code ExitCode) Name() string {
+	if code == 0 {
+		return "SUCCESS"
+	}
+	if name, ok := exitCodeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN_%d", int(code))
+}
+
+func (code ExitCode) Retriable() bool {
+	return retriableCodes[code]
+}
+
+// Phase is the part of the task lifecycle osmo-ctrl is in
+type Phase string
+
+const (
+	INIT_PHASE     Phase = "init"
+	DOWNLOAD_PHASE Phase = "download"
+	EXEC_PHASE     Phase = "exec"
+	UPLOAD_PHASE   Phase = "upload"
+	FINISH_PHASE   Phase = "finish"
+)
+
+// Kubernetes truncates termination messages longer than 4096 bytes
+const maxTerminationMessageLength = 2048
+
+// TerminationRecord describes why osmo-ctrl exited
+type TerminationRecord struct {
+	Code      int    `json:"code"`
+	Name      string `json:"name"`
+	Phase     Phase  `json:"phase,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Target    string `json:"target,omitempty"` // The input or output being transferred
+	Retriable bool   `json:"retriable"`
+}
+
+var terminationMutex sync.Mutex
+var phase Phase = INIT_PHASE
+var target string
+var message string
+
+// Masks secrets in the failure message, e.g. in the text of a recovered panic
+var redact = func(text string) string { return text }
+
+// SetPhase records the lifecycle phase and clears the target of the previous phase
+func SetPhase(newPhase Phase) {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	phase = newPhase
+	target = ""
+}
+
+// SetTarget records the input or output being transferred
+func SetTarget(newTarget string) {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	target = newTarget
+}
+
+// SetRedactor sets the function masking secrets in the failure message
+func SetRedactor(redactor func(string) string) {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	redact = redactor
+}
+
+// SetMessage records the reason of the failure with its secrets masked
+func SetMessage(newMessage string) {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	newMessage = redact(newMessage)
+	if len(newMessage) > maxTerminationMessageLength {
+		// Cut before the rune at the limit, so that the message stays valid UTF-8
+		cut := maxTerminationMessageLength
+		for cut > 0 && !utf8.RuneStart(newMessage[cut]) {
+			cut--
+		}
+		newMessage = newMessage[:cut]
+	}
+	message = newMessage
+}
+
+// GetTerminationRecord returns the record of the current failure
+func GetTerminationRecord() TerminationRecord {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	return TerminationRecord{
+		Code:      int(exitCode),
+		Name:      exitCode.Name(),
+		Phase:     phase,
+		Message:   message,
+		Target:    target,
+		Retriable: exitCode.Retriable(),
+	}
+}
+
 type TimeoutError struct {
 	S string
 }
@@ -62,21 +200,80 @@ func (e *TimeoutError) Error() string {
 	return e.S
 }
 
+// ExitError is a failure returned with the exit code of its type
+type ExitError struct {
+	Code ExitCode
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// NewExitError returns err with the exit code, or nil if there is no error
+func NewExitError(code ExitCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// Errorf formats an error with the exit code
+func Errorf(code ExitCode, format string, args ...any) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// CodeOf returns the exit code of the first ExitError wrapped by err, or MISC_FAILED_CODE
+func CodeOf(err error) ExitCode {
+	var exitError *ExitError
+	if errors.As(err, &exitError) {
+		return exitError.Code
+	}
+	return MISC_FAILED_CODE
+}
+
+// Fail records the exit code of err and panics with it, so that HandleExit writes the
+// termination record. It is only called by the main functions.
+func Fail(err error) {
+	SetExitCode(CodeOf(err))
+	panic(err)
+}
+
+// CommandError reports the output of a failed command and returns err with the exit code
+func CommandError(stdout string, stderr string, osmoChan chan string, err error,
+	code ExitCode) error {
+	if err == nil {
+		return nil
+	}
+	log.Println("out:", stdout)
+	log.Println("err:", stderr)
+	osmoChan <- stdout
+	osmoChan <- stderr
+	return NewExitError(code, err)
+}
+
 func LogError(stdout string, stderr string, osmoChan chan string, err error, code ExitCode) {
-	if err != nil {
-		log.Println("out:", stdout)
-		log.Println("err:", stderr)
-		osmoChan <- stdout
-		osmoChan <- stderr
-		SetExitCode(code)
-		panic(err)
+	if err := CommandError(stdout, stderr, osmoChan, err, code); err != nil {
+		Fail(err)
 	}
 }
 
 func SetExitCode(code ExitCode) {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
 	exitCode = code
 }
 
+func GetExitCode() ExitCode {
+	terminationMutex.Lock()
+	defer terminationMutex.Unlock()
+	return exitCode
+}
+
 func SaveExitCode() {
 	// TODO: This file applies to kubernetes. Won't work with slurm
 	file, err := os.Create("/dev/termination-log")
@@ -85,13 +282,31 @@ func SaveExitCode() {
 	}
 	defer file.Close()
 
-	log.Printf("Writing failure code %d to termination log", exitCode)
-	exitCodeJson, err := json.Marshal(map[string]int{"code": int(exitCode)})
+	log.Printf("Writing failure code %d to termination log", GetExitCode())
+	recordJson, err := json.Marshal(GetTerminationRecord())
 	if err != nil {
 		panic(err)
 	}
-	_, err = file.Write(exitCodeJson)
+	_, err = file.Write(recordJson)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// HandleExit saves the termination record when main returns. A panic is recovered and
+// recorded as the failure message, and the process exits with the failure code. It must be
+// deferred directly.
+func HandleExit() {
+	if r := recover(); r != nil {
+		if GetExitCode() == 0 {
+			SetExitCode(MISC_FAILED_CODE)
+		}
+		SetMessage(fmt.Sprint(r))
+		record := GetTerminationRecord()
+		log.Printf("Failed with %s during %s: %s", record.Name, record.Phase, record.Message)
+		log.Printf("Stack trace:\n%s", debug.Stack())
+		SaveExitCode()
+		os.Exit(int(GetExitCode()))
+	}
+	SaveExitCode()
+}
@@ -20,10 +20,8 @@ package rsync
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
 	"sync"
@@ -58,7 +56,7 @@ func RunRsync(
 	rsyncReadLimit int,
 	rsyncWriteLimit int,
 	rsyncPathAllowList string,
-	unixConn net.Conn,
+	userConn *messages.Conn,
 ) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -82,7 +80,7 @@ func RunRsync(
 		return err
 	}
 
-	go monitorRsync(ctx, rsyncCmd, unixConn)
+	go monitorRsync(ctx, rsyncCmd, userConn)
 
 	if err := rsyncCmd.Wait(); err != nil {
 		log.Printf("Rsync command exited with error: %v", err)
@@ -92,7 +90,7 @@ func RunRsync(
 	return nil
 }
 
-func monitorRsync(ctx context.Context, rsyncCmd *exec.Cmd, unixConn net.Conn) {
+func monitorRsync(ctx context.Context, rsyncCmd *exec.Cmd, userConn *messages.Conn) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -100,34 +98,26 @@ func monitorRsync(ctx context.Context, rsyncCmd *exec.Cmd, unixConn net.Conn) {
 		select {
 		case <-ctx.Done():
 			// Context was cancelled, send status update and exit
-			if err := json.NewEncoder(unixConn).Encode(
-				messages.UserRsyncStatusRequest(false),
-			); err != nil {
+			if err := userConn.Send(messages.UserRsyncStatusRequest(false)); err != nil {
 				log.Printf("Failed to send request: %v\n", err)
 			}
 			return
 		case <-ticker.C:
 			if rsyncCmd.Process == nil {
-				if err := json.NewEncoder(unixConn).Encode(
-					messages.UserRsyncStatusRequest(false),
-				); err != nil {
+				if err := userConn.Send(messages.UserRsyncStatusRequest(false)); err != nil {
 					log.Printf("Failed to send request: %v\n", err)
 				}
 				continue
 			}
 
 			if err := rsyncCmd.Process.Signal(syscall.Signal(0)); err != nil {
-				if err := json.NewEncoder(unixConn).Encode(
-					messages.UserRsyncStatusRequest(false),
-				); err != nil {
+				if err := userConn.Send(messages.UserRsyncStatusRequest(false)); err != nil {
 					log.Printf("Failed to send request: %v\n", err)
 				}
 				continue
 			}
 
-			if err := json.NewEncoder(unixConn).Encode(
-				messages.UserRsyncStatusRequest(true),
-			); err != nil {
+			if err := userConn.Send(messages.UserRsyncStatusRequest(true)); err != nil {
 				log.Printf("Failed to send request: %v\n", err)
 			}
 		}
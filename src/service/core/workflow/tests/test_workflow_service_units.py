@@ -1367,6 +1367,37 @@ class TestActionRequestHelper(unittest.TestCase):
         redis_client.set.assert_called_once()
         redis_client.lpush.assert_called_once()
 
+    def test_action_helper_webserver_refuses_task_not_ready(self):
+        workflow_result = _make_workflow_response(
+            name='wf-1', status=job_workflow.WorkflowStatus.RUNNING)
+        backend_config = SimpleNamespace(router_address='wss://router')
+        redis_client = mock.Mock()
+        redis_client.get.return_value = b'0'
+        redis_instance = SimpleNamespace(client=redis_client)
+        target_task = _make_task_query(name='t1', retry_id=2)
+
+        with mock.patch.object(workflow_service.objects.WorkflowServiceContext,
+                               'get', return_value=mock.Mock()), \
+             mock.patch.object(workflow_service.connectors.Backend,
+                               'fetch_from_db',
+                               return_value=backend_config), \
+             mock.patch.object(workflow_service.helpers, 'get_running_task',
+                               return_value=target_task), \
+             mock.patch.object(workflow_service.connectors.RedisConnector,
+                               'get_instance',
+                               return_value=redis_instance):
+            with self.assertRaises(osmo_errors.OSMOUserError) as ctx:
+                workflow_service.action_request_helper(
+                    workflow_service.ActionType.WEBSERVER, {'task_port': 8080},
+                    'wf-1', task_name='t1',
+                    cached_workflow_response=workflow_result)
+
+        self.assertEqual(ctx.exception.status_code, http.HTTPStatus.TOO_EARLY.value)
+        self.assertIn('not ready', ctx.exception.message)
+        redis_client.get.assert_called_once_with(
+            workflow_service.job_common.readiness_key('wf-1', 't1', 2))
+        redis_client.lpush.assert_not_called()
+
     def test_action_helper_group_dispatches_group_tasks(self):
         workflow_result = _make_workflow_response(
             name='wf-1', status=job_workflow.WorkflowStatus.RUNNING)
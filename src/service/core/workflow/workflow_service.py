@@ -1138,6 +1138,18 @@ def action_request_helper(action_type: ActionType, payload: Dict[str, Any], name
         tasks.extend(helpers.get_running_tasks_from_workflow(workflow_result))
 
     redis_client = connectors.RedisConnector.get_instance().client
+    # Tasks with a readiness probe report when their command is ready to serve its ports
+    if action_type in (ActionType.PORTFORWARD, ActionType.WEBSERVER):
+        for task_obj in tasks:  # type: ignore
+            readiness = redis_client.get(
+                job_common.readiness_key(workflow_id, task_obj.name, task_obj.retry_id))
+            if readiness == b'0':
+                raise osmo_errors.OSMOUserError(
+                    f'Task {task_obj.name} is not ready yet...',
+                    workflow_id=workflow_id,
+                    status_code=http.HTTPStatus.TOO_EARLY.value,
+                )
+
     total_timeout = job_common.calculate_total_timeout(
         workflow_id, workflow_result.queue_timeout, workflow_result.exec_timeout)
     cookie = helpers.get_router_cookie(router_address)
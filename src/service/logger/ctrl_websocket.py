@@ -39,11 +39,19 @@ class MetricsOptions(pydantic.BaseModel):
         default=None, description='Metrics for group')
     task_io_metrics: Optional[task_io.TaskIOMetrics] = pydantic.Field(
         default=None, description='Metrics for task io')
+    task_kpi_metrics: Optional[task_io.TaskKPIMetrics] = pydantic.Field(
+        default=None, description='Values of a task KPI file')
+    task_resource_metrics: Optional[task_io.TaskResourceMetrics] = pydantic.Field(
+        default=None, description='Resource usage of a task command')
+    task_io_progress_metrics: Optional[task_io.TaskIOProgressMetrics] = pydantic.Field(
+        default=None, description='Progress of a task input or output being transferred')
+    task_restart_metrics: Optional[task_io.TaskRestartMetrics] = pydantic.Field(
+        default=None, description='Restart of a task command by its restart policy')
 
     @pydantic.model_validator(mode='before')
     @classmethod
     def validate_single_field(cls, values):
-        """ A valid metric can only be one of the two types """
+        """ A valid metric can only be one of the types """
         num_fields_set = sum(1 for value in values.values()
                              if value is not None)
         if num_fields_set != 1:
@@ -80,8 +88,10 @@ def update_metrics(
             retry_id=metrics.retry_id,
             url=metrics.url,
             uuid=common.generate_unique_id(),
+            # Git inputs are cloned from a repository rather than a storage bucket
             storage_bucket=storage.construct_storage_backend(
-                metrics.url).container_uri if metrics.url else '',
+                metrics.url).container_uri
+                if metrics.url and metrics.operation_type != 'Git' else '',
             type=metrics.type,
             start_time=metrics.start_time,
             end_time=metrics.end_time,
@@ -90,6 +100,25 @@ def update_metrics(
             download_type=metrics.download_type,
             number_of_files=metrics.number_of_files
         ).insert_to_db()
+    elif isinstance(metrics, task_io.TaskKPIMetrics):
+        for kpi_name, kpi_value in metrics.values.items():
+            task_io.TaskKPIValue(
+                database=database,
+                workflow_id=name,
+                group_name=metrics.group_name,
+                task_name=metrics.task_name,
+                retry_id=metrics.retry_id,
+                path=metrics.path,
+                name=kpi_name,
+                value=kpi_value,
+                time=metrics.time
+            ).insert_to_db()
+    elif isinstance(metrics, task_io.TaskResourceMetrics):
+        metrics.insert_to_db(database, name)
+    elif isinstance(metrics, task_io.TaskIOProgressMetrics):
+        metrics.insert_to_db(database, name)
+    elif isinstance(metrics, task_io.TaskRestartMetrics):
+        metrics.insert_to_db(database, name)
 
 
 async def update_barrier(database, redis_client, workflow_id: str, group_name: str, task_name: str,
@@ -117,6 +146,14 @@ async def update_barrier(database, redis_client, workflow_id: str, group_name: s
             await redis_client.lpush(queue_name, key)
 
 
+async def update_readiness(redis_client, workflow_id: str, task_name: str, retry_id: int,
+                           ready: bool, total_timeout: int):
+    key = job_common.readiness_key(workflow_id, task_name, retry_id)
+    logging.info('Task %s of workflow %s is %s', task_name, workflow_id,
+                 'ready' if ready else 'not ready')
+    await redis_client.set(key, '1' if ready else '0', ex=total_timeout)
+
+
 async def run_websocket(websocket: fastapi.WebSocket, name: str, task_name: str, retry_id: int):
     """ Websocket for osmo-ctrl for sending workflow logs and metrics. """
     await websocket.accept()
@@ -179,6 +216,11 @@ async def run_websocket(websocket: fastapi.WebSocket, name: str, task_name: str,
                                                 loaded_json.get('name'),  # type: ignore[arg-type]
                                                 loaded_json.get('count'),  # type: ignore[arg-type]
                                                 total_timeout)
+                        elif io_type == connectors.IOType.READINESS:
+                            await update_readiness(redis_client, workflow_obj.workflow_id,
+                                                   task_name, retry_id,
+                                                   bool(loaded_json.get('ready')),
+                                                   total_timeout)
                         else:
                             if io_type.workflow_logs() and (first_run or\
                                 datetime.datetime.now() - last_heartbeat_check > heartbeat_freq_dt):
@@ -192,12 +234,18 @@ async def run_websocket(websocket: fastapi.WebSocket, name: str, task_name: str,
                                         workflow_obj.workflow_id, retry_id))
                             loaded_json['text'] = common.mask_string(loaded_json.get('text', ''),
                                                                     task_cred_values)
+                            fields = ''
+                            if loaded_json.get('fields'):
+                                fields = common.mask_string(json.dumps(loaded_json['fields']),
+                                                            task_cred_values)
                             logs = connectors.LogStreamBody(
                                 source=loaded_json['source'],
                                 retry_id=retry_id,
                                 time=loaded_json['time'],
                                 text=loaded_json['text'],
-                                io_type=loaded_json['iotype'])
+                                io_type=loaded_json['iotype'],
+                                level=loaded_json.get('level', ''),
+                                fields=fields)
                             # Use logs.model_dump_json() instead of
                             # logs.model_dump() to convert enum and
                             # datetime to strings
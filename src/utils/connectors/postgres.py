@@ -1017,6 +1017,85 @@ class PostgresConnector:
         '''
         self.execute_commit_command(create_cmd, ())
 
+        # Creates table for the values parsed from task KPI files
+        create_cmd = '''
+            CREATE TABLE IF NOT EXISTS task_kpi (
+                workflow_id TEXT,
+                group_name TEXT,
+                task_name TEXT,
+                retry_id INT,
+                path TEXT,
+                name TEXT,
+                value DOUBLE PRECISION,
+                time TIMESTAMP,
+                PRIMARY KEY (workflow_id, group_name, task_name, retry_id, path, name)
+            );
+        '''
+        self.execute_commit_command(create_cmd, ())
+
+        # Creates table for the resource usage samples of task commands
+        create_cmd = '''
+            CREATE TABLE IF NOT EXISTS task_resource_usage (
+                workflow_id TEXT,
+                group_name TEXT,
+                task_name TEXT,
+                retry_id INT,
+                time TIMESTAMP,
+                processes INT,
+                cpu_seconds DOUBLE PRECISION,
+                rss_bytes BIGINT,
+                open_files INT,
+                cgroup_cpu_seconds DOUBLE PRECISION,
+                cgroup_memory_bytes BIGINT,
+                cgroup_memory_peak_bytes BIGINT,
+                oom_kills BIGINT,
+                output_size_bytes BIGINT,
+                network_rx_bytes BIGINT,
+                network_tx_bytes BIGINT,
+                PRIMARY KEY (workflow_id, group_name, task_name, retry_id, time)
+            );
+        '''
+        self.execute_commit_command(create_cmd, ())
+
+        # Creates table for the restarts of task commands by the restart policy of osmo-user. The
+        # restart counter starts over when the command is restarted by a restart action, so the
+        # time tells the restarts apart.
+        create_cmd = '''
+            CREATE TABLE IF NOT EXISTS task_restarts (
+                workflow_id TEXT,
+                group_name TEXT,
+                task_name TEXT,
+                retry_id INT,
+                time TIMESTAMP,
+                restart INT,
+                exit_code INT,
+                backoff_seconds DOUBLE PRECISION,
+                PRIMARY KEY (workflow_id, group_name, task_name, retry_id, time, restart)
+            );
+        '''
+        self.execute_commit_command(create_cmd, ())
+
+        # Creates table for the latest progress of the inputs and outputs being transferred
+        create_cmd = '''
+            CREATE TABLE IF NOT EXISTS task_io_progress (
+                workflow_id TEXT,
+                group_name TEXT,
+                task_name TEXT,
+                retry_id INT,
+                url TEXT,
+                type TEXT,
+                time TIMESTAMP,
+                bytes_done BIGINT,
+                bytes_total BIGINT,
+                files_done INT,
+                files_total INT,
+                throughput_bytes_per_second DOUBLE PRECISION,
+                eta_seconds DOUBLE PRECISION,
+                PRIMARY KEY (workflow_id, group_name, task_name, retry_id, url, type)
+            );
+        '''
+        self.execute_commit_command(create_cmd, ())
+
         # Creates table for apps
         create_cmd = '''
             CREATE TABLE IF NOT EXISTS apps (
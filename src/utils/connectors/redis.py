@@ -172,6 +172,8 @@ class IOType(enum.Enum):
     DUMP = 'DUMP'
     # Use to synchronize tasks in a group
     BARRIER = 'BARRIER'
+    # Readiness of the task command reported by its readiness probe
+    READINESS = 'READINESS'
 
     def ctrl_logs(self) -> bool:
         """ Logs pertaining to OSMO control. """
@@ -189,6 +191,9 @@ class LogStreamBody(pydantic.BaseModel, extra='forbid'):
     time: datetime.datetime
     text: str
     io_type: IOType
+    # Set for structured user logs, fields are JSON encoded since stream values are strings
+    level: str = ''
+    fields: str = ''
 
 
 async def redis_log_streamer(
@@ -112,6 +112,10 @@ def barrier_key(workflow_id: str, group_name: str, barrier_name: str) -> str:
     return f'client-connections:{workflow_id}:{group_name}:barrier-{barrier_name}'
 
 
+def readiness_key(workflow_id: str, task_name: str, retry_id: int) -> str:
+    return f'client-connections:{workflow_id}:{task_name}:{retry_id}:readiness'
+
+
 class WorkflowPlugins(pydantic.BaseModel):
     """ Represents the state of plugins in a workflow upon submission. """
     rsync: bool = False
@@ -348,12 +348,32 @@ InputType = TaskInputOutput | URLInputOutput
 OutputType = URLInputOutput
 
 
+class CheckpointVersioning(str, enum.Enum):
+    """ How checkpoint rounds are laid out under the checkpoint url """
+    # Every round overwrites the previous one
+    NONE = 'none'
+    # Every round is written to a new timestamped prefix
+    TIMESTAMP = 'timestamp'
+    # Every round is written to a new step numbered prefix
+    STEP = 'step'
+
+
 class CheckpointSpec(pydantic.BaseModel, extra='forbid'):
     """ Represents a checkpoint spec """
     path: str
     url: constants.StorageBackendPattern
     frequency: datetime.timedelta
     regex: str = ''
+    versioning: CheckpointVersioning = CheckpointVersioning.NONE
+    # Number of versions to keep, 0 keeps all versions
+    keep: int = pydantic.Field(default=0, ge=0)
+
+    @pydantic.model_validator(mode='after')
+    def validate_retention(self):
+        """ Rotation only applies to versioned checkpoints. """
+        if self.keep and self.versioning == CheckpointVersioning.NONE:
+            raise ValueError('Checkpoint keep requires versioning to be timestamp or step')
+        return self
 
     @pydantic.field_validator('frequency', mode='before')
     @classmethod
@@ -2658,11 +2678,12 @@ class TaskGroup(pydantic.BaseModel):
             checkpoint_path = checkpoint.path
             if not checkpoint_path.startswith('/'):
                 checkpoint_path = f'/{checkpoint_path}'
-            user_args += [
-                '-checkpoint',
-                f'{checkpoint_path};{checkpoint.url};' +
+            checkpoint_arg = f'{checkpoint_path};{checkpoint.url};' + \
                 f'{int(checkpoint.frequency.total_seconds())};{checkpoint.regex}'
-                ]
+            # Only appended for versioned checkpoints to stay compatible with older runtimes
+            if checkpoint.versioning != CheckpointVersioning.NONE:
+                checkpoint_arg += f';{checkpoint.versioning.value};{checkpoint.keep}'
+            user_args += ['-checkpoint', checkpoint_arg]
 
         host_mounts = []
         for i, mount in enumerate(task_spec.volumeMounts):
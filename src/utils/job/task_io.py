@@ -18,6 +18,7 @@ SPDX-License-Identifier: Apache-2.0
 
 import datetime
 import enum
+from typing import Dict
 
 import pydantic
 
@@ -86,3 +87,145 @@ class TaskIO(pydantic.BaseModel):
              self.start_time, self.end_time, self.size, self.operation_type,
              self.download_type.value, self.number_of_files
             ))
+
+
+class TaskKPIMetrics(pydantic.BaseModel, extra='forbid'):
+    """ Represents the numeric values of a KPI file submitted by a user task """
+    group_name: task_common.NamePattern
+    task_name: task_common.NamePattern
+    retry_id: int
+    url: str
+    path: str
+    time: datetime.datetime
+    values: Dict[str, float]
+
+
+class TaskKPIValue(pydantic.BaseModel):
+    """ Represents a single KPI value of a task """
+    model_config = pydantic.ConfigDict(extra='forbid', arbitrary_types_allowed=True)
+    workflow_id: task_common.NamePattern
+    group_name: task_common.NamePattern
+    task_name: task_common.NamePattern
+    retry_id: int = 0
+    path: str
+    name: str
+    value: float
+    time: datetime.datetime
+    database: connectors.PostgresConnector
+
+    def insert_to_db(self):
+        """ Creates or updates the entry in the database for the KPI value. """
+        insert_cmd = '''
+            INSERT INTO task_kpi
+            (workflow_id, group_name, task_name, retry_id, path, name, value, time)
+            VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
+            ON CONFLICT (workflow_id, group_name, task_name, retry_id, path, name)
+            DO UPDATE SET value = EXCLUDED.value, time = EXCLUDED.time;
+        '''
+        self.database.execute_commit_command(
+            insert_cmd,
+            (self.workflow_id, self.group_name, self.task_name, self.retry_id,
+             self.path, self.name, self.value, self.time))
+
+
+class TaskResourceMetrics(pydantic.BaseModel, extra='forbid'):
+    """ Represents a resource usage sample of the command of a user task """
+    group_name: task_common.NamePattern
+    task_name: task_common.NamePattern
+    retry_id: int
+    time: datetime.datetime
+    processes: int
+    cpu_seconds: float
+    rss_bytes: int
+    open_files: int
+    cgroup_cpu_seconds: float
+    cgroup_memory_bytes: int
+    cgroup_memory_peak_bytes: int
+    oom_kills: int
+    output_size_bytes: int
+    network_rx_bytes: int
+    network_tx_bytes: int
+
+    def insert_to_db(self, database: connectors.PostgresConnector, workflow_id: str):
+        """ Creates an entry in the database for the sample. """
+        insert_cmd = '''
+            INSERT INTO task_resource_usage
+            (workflow_id, group_name, task_name, retry_id, time, processes, cpu_seconds,
+             rss_bytes, open_files, cgroup_cpu_seconds, cgroup_memory_bytes,
+             cgroup_memory_peak_bytes, oom_kills, output_size_bytes, network_rx_bytes,
+             network_tx_bytes
+            )
+            VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+            ON CONFLICT DO NOTHING;
+        '''
+        database.execute_commit_command(
+            insert_cmd,
+            (workflow_id, self.group_name, self.task_name, self.retry_id, self.time,
+             self.processes, self.cpu_seconds, self.rss_bytes, self.open_files,
+             self.cgroup_cpu_seconds, self.cgroup_memory_bytes, self.cgroup_memory_peak_bytes,
+             self.oom_kills, self.output_size_bytes, self.network_rx_bytes,
+             self.network_tx_bytes))
+
+
+class TaskRestartMetrics(pydantic.BaseModel, extra='forbid'):
+    """ Represents a restart of the command of a user task by its restart policy """
+    group_name: task_common.NamePattern
+    task_name: task_common.NamePattern
+    retry_id: int
+    time: datetime.datetime
+    restart: int
+    exit_code: int
+    backoff_seconds: float
+
+    def insert_to_db(self, database: connectors.PostgresConnector, workflow_id: str):
+        """ Creates an entry in the database for the restart. """
+        insert_cmd = '''
+            INSERT INTO task_restarts
+            (workflow_id, group_name, task_name, retry_id, time, restart, exit_code,
+             backoff_seconds
+            )
+            VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
+            ON CONFLICT DO NOTHING;
+        '''
+        database.execute_commit_command(
+            insert_cmd,
+            (workflow_id, self.group_name, self.task_name, self.retry_id, self.time,
+             self.restart, self.exit_code, self.backoff_seconds))
+
+
+class TaskIOProgressMetrics(pydantic.BaseModel, extra='forbid'):
+    """ Represents the progress of an input or output of a task while it is transferred """
+    group_name: task_common.NamePattern
+    task_name: task_common.NamePattern
+    retry_id: int
+    url: str
+    type: str
+    time: datetime.datetime
+    bytes_done: int
+    bytes_total: int
+    files_done: int
+    files_total: int
+    throughput_bytes_per_second: float
+    # Negative when the remaining time is unknown
+    eta_seconds: float
+
+    def insert_to_db(self, database: connectors.PostgresConnector, workflow_id: str):
+        """ Creates or updates the entry in the database with the latest progress. """
+        insert_cmd = '''
+            INSERT INTO task_io_progress
+            (workflow_id, group_name, task_name, retry_id, url, type, time, bytes_done,
+             bytes_total, files_done, files_total, throughput_bytes_per_second, eta_seconds
+            )
+            VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+            ON CONFLICT (workflow_id, group_name, task_name, retry_id, url, type)
+            DO UPDATE SET time = EXCLUDED.time, bytes_done = EXCLUDED.bytes_done,
+                bytes_total = EXCLUDED.bytes_total, files_done = EXCLUDED.files_done,
+                files_total = EXCLUDED.files_total,
+                throughput_bytes_per_second = EXCLUDED.throughput_bytes_per_second,
+                eta_seconds = EXCLUDED.eta_seconds;
+        '''
+        database.execute_commit_command(
+            insert_cmd,
+            (workflow_id, self.group_name, self.task_name, self.retry_id, self.url, self.type,
+             self.time, self.bytes_done, self.bytes_total, self.files_done, self.files_total,
+             self.throughput_bytes_per_second, self.eta_seconds))
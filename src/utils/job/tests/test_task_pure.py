@@ -687,6 +687,26 @@ class CheckpointSpecTest(unittest.TestCase):
         spec = self._make(frequency=1, regex=r'\d+')
         self.assertEqual(spec.regex, r'\d+')
 
+    def test_versioning_defaults_to_none(self):
+        spec = self._make(frequency=1)
+        self.assertEqual(spec.versioning, task.CheckpointVersioning.NONE)
+        self.assertEqual(spec.keep, 0)
+
+    def test_versioned_with_keep_passes(self):
+        spec = task.CheckpointSpec(path='/some/path', url='s3://bucket/key', frequency=1,
+                                   versioning='step', keep=3)
+        self.assertEqual(spec.versioning, task.CheckpointVersioning.STEP)
+        self.assertEqual(spec.keep, 3)
+
+    def test_keep_without_versioning_raises(self):
+        with self.assertRaises(pydantic.ValidationError):
+            task.CheckpointSpec(path='/some/path', url='s3://bucket/key', frequency=1, keep=3)
+
+    def test_negative_keep_raises(self):
+        with self.assertRaises(pydantic.ValidationError):
+            task.CheckpointSpec(path='/some/path', url='s3://bucket/key', frequency=1,
+                                versioning='timestamp', keep=-1)
+
 
 class FileTest(unittest.TestCase):
     """Tests for File.validate_path and encoded_contents."""